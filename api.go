@@ -5,40 +5,236 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/n0h4rt/chadango/models"
 	"github.com/n0h4rt/chadango/utils"
 )
 
-// httpClient is an [http.Client] to interact with the Chatango APIs.
-var httpClient *http.Client
+// defaultAPI holds the options of the API clients created outside an [Application], see [ConfigureAPI].
+var defaultAPI apiConfig
 
-// initHttpClient initializes the [http.Client] with custom headers and a cookie jar.
-func initHttpClient() {
-	httpClient = &http.Client{
+// APIOptions represents the options of the Chatango API clients, see [Config.API] and [ConfigureAPI].
+//
+// The zero value of a field means its default.
+type APIOptions struct {
+	Transport http.RoundTripper // The underlying RoundTripper, e.g. an [http.Transport] with a proxy, defaults to [http.DefaultTransport].
+	Timeout   time.Duration     // The timeout of a request, defaults to [API_TIMEOUT].
+	UserAgent string            // The "User-Agent" header, defaults to [API_USER_AGENT].
+	Origin    string            // The "Origin" header, defaults to [API_ORIGIN].
+	Headers   map[string]string // Extra headers added to the requests, they override the headers above.
+
+	Retries      int           // The retries of a failed request, defaults to [API_RETRIES], negative disables them.
+	RetryBackoff time.Duration // The initial wait between the retries, defaults to [API_RETRY_BACKOFF].
+	RetryPOST    bool          // Whether POST requests are retried too, which may post twice if the server did process the request.
+}
+
+// hexColorRe matches a hexadecimal color of 3 or 6 digits, optionally prefixed with "#".
+var hexColorRe = regexp.MustCompile(`^#?([\da-fA-F]{3}|[\da-fA-F]{6})$`)
+
+// apiConfig holds the options of a set of API clients, and the shared [http.Client] built from them.
+//
+// Each [Application] has its own, so applications with different options can coexist.
+// The zero value is ready to use with the default options.
+type apiConfig struct {
+	mu      sync.RWMutex
+	options APIOptions   // The current options.
+	client  *http.Client // The shared HTTP client used by the unauthenticated API calls, built on the first use.
+	version uint64       // Incremented on each change of the options, so the own HTTP clients are rebuilt.
+}
+
+// set replaces the options, and rebuilds the shared HTTP client keeping its cookie jar.
+//
+// The replaced client is left intact for the requests still using it.
+//
+// Args:
+//   - opts: The API options, the zero value restores the defaults.
+func (c *apiConfig) set(opts APIOptions) {
+	opts = copyAPIOptions(opts)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.options = opts
+	c.rebuild()
+}
+
+// setTransport replaces the [APIOptions.Transport], keeping the other options.
+//
+// Args:
+//   - rt: The RoundTripper, [http.DefaultTransport] is used if nil.
+func (c *apiConfig) setTransport(rt http.RoundTripper) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.options.Transport = rt
+	c.rebuild()
+}
+
+// rebuild bumps the version of the options, and rebuilds the shared HTTP client if it is built already.
+//
+// The caller must hold [apiConfig.mu].
+func (c *apiConfig) rebuild() {
+	c.version++
+	if c.client != nil {
+		c.client = buildHttpClient(c.options, c.client.Jar)
+	}
+}
+
+// current returns a copy of the current options, along with their version.
+//
+// Returns:
+//   - APIOptions: The current options.
+//   - uint64: The version of the options.
+func (c *apiConfig) current() (APIOptions, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.options, c.version
+}
+
+// sharedClient returns the shared HTTP client, building it on the first use.
+//
+// Returns:
+//   - *http.Client: The shared HTTP client.
+func (c *apiConfig) sharedClient() *http.Client {
+	c.mu.RLock()
+	client := c.client
+	c.mu.RUnlock()
+	if client != nil {
+		return client
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		c.client = buildHttpClient(c.options, nil)
+	}
+
+	return c.client
+}
+
+// copyAPIOptions copies the options, including the headers, so the caller may modify its map afterwards.
+//
+// Args:
+//   - opts: The API options.
+//
+// Returns:
+//   - APIOptions: The copy of the options.
+func copyAPIOptions(opts APIOptions) APIOptions {
+	if opts.Headers != nil {
+		headers := make(map[string]string, len(opts.Headers))
+		for key, value := range opts.Headers {
+			headers[key] = value
+		}
+		opts.Headers = headers
+	}
+
+	return opts
+}
+
+// SetAPITransport sets the underlying [http.RoundTripper] used by the API clients created by [NewPrivateAPI] and [NewPublicAPI],
+// e.g. an [http.Transport] with a proxy or a custom TLS config.
+//
+// It is a shorthand for setting [APIOptions.Transport], keeping the other options.
+// The custom Chatango headers are still applied on top of the [rt].
+// The API clients of an [Application] use [Application.SetAPITransport] instead.
+//
+// Args:
+//   - rt: The RoundTripper, [http.DefaultTransport] is used if nil.
+func SetAPITransport(rt http.RoundTripper) {
+	defaultAPI.setTransport(rt)
+}
+
+// ConfigureAPI sets the options of the API clients created by [NewPrivateAPI] and [NewPublicAPI].
+//
+// The clients pick the options up on their next request, keeping their cookies.
+// The API clients of an [Application] use [Config.API] and [Application.ConfigureAPI] instead.
+//
+// Args:
+//   - opts: The API options, the zero value restores the defaults.
+func ConfigureAPI(opts APIOptions) {
+	defaultAPI.set(opts)
+}
+
+// apiHeaders returns the custom headers of the API requests according to the options.
+//
+// Args:
+//   - opts: The API options.
+//
+// Returns:
+//   - map[string]string: A new map of the headers.
+func apiHeaders(opts APIOptions) map[string]string {
+	headers := map[string]string{
+		"Host":       "script.st.chatango.com",
+		"Origin":     API_ORIGIN,
+		"User-Agent": API_USER_AGENT,
+	}
+	if opts.Origin != "" {
+		headers["Origin"] = opts.Origin
+	}
+	if opts.UserAgent != "" {
+		headers["User-Agent"] = opts.UserAgent
+	}
+	for key, value := range opts.Headers {
+		headers[key] = value
+	}
+
+	return headers
+}
+
+// buildHttpClient creates an [http.Client] with custom headers according to the options.
+//
+// Args:
+//   - opts: The API options.
+//   - jar: The cookie jar to use, a new one is created if nil.
+//
+// Returns:
+//   - *http.Client: The new HTTP client.
+func buildHttpClient(opts APIOptions, jar http.CookieJar) *http.Client {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = API_TIMEOUT
+	}
+	rt := opts.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	client := &http.Client{
 		Transport: &Transport{
-			Transport: http.DefaultTransport,
-			Headers: map[string]string{
-				"Host":       "script.st.chatango.com",
-				"Origin":     "https://st.chatango.com",
-				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64)",
-			},
+			Transport: rt,
+			Headers:   apiHeaders(opts),
 		},
-		Timeout: API_TIMEOUT,
+		Timeout: timeout,
+		Jar:     jar,
 	}
 
-	var err error
-	if httpClient.Jar, err = cookiejar.New(nil); err != nil {
-		log.Fatalf("Failed to create cookie jar: %v", err)
+	if client.Jar == nil {
+		var err error
+		if client.Jar, err = cookiejar.New(nil); err != nil {
+			log.Fatalf("Failed to create cookie jar: %v", err)
+		}
 	}
+
+	return client
 }
 
 // Transport is a custom RoundTripper implementation.
@@ -68,28 +264,216 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 // APIClient represents a client for the Chatango API.
 type APIClient struct {
-	context context.Context
+	context  context.Context
+	config   *apiConfig   // The options of the client, the ones set by [ConfigureAPI] are used if nil.
+	own      bool         // Whether the client has its own HTTP client, i.e. its own cookie jar.
+	clientMu sync.Mutex   // Guards the [APIClient.client] and the [APIClient.version].
+	client   *http.Client // The own HTTP client, the shared one of the [APIClient.config] is used if nil.
+	version  uint64       // The version of the options the own HTTP client is built with.
+}
+
+// apiConfig returns the options of the API client.
+//
+// Returns:
+//   - *apiConfig: The options of the client.
+func (p *APIClient) apiConfig() *apiConfig {
+	if p.config != nil {
+		return p.config
+	}
+
+	return &defaultAPI
+}
+
+// httpClient returns the HTTP client of the API client.
+//
+// The own HTTP client is rebuilt with its cookie jar once the options change.
+//
+// Returns:
+//   - *http.Client: The own HTTP client if any, the shared one otherwise.
+func (p *APIClient) httpClient() *http.Client {
+	config := p.apiConfig()
+	opts, version := config.current()
+
+	p.clientMu.Lock()
+	defer p.clientMu.Unlock()
+
+	if p.own && (p.client == nil || p.version != version) {
+		var jar http.CookieJar
+		if p.client != nil {
+			jar = p.client.Jar
+		}
+		p.client = buildHttpClient(opts, jar)
+		p.version = version
+	}
+	if p.client != nil {
+		return p.client
+	}
+
+	return config.sharedClient()
 }
 
 // executeRequest executes the given HTTP request and checks for errors.
 //
+// Network errors and 5xx responses are retried with a backoff, see [APIOptions.Retries].
+// The wait between the retries is bounded by the request context.
+//
 // Args:
 //   - req: The HTTP request to be executed.
 //
 // Returns:
 //   - *http.Response: The HTTP response.
 //   - error: An error if the request fails.
-func executeRequest(req *http.Request) (res *http.Response, err error) {
-	if res, err = httpClient.Do(req); err != nil {
+func (p *APIClient) executeRequest(req *http.Request) (res *http.Response, err error) {
+	config := p.apiConfig()
+	retries := config.retries(req)
+	var backoff *Backoff
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return
+			}
+		}
+
+		res, err = p.httpClient().Do(req)
+		if attempt >= retries || !shouldRetry(req, res, err) {
+			break
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+
+		if backoff == nil {
+			backoff = newBackoff(config.retryBackoff(), MAX_BACKOFF_DUR, 0)
+		}
+		if backoff.Sleep(req.Context()) {
+			return nil, req.Context().Err()
+		}
+	}
+
+	if err != nil {
 		return
 	}
 	if res.StatusCode != http.StatusOK {
 		defer res.Body.Close()
-		err = ErrRequestFailed
+		err = newAPIError(req, res, nil)
 	}
 	return
 }
 
+// APIError represents a failed Chatango API request.
+//
+// It wraps [ErrRequestFailed], so errors.Is(err, ErrRequestFailed) still holds.
+type APIError struct {
+	StatusCode int    // The HTTP status code of the response.
+	Method     string // The method of the request.
+	URL        string // The URL of the request.
+	Body       string // The response body, truncated to [API_ERROR_BODY_SIZE] bytes.
+}
+
+// newAPIError creates an [APIError] from the request and its response.
+//
+// Args:
+//   - req: The HTTP request.
+//   - res: The HTTP response.
+//   - body: The already read response body, or nil to read it from the [res].
+//
+// Returns:
+//   - *APIError: The new API error.
+func newAPIError(req *http.Request, res *http.Response, body []byte) *APIError {
+	if body == nil {
+		body, _ = io.ReadAll(io.LimitReader(res.Body, API_ERROR_BODY_SIZE))
+	} else if len(body) > API_ERROR_BODY_SIZE {
+		body = body[:API_ERROR_BODY_SIZE]
+	}
+
+	err := &APIError{
+		StatusCode: res.StatusCode,
+		Body:       string(body),
+	}
+	if req != nil {
+		err.Method = req.Method
+		err.URL = req.URL.Redacted()
+	}
+
+	return err
+}
+
+// Error returns the description of the error.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s: %s %s: %d %s", ErrRequestFailed, e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if body := strings.TrimSpace(e.Body); body != "" {
+		msg += ": " + body
+	}
+
+	return msg
+}
+
+// Unwrap returns [ErrRequestFailed].
+func (e *APIError) Unwrap() error {
+	return ErrRequestFailed
+}
+
+// retries returns the number of retries allowed for the request according to the options.
+//
+// Only idempotent requests are retried, unless [APIOptions.RetryPOST] is set and the body can be replayed.
+//
+// Args:
+//   - req: The HTTP request.
+//
+// Returns:
+//   - int: The number of retries.
+func (c *apiConfig) retries(req *http.Request) int {
+	opts, _ := c.current()
+	retries := opts.Retries
+	if retries == 0 {
+		retries = API_RETRIES
+	}
+	if retries < 0 {
+		return 0
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return retries
+	case http.MethodPost:
+		if opts.RetryPOST && (req.Body == nil || req.GetBody != nil) {
+			return retries
+		}
+	}
+
+	return 0
+}
+
+// retryBackoff returns the initial wait between the retries according to the options.
+func (c *apiConfig) retryBackoff() time.Duration {
+	if opts, _ := c.current(); opts.RetryBackoff > 0 {
+		return opts.RetryBackoff
+	}
+
+	return API_RETRY_BACKOFF
+}
+
+// shouldRetry reports whether a request is worth retrying, i.e. on a network error or a 5xx response.
+//
+// Args:
+//   - req: The HTTP request.
+//   - res: The HTTP response, if any.
+//   - err: The error of the request, if any.
+//
+// Returns:
+//   - bool: True if the request should be retried.
+func shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+
+	return res.StatusCode >= http.StatusInternalServerError
+}
+
 // Get sends a GET request to the specified URL with the provided parameters.
 //
 // Args:
@@ -107,7 +491,7 @@ func (p *APIClient) Get(url string, param url.Values) (*http.Response, error) {
 
 	req.URL.RawQuery = param.Encode()
 
-	return executeRequest(req)
+	return p.executeRequest(req)
 }
 
 // PostForm sends a POST request with form data to the specified URL.
@@ -125,7 +509,7 @@ func (p *APIClient) PostForm(url string, data url.Values) (*http.Response, error
 		return nil, err
 	}
 
-	return executeRequest(req)
+	return p.executeRequest(req)
 }
 
 // PostMultipart sends a POST request with body and its content-type to the specified URL.
@@ -146,18 +530,21 @@ func (p *APIClient) PostMultipart(url string, body io.Reader, ctype string) (*ht
 
 	req.Header.Set("Content-Type", ctype)
 
-	return executeRequest(req)
+	return p.executeRequest(req)
 }
 
 // PrivateAPI represents a compilation of various Chatango APIs that needs to be authenticated.
 type PrivateAPI struct {
 	APIClient
 
-	MessageBackground models.MessageBackground
-	MessageStyle      models.MessageStyle
+	MessageBackground   models.MessageBackground
+	MessageStyle        models.MessageStyle
+	MaxImageSize        int64 // The maximum size of an image for [PrivateAPI.UploadImage] in bytes, defaults to [IMG_MAX_SIZE], negative disables it.
+	SkipImageValidation bool  // Whether [PrivateAPI.UploadImage] skips the format and dimension check.
 
 	username       string
 	password       string
+	mu             sync.RWMutex // Guards the cookies, the login state, and the [PrivateAPI.lastBgUpload].
 	cookies        map[string]string
 	loggedIn       bool
 	recentGroups   map[string]string // Map of recently visited groups.[key=name,val=desc]
@@ -165,9 +552,10 @@ type PrivateAPI struct {
 	createdGroups  map[string]string // Map of created groups.[key=name,val=desc]
 	gcmID          string
 	gcmToken       string
+	lastBgUpload   time.Time // The time of the last message background image upload, guarded by [PrivateAPI.mu].
 }
 
-// NewPrivateAPI creates a new [chadango.PrivateAPI] instance.
+// NewPrivateAPI creates a new [chadango.PrivateAPI] instance, using the options set by [ConfigureAPI].
 //
 // Args:
 //   - username: The username of the Chatango account.
@@ -177,6 +565,20 @@ type PrivateAPI struct {
 // Returns:
 //   - *PrivateAPI: A new [PrivateAPI] instance.
 func NewPrivateAPI(username, password string, ctx context.Context) *PrivateAPI {
+	return newPrivateAPI(username, password, ctx, &defaultAPI)
+}
+
+// newPrivateAPI creates a new [chadango.PrivateAPI] instance with the options.
+//
+// Args:
+//   - username: The username of the Chatango account.
+//   - password: The password of the Chatango account.
+//   - ctx: The context for the API client.
+//   - config: The options of the API client.
+//
+// Returns:
+//   - *PrivateAPI: A new [PrivateAPI] instance.
+func newPrivateAPI(username, password string, ctx context.Context, config *apiConfig) *PrivateAPI {
 	api := &PrivateAPI{
 		username:      username,
 		password:      password,
@@ -185,6 +587,10 @@ func NewPrivateAPI(username, password string, ctx context.Context) *PrivateAPI {
 		createdGroups: make(map[string]string),
 	}
 	api.context = ctx
+	api.config = config
+	// Each account has its own cookie jar, so multiple accounts can coexist.
+	api.own = true
+	api.httpClient()
 
 	return api
 }
@@ -198,6 +604,9 @@ func NewPrivateAPI(username, password string, ctx context.Context) *PrivateAPI {
 //   - string: The value of the cookie.
 //   - bool: True if the cookie exists, false otherwise.
 func (p *PrivateAPI) GetCookie(name string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	value, ok := p.cookies[name]
 	return value, ok
 }
@@ -255,6 +664,9 @@ func (p *PrivateAPI) GetUnreadMsgCount() int {
 // Returns:
 //   - bool: True if the user is logged in, false otherwise.
 func (p *PrivateAPI) IsLoggedIn() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	return p.loggedIn
 }
 
@@ -276,9 +688,13 @@ func (p *PrivateAPI) Login() error {
 	}
 	defer res.Body.Close()
 
-	p.loggedIn = true
+	cookies := p.httpClient().Jar.Cookies(res.Request.URL)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	for _, cookie := range httpClient.Jar.Cookies(res.Request.URL) {
+	p.loggedIn = true
+	for _, cookie := range cookies {
 		p.cookies[cookie.Name] = cookie.Value
 	}
 
@@ -309,12 +725,20 @@ func (p *PrivateAPI) RegisterGCM() (err error) {
 	return
 }
 
+// publicAPI returns a [PublicAPI] sharing the context and the HTTP client of the private API.
+func (p *PrivateAPI) publicAPI() *PublicAPI {
+	api := newPublicAPI(p.context, p.config)
+	api.client = p.httpClient()
+
+	return api
+}
+
 // RetrieveMsgBg retrieves the message background of the current user.
 //
 // Returns:
 //   - error: An error if the retrieval fails.
 func (p *PrivateAPI) RetrieveMsgBg() error {
-	msgBg, err := publicAPI.GetBackground(p.username)
+	msgBg, err := p.publicAPI().GetBackground(p.username)
 	if err != nil {
 		return err
 	}
@@ -329,7 +753,7 @@ func (p *PrivateAPI) RetrieveMsgBg() error {
 // Returns:
 //   - error: An error if the retrieval fails.
 func (p *PrivateAPI) RetrieveMsgStyle() error {
-	msgStyle, err := publicAPI.GetStyle(p.username)
+	msgStyle, err := p.publicAPI().GetStyle(p.username)
 	if err != nil {
 		return err
 	}
@@ -446,6 +870,58 @@ func (p *PrivateAPI) SearchPeople(query models.PeopleQuery) (result []models.Peo
 	return
 }
 
+// SearchPeopleAll searches for people page by page, until a short page is returned or [max] results are collected.
+//
+// The pages are requested with [models.PeopleQuery.NextOffset] and the results are deduplicated by their usernames.
+// The paging also stops once a page without new results is returned.
+//
+// Args:
+//   - ctx: The context checked between the pages.
+//   - query: The search query, the [models.PeopleQuery.Amount] defaults to [PEOPLE_PAGE_SIZE] if not positive.
+//   - max: The maximum number of results, unlimited if not positive.
+//
+// Returns:
+//   - []PeopleResult: A list of search results.
+//   - error: An error if retrieving a page fails, or the [ctx] error if it is done.
+func (p *PrivateAPI) SearchPeopleAll(ctx context.Context, query models.PeopleQuery, max int) (result []models.PeopleResult, err error) {
+	if query.Amount <= 0 {
+		query.Amount = PEOPLE_PAGE_SIZE
+	}
+
+	seen := make(map[string]bool)
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var page []models.PeopleResult
+		if page, err = p.SearchPeople(query); err != nil {
+			return
+		}
+
+		added := 0
+		for _, person := range page {
+			name := strings.ToLower(person.Username)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			result = append(result, person)
+			added++
+
+			if max > 0 && len(result) >= max {
+				return
+			}
+		}
+
+		if len(page) < query.Amount || added == 0 {
+			return
+		}
+		query.NextOffset()
+	}
+}
+
 // SetGCMID sets the GCM ID of the user.
 //
 // Args:
@@ -501,6 +977,37 @@ func (p *PrivateAPI) UpdateMsgBg() (err error) {
 	return
 }
 
+// SetBackgroundColor sets a solid-color message background without an image.
+//
+// The other settings of the current [PrivateAPI.MessageBackground] (e.g. the alignment) are kept.
+//
+// Args:
+//   - hex: The background color in hexadecimal, e.g. "ff0000", "#f00".
+//   - alpha: The background color transparency, from 0 to 100.
+//
+// Returns:
+//   - error: [ErrInvalidColor] if the color or the alpha is not valid, or an error if the update fails.
+func (p *PrivateAPI) SetBackgroundColor(hex string, alpha int) error {
+	if !hexColorRe.MatchString(hex) {
+		return ErrInvalidColor
+	}
+	if alpha < 0 || alpha > 100 {
+		return fmt.Errorf("%w: the alpha %d is out of range", ErrInvalidColor, alpha)
+	}
+
+	hex = strings.ToLower(strings.TrimPrefix(hex, "#"))
+	if len(hex) == 3 {
+		// The short form is expanded, e.g. "f00" to "ff0000".
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	p.MessageBackground.Color = hex
+	p.MessageBackground.Alpha = float64(alpha)
+	p.MessageBackground.UseImage = false
+
+	return p.UpdateMsgBg()
+}
+
 // UpdateMsgStyle updates the message style of the current user.
 //
 // Returns:
@@ -522,37 +1029,54 @@ func (p *PrivateAPI) UpdateMsgStyle() (err error) {
 
 // UploadImage uploads an image to the Chatango server.
 //
+// The multipart body is streamed while uploading, so a large image is not buffered entirely in memory.
+// Unless [PrivateAPI.SkipImageValidation] is set, the image header is checked before uploading:
+// the image must be a JPEG, PNG, or GIF within [IMG_MAX_DIM] pixels.
+//
 // Args:
 //   - filename: The name of the image file.
 //   - image: The image data.
 //
 // Returns:
 //   - UploadedImage: The uploaded image information.
-//   - error: An error if the upload fails.
+//   - error: [ErrInvalidImage] if the validation fails, [ErrImageTooLarge] if the image exceeds [PrivateAPI.MaxImageSize],
+//     or an error if the upload fails.
 func (p *PrivateAPI) UploadImage(filename string, image io.Reader) (img models.UploadedImage, err error) {
-	var (
-		reqBody *bytes.Buffer
-		writer  = multipart.NewWriter(reqBody)
-		part    io.Writer
-	)
-
-	if err = writer.WriteField("u", p.username); err != nil {
-		return
+	if !p.SkipImageValidation {
+		// Keep the header read by the check, so it can be uploaded along with the rest.
+		var header bytes.Buffer
+		if err = checkImageConfig(io.TeeReader(image, &header), IMG_MAX_DIM); err != nil {
+			return
+		}
+		image = io.MultiReader(&header, image)
 	}
 
-	if err = writer.WriteField("p", p.password); err != nil {
-		return
+	maxSize := p.MaxImageSize
+	if maxSize == 0 {
+		maxSize = IMG_MAX_SIZE
 	}
 
-	if part, err = writer.CreateFormFile("filedata", filename); err != nil {
-		return
-	}
-	if _, err = io.Copy(part, image); err != nil {
-		return
-	}
+	reqBody, bodyWriter := io.Pipe()
+	writer := multipart.NewWriter(bodyWriter)
+
+	written := make(chan error, 1)
+	go func() {
+		err := writeImageForm(writer, p.username, p.password, filename, image, maxSize)
+		bodyWriter.CloseWithError(err)
+		written <- err
+	}()
 
 	var res *http.Response
 	res, err = p.PostMultipart(API_UPLOAD_IMG, reqBody, writer.FormDataContentType())
+	// Unblock the writer if the request ended before the whole body was read.
+	reqBody.Close()
+
+	if werr := <-written; err == nil && werr != nil {
+		res.Body.Close()
+		err = werr
+	} else if errors.Is(werr, ErrImageTooLarge) {
+		err = werr
+	}
 	if err != nil {
 		return
 	}
@@ -565,7 +1089,7 @@ func (p *PrivateAPI) UploadImage(filename string, image io.Reader) (img models.U
 
 	status, id, ok := strings.Cut(string(body), ":")
 	if !ok || !strings.EqualFold(status, "success") {
-		err = ErrRequestFailed
+		err = newAPIError(res.Request, res, body)
 		return
 	}
 
@@ -575,17 +1099,89 @@ func (p *PrivateAPI) UploadImage(filename string, image io.Reader) (img models.U
 	return
 }
 
+// writeImageForm writes the multipart form of [PrivateAPI.UploadImage] and closes the writer.
+//
+// Args:
+//   - writer: The multipart writer.
+//   - username: The username of the account.
+//   - password: The password of the account.
+//   - filename: The name of the image file.
+//   - image: The image data.
+//   - maxSize: The maximum size of the image in bytes, unlimited if negative.
+//
+// Returns:
+//   - error: [ErrImageTooLarge] if the image exceeds [maxSize], or an error if writing fails.
+func writeImageForm(writer *multipart.Writer, username, password, filename string, image io.Reader, maxSize int64) (err error) {
+	if err = writer.WriteField("u", username); err != nil {
+		return
+	}
+
+	if err = writer.WriteField("p", password); err != nil {
+		return
+	}
+
+	var part io.Writer
+	if part, err = writer.CreateFormFile("filedata", filename); err != nil {
+		return
+	}
+
+	if maxSize >= 0 {
+		image = io.LimitReader(image, maxSize+1)
+	}
+
+	var n int64
+	if n, err = io.Copy(part, image); err != nil {
+		return
+	}
+	if maxSize >= 0 && n > maxSize {
+		return ErrImageTooLarge
+	}
+
+	return writer.Close()
+}
+
 // UploadMsgBgImage uploads an image to be used as the message background.
 //
+// The image is validated before uploading, see [validateImage].
+// Consecutive uploads are limited to one per [MSG_BG_UPLOAD_DELAY].
+//
 // Args:
 //   - filename: The name of the image file.
 //   - image: The image data.
 //
 // Returns:
-//   - error: An error if the upload fails.
+//   - error: [ErrInvalidImage] if the validation fails, [ErrRateLimited] if uploading too fast, or an error if the upload fails.
 func (p *PrivateAPI) UploadMsgBgImage(filename string, image io.Reader) (err error) {
+	// Reserve the upload slot, so the concurrent uploads are limited as well.
+	p.mu.Lock()
+	last := p.lastBgUpload
+	if time.Since(last) < MSG_BG_UPLOAD_DELAY {
+		p.mu.Unlock()
+		return ErrRateLimited
+	}
+	p.lastBgUpload = time.Now()
+	p.mu.Unlock()
+
+	sent := false
+	defer func() {
+		if !sent {
+			// Nothing was uploaded, release the slot.
+			p.mu.Lock()
+			p.lastBgUpload = last
+			p.mu.Unlock()
+		}
+	}()
+
+	var data []byte
+	if data, err = io.ReadAll(io.LimitReader(image, MSG_BG_MAX_SIZE+1)); err != nil {
+		return
+	}
+	if err = validateImage(data, MSG_BG_MAX_SIZE, MSG_BG_MAX_DIM); err != nil {
+		return
+	}
+
 	var (
-		reqBody *bytes.Buffer
+		reqBody = &bytes.Buffer{}
 		writer  = multipart.NewWriter(reqBody)
 	)
 
@@ -601,9 +1197,14 @@ func (p *PrivateAPI) UploadMsgBgImage(filename string, image io.Reader) (err err
 	if part, err = writer.CreateFormFile("Filedata", filename); err != nil {
 		return
 	}
-	if _, err = io.Copy(part, image); err != nil {
+	if _, err = part.Write(data); err != nil {
 		return
 	}
+	if err = writer.Close(); err != nil {
+		return
+	}
+
+	sent = true
 
 	var res *http.Response
 	res, err = p.PostMultipart(API_UPD_MSG_BG, reqBody, writer.FormDataContentType())
@@ -615,12 +1216,60 @@ func (p *PrivateAPI) UploadMsgBgImage(filename string, image io.Reader) (err err
 	return
 }
 
+// validateImage checks that the data is a supported image within the size and dimension limits.
+//
+// The supported formats are JPEG, PNG, and GIF.
+//
+// Args:
+//   - data: The image data.
+//   - maxSize: The maximum size of the data in bytes.
+//   - maxDim: The maximum width and height in pixels.
+//
+// Returns:
+//   - error: [ErrInvalidImage] if the image is not valid, nil otherwise.
+func validateImage(data []byte, maxSize, maxDim int) error {
+	if len(data) == 0 || len(data) > maxSize {
+		return ErrInvalidImage
+	}
+
+	return checkImageConfig(bytes.NewReader(data), maxDim)
+}
+
+// checkImageConfig checks that the image header describes a supported image within the dimension limit.
+//
+// Only the header is read from the [r], so the image is not decoded entirely.
+//
+// Args:
+//   - r: The image data.
+//   - maxDim: The maximum width and height in pixels.
+//
+// Returns:
+//   - error: [ErrInvalidImage] if the image is not valid, nil otherwise.
+func checkImageConfig(r io.Reader, maxDim int) error {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return ErrInvalidImage
+	}
+
+	switch format {
+	case "jpeg", "png", "gif":
+	default:
+		return ErrInvalidImage
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxDim || config.Height > maxDim {
+		return ErrInvalidImage
+	}
+
+	return nil
+}
+
 // PublicAPI represents a compilation of various Chatango APIs that doesn't needs to be authenticated.
 type PublicAPI struct {
 	APIClient
 }
 
-// NewPublicAPI creates a new PublicAPI instance.
+// NewPublicAPI creates a new PublicAPI instance, using the options set by [ConfigureAPI].
 //
 // Args:
 //   - ctx: The context for the API client.
@@ -628,8 +1277,21 @@ type PublicAPI struct {
 // Returns:
 //   - *PublicAPI: A new instance of PublicAPI.
 func NewPublicAPI(ctx context.Context) *PublicAPI {
+	return newPublicAPI(ctx, &defaultAPI)
+}
+
+// newPublicAPI creates a new PublicAPI instance with the options.
+//
+// Args:
+//   - ctx: The context for the API client.
+//   - config: The options of the API client.
+//
+// Returns:
+//   - *PublicAPI: A new instance of PublicAPI.
+func newPublicAPI(ctx context.Context, config *apiConfig) *PublicAPI {
 	api := &PublicAPI{}
 	api.context = ctx
+	api.config = config
 
 	return api
 }
@@ -729,17 +1391,45 @@ func (p *PublicAPI) GetBackground(username string) (background models.MessageBac
 //
 // Returns:
 //   - FullProfile: The full profile of the specified username.
-//   - error: An error if the retrieval fails.
+//   - error: [ErrUserNotFound] if the username does not exist, or another error if the retrieval fails.
 func (p *PublicAPI) GetFullProfile(username string) (profile models.FullProfile, err error) {
 	username = strings.ToLower(username)
 
 	var res *http.Response
-	if res, err = p.Get(utils.UsernameToURL(API_MINI_XML, username), nil); err != nil {
+	if res, err = p.Get(utils.UsernameToURL(API_FULL_XML, username), nil); err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			err = ErrUserNotFound
+		}
 		return
 	}
 	defer res.Body.Close()
 
-	err = xml.NewDecoder(res.Body).Decode(&profile)
+	err = decodeProfile(res.Body, &profile)
+
+	return
+}
+
+// GetGroupInfo retrieves the owner message and the title of the specified group without joining it.
+//
+// Args:
+//   - groupname: The name of the group.
+//
+// Returns:
+//   - GroupInfo: The information of the specified group.
+//   - error: [ErrGroupNotFound] if the group does not exist, or another error if the retrieval fails.
+func (p *PublicAPI) GetGroupInfo(groupname string) (info models.GroupInfo, err error) {
+	groupname = strings.ToLower(groupname)
+
+	var res *http.Response
+	if res, err = p.Get(utils.UsernameToURL(API_GROUP_INFO, groupname), nil); err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			err = ErrGroupNotFound
+		}
+		return
+	}
+	defer res.Body.Close()
+
+	err = json.NewDecoder(res.Body).Decode(&info)
 
 	return
 }
@@ -751,17 +1441,20 @@ func (p *PublicAPI) GetFullProfile(username string) (profile models.FullProfile,
 //
 // Returns:
 //   - MiniProfile: The mini profile of the specified username.
-//   - error: An error if the retrieval fails.
+//   - error: [ErrUserNotFound] if the username does not exist, or another error if the retrieval fails.
 func (p *PublicAPI) GetMiniProfile(username string) (profile models.MiniProfile, err error) {
 	username = strings.ToLower(username)
 
 	var res *http.Response
 	if res, err = p.Get(utils.UsernameToURL(API_MINI_XML, username), nil); err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			err = ErrUserNotFound
+		}
 		return
 	}
 	defer res.Body.Close()
 
-	if err = xml.NewDecoder(res.Body).Decode(&profile); err != nil {
+	if err = decodeProfile(res.Body, &profile); err != nil {
 		return
 	}
 
@@ -770,6 +1463,44 @@ func (p *PublicAPI) GetMiniProfile(username string) (profile models.MiniProfile,
 	return
 }
 
+// decodeProfile decodes the profile XML from the reader into the [v].
+//
+// A non-existing username yields an HTML error page or an empty body instead of the "mod" XML document,
+// so anything that is not a well-formed "mod" document is reported as [ErrUserNotFound].
+//
+// Args:
+//   - r: The reader of the response body.
+//   - v: The profile to decode into.
+//
+// Returns:
+//   - error: [ErrUserNotFound] if the body is not a profile document, or an error if reading fails.
+func decodeProfile(r io.Reader, v any) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ErrUserNotFound
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local != "mod" {
+				return ErrUserNotFound
+			}
+			break
+		}
+	}
+
+	if err = xml.Unmarshal(body, v); err != nil {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
 // GetStyle retrieves the message style of the specified username.
 //
 // Args:
@@ -827,19 +1558,3 @@ func (p *PublicAPI) IsGroup(groupname string) (answer bool, err error) {
 
 	return
 }
-
-var (
-	privateAPI *PrivateAPI
-	publicAPI  *PublicAPI
-)
-
-// initAPI initializes the API clients with the provided username, password, and context.
-//
-// Args:
-//   - username: The username for the private API.
-//   - password: The password for the private API.
-//   - ctx: The context for the API clients.
-func initAPI(username, password string, ctx context.Context) {
-	privateAPI = NewPrivateAPI(username, password, ctx)
-	publicAPI = NewPublicAPI(ctx)
-}
@@ -0,0 +1,492 @@
+package chadango
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n0h4rt/chadango/models"
+	"github.com/n0h4rt/chadango/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{
+			name:    "ValidProfile",
+			body:    `<?xml version="1.0" ?><mod><body>hello%20world</body><s>M</s><b>2000-01-02</b><l c="ID" g="" lat="-6.2" lon="106.8">Jakarta</l><d></d></mod>`,
+			wantErr: nil,
+		},
+		{
+			name:    "HTMLErrorPage",
+			body:    "<!DOCTYPE html><html><head><title>404 Not Found</title></head><body><h1>Not Found</h1></body></html>",
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name:    "EmptyBody",
+			body:    "",
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name:    "MalformedXML",
+			body:    "<mod><body>unterminated",
+			wantErr: ErrUserNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var profile models.MiniProfile
+			err := decodeProfile(strings.NewReader(tt.body), &profile)
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, "hello world", string(profile.Body))
+				assert.Equal(t, "Indonesia", profile.Location.CountryName())
+			}
+		})
+	}
+}
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)))
+	assert.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+func TestValidateImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"ValidImage", encodeTestPNG(t, 100, 100), nil},
+		{"TooWide", encodeTestPNG(t, MSG_BG_MAX_DIM+1, 10), ErrInvalidImage},
+		{"TooTall", encodeTestPNG(t, 10, MSG_BG_MAX_DIM+1), ErrInvalidImage},
+		{"TooLarge", append(encodeTestPNG(t, 10, 10), make([]byte, MSG_BG_MAX_SIZE)...), ErrInvalidImage},
+		{"NotAnImage", []byte("definitely not an image"), ErrInvalidImage},
+		{"Empty", nil, ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, validateImage(tt.data, MSG_BG_MAX_SIZE, MSG_BG_MAX_DIM))
+		})
+	}
+}
+
+func TestPrivateAPI_SetBackgroundColorValidation(t *testing.T) {
+	for _, hex := range []string{"ff0000", "#FF0000", "f00", "#abc"} {
+		assert.True(t, hexColorRe.MatchString(hex), "%q should be a valid color", hex)
+	}
+
+	api := &PrivateAPI{}
+	for _, hex := range []string{"", "red", "ff00", "#ff00000", "gg0000"} {
+		assert.ErrorIs(t, api.SetBackgroundColor(hex, 100), ErrInvalidColor, "%q should be rejected", hex)
+	}
+	for _, alpha := range []int{-1, 101} {
+		assert.ErrorIs(t, api.SetBackgroundColor("f00", alpha), ErrInvalidColor, "The alpha %d should be rejected", alpha)
+	}
+	assert.Empty(t, api.MessageBackground.Color, "A rejected color should not be applied")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	api = NewPrivateAPI("user", "pass", context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+	assert.NoError(t, api.SetBackgroundColor("#F0a", 50))
+	assert.Equal(t, "ff00aa", api.MessageBackground.Color, "The short form should be expanded")
+	assert.Equal(t, float64(50), api.MessageBackground.Alpha)
+}
+
+func TestApplication_SeparateAPIs(t *testing.T) {
+	app1 := New(&Config{Username: "user1", Password: "pass1"})
+	app2 := New(&Config{Username: "user2", Password: "pass2"})
+	app1.initAPI(context.Background())
+	app2.initAPI(context.Background())
+
+	assert.NotSame(t, app1.PrivateAPI(), app2.PrivateAPI(), "Each application should have its own private API")
+	assert.NotSame(t, app1.PrivateAPI().httpClient(), app2.PrivateAPI().httpClient(), "Each account should have its own HTTP client")
+	assert.NotSame(t, app1.PrivateAPI().httpClient().Jar, app2.PrivateAPI().httpClient().Jar, "Each account should have its own cookie jar")
+	assert.Equal(t, "user1", app1.PrivateAPI().username)
+	assert.Equal(t, "user2", app2.PrivateAPI().username)
+	assert.Same(t, app1.api.sharedClient(), app1.PublicAPI().httpClient(), "The public API should use the shared HTTP client of the application")
+	assert.NotSame(t, app1.PublicAPI().httpClient(), app2.PublicAPI().httpClient(), "Each application should have its own shared HTTP client")
+}
+
+func TestApplication_ConfigureAPI(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	app1 := New(&Config{Username: "user1", API: APIOptions{Transport: &redirectTransport{target: target}, UserAgent: "app1"}})
+	app2 := New(&Config{Username: "user2"})
+	app1.initAPI(context.Background())
+	app2.initAPI(context.Background())
+
+	assert.Equal(t, "app1", app1.PublicAPI().httpClient().Transport.(*Transport).Headers["User-Agent"], "The configured options should be applied")
+	assert.Equal(t, API_USER_AGENT, app2.PublicAPI().httpClient().Transport.(*Transport).Headers["User-Agent"], "The other application should keep the defaults")
+	assert.Equal(t, API_USER_AGENT, defaultAPI.sharedClient().Transport.(*Transport).Headers["User-Agent"], "The default options should be untouched")
+
+	jar := app1.PrivateAPI().httpClient().Jar
+	app1.ConfigureAPI(APIOptions{Transport: &redirectTransport{target: target}, UserAgent: "app1/2"})
+	for _, api := range []*APIClient{&app1.PrivateAPI().APIClient, &app1.PublicAPI().APIClient} {
+		res, err := api.Get("https://chatango.com/", nil)
+		if assert.NoError(t, err) {
+			res.Body.Close()
+			assert.Equal(t, "app1/2", (<-headers).Get("User-Agent"), "The options should reach the existing clients")
+		}
+	}
+	assert.Same(t, jar, app1.PrivateAPI().httpClient().Jar, "The cookies should be kept")
+	assert.Equal(t, "app1/2", app1.Config.API.UserAgent)
+
+	app2.SetAPITransport(&redirectTransport{target: target})
+	res, err := app2.PrivateAPI().Get("https://chatango.com/", nil)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, API_USER_AGENT, (<-headers).Get("User-Agent"), "The other options should be kept")
+	}
+	assert.Same(t, http.DefaultTransport, defaultAPI.sharedClient().Transport.(*Transport).Transport, "The default transport should be untouched")
+}
+
+// redirectTransport sends every request to the target server instead.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSetAPITransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Origin")))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	SetAPITransport(&redirectTransport{target: target})
+	defer SetAPITransport(nil)
+
+	api := NewPrivateAPI("user", "pass", context.Background())
+	for _, client := range []*http.Client{defaultAPI.sharedClient(), api.httpClient()} {
+		res, err := client.Get("https://chatango.com/")
+		if assert.NoError(t, err) {
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			assert.Equal(t, "https://st.chatango.com", string(body), "The custom headers should be applied on top of the transport")
+		}
+	}
+
+	jar := defaultAPI.sharedClient().Jar
+	SetAPITransport(nil)
+	assert.Same(t, http.DefaultTransport, defaultAPI.sharedClient().Transport.(*Transport).Transport)
+	assert.Same(t, jar, defaultAPI.sharedClient().Jar, "The cookies should be kept")
+}
+
+func TestConfigureAPI(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	defer server.Close()
+
+	assert.Equal(t, API_TIMEOUT, defaultAPI.sharedClient().Timeout, "The defaults should be kept")
+
+	target, _ := url.Parse(server.URL)
+	custom := map[string]string{"X-Test": "1"}
+	ConfigureAPI(APIOptions{Transport: &redirectTransport{target: target}, Timeout: time.Minute, UserAgent: "chadango/test", Headers: custom})
+	defer ConfigureAPI(APIOptions{})
+	custom["X-Test"] = "2"
+
+	client := defaultAPI.sharedClient()
+	assert.Equal(t, time.Minute, client.Timeout)
+	res, err := client.Get("https://chatango.com/")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		header := <-headers
+		assert.Equal(t, "chadango/test", header.Get("User-Agent"))
+		assert.Equal(t, API_ORIGIN, header.Get("Origin"), "The unset options should keep the defaults")
+		assert.Equal(t, "1", header.Get("X-Test"), "The headers should be copied")
+	}
+
+	ConfigureAPI(APIOptions{})
+	assert.Equal(t, API_USER_AGENT, defaultAPI.sharedClient().Transport.(*Transport).Headers["User-Agent"])
+	assert.Same(t, http.DefaultTransport, defaultAPI.sharedClient().Transport.(*Transport).Transport, "The zero value should restore the default transport")
+	assert.Same(t, client.Jar, defaultAPI.sharedClient().Jar, "The cookies should be kept")
+}
+
+func TestAPIClient_Retry(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n := hits.Add(1); {
+		case r.URL.Path == "/notfound":
+			http.NotFound(w, r)
+		case n%2 == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	ConfigureAPI(APIOptions{RetryBackoff: 10 * time.Millisecond})
+	defer ConfigureAPI(APIOptions{})
+
+	target, _ := url.Parse(server.URL)
+	api := NewPublicAPI(context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+
+	res, err := api.Get("https://chatango.com/flaky", nil)
+	if assert.NoError(t, err, "A GET should be retried after a 503") {
+		res.Body.Close()
+		assert.Equal(t, int32(2), hits.Load())
+	}
+
+	hits.Store(0)
+	_, err = api.Get("https://chatango.com/notfound", nil)
+	assert.ErrorIs(t, err, ErrRequestFailed)
+	assert.Equal(t, int32(1), hits.Load(), "A 4xx should not be retried")
+
+	hits.Store(0)
+	_, err = api.PostForm("https://chatango.com/flaky", url.Values{"a": {"1"}})
+	assert.ErrorIs(t, err, ErrRequestFailed)
+	assert.Equal(t, int32(1), hits.Load(), "A POST should not be retried by default")
+
+	ConfigureAPI(APIOptions{RetryBackoff: 10 * time.Millisecond, RetryPOST: true})
+	hits.Store(0)
+	res, err = api.PostForm("https://chatango.com/flaky", url.Values{"a": {"1"}})
+	if assert.NoError(t, err, "A POST should be retried when opted in") {
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, "a=1", string(body), "The body should be replayed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ConfigureAPI(APIOptions{RetryBackoff: time.Hour})
+	api = NewPublicAPI(ctx)
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	hits.Store(0)
+	start := time.Now()
+	_, err = api.Get("https://chatango.com/flaky", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second, "The backoff should respect the request context")
+}
+
+func TestAPIClient_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("denied " + strings.Repeat("x", API_ERROR_BODY_SIZE)))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	api := NewPublicAPI(context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+
+	_, err := api.Get("https://chatango.com/check", url.Values{"q": {"1"}})
+	assert.ErrorIs(t, err, ErrRequestFailed, "The sentinel should still match")
+
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+		assert.Equal(t, "GET", apiErr.Method)
+		assert.Equal(t, "https://chatango.com/check?q=1", apiErr.URL)
+		assert.Len(t, apiErr.Body, API_ERROR_BODY_SIZE, "The body should be truncated")
+		assert.True(t, strings.HasPrefix(apiErr.Body, "denied "))
+		assert.Contains(t, err.Error(), "403 Forbidden")
+	}
+}
+
+func TestPrivateAPI_SearchPeopleAll(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		from, _ := strconv.Atoi(form.Get("f"))
+		to, _ := strconv.Atoi(form.Get("t"))
+
+		// The pages overlap by one entry, which should be deduplicated.
+		var entries []string
+		for i := utils.Max(0, from-1); i < to && i < 45; i++ {
+			entries = append(entries, fmt.Sprintf("user%d;%d", i, i%2))
+		}
+		w.Write([]byte("h=" + strings.Join(entries, ",")))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	api := NewPrivateAPI("user", "pass", context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+
+	result, err := api.SearchPeopleAll(context.Background(), models.PeopleQuery{}, 0)
+	if assert.NoError(t, err) {
+		assert.Len(t, result, 45)
+		assert.Equal(t, "user44", result[44].Username)
+		assert.True(t, result[1].IsOnline)
+	}
+
+	result, err = api.SearchPeopleAll(context.Background(), models.PeopleQuery{Amount: 10}, 15)
+	if assert.NoError(t, err) {
+		assert.Len(t, result, 15, "The results should be capped at max")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	requests.Store(0)
+	_, err = api.SearchPeopleAll(ctx, models.PeopleQuery{}, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, requests.Load(), "Nothing should be requested once ctx is done")
+}
+
+func TestPrivateAPI_UploadImage(t *testing.T) {
+	data := encodeTestPNG(t, 10, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("filedata")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		uploaded, _ := io.ReadAll(file)
+
+		if r.FormValue("u") != "User" || r.FormValue("p") != "pass" || header.Filename != "test.png" || !bytes.Equal(uploaded, data) {
+			w.Write([]byte("error:bad form"))
+			return
+		}
+		w.Write([]byte("success:123"))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	api := NewPrivateAPI("User", "pass", context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+
+	img, err := api.UploadImage("test.png", bytes.NewReader(data))
+	if assert.NoError(t, err, "The multipart fields should be present") {
+		assert.Equal(t, 123, img.ID)
+		assert.Equal(t, "user", img.Username)
+	}
+
+	api.MaxImageSize = int64(len(data) - 1)
+	_, err = api.UploadImage("test.png", bytes.NewReader(data))
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
+
+func TestPrivateAPI_UploadImageValidation(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("success:1"))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	api := NewPrivateAPI("user", "pass", context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"></svg>`
+	for name, data := range map[string][]byte{
+		"SVG":     []byte(svg),
+		"Text":    []byte("definitely not an image"),
+		"TooWide": encodeTestPNG(t, IMG_MAX_DIM+1, 1),
+	} {
+		_, err := api.UploadImage("test", bytes.NewReader(data))
+		assert.ErrorIs(t, err, ErrInvalidImage, "%s should be rejected", name)
+	}
+	assert.Zero(t, requests.Load(), "Invalid images should be rejected before hitting the network")
+
+	_, err := api.UploadImage("test.png", bytes.NewReader(encodeTestPNG(t, 10, 10)))
+	assert.NoError(t, err, "A valid PNG should pass")
+
+	api.SkipImageValidation = true
+	_, err = api.UploadImage("test.svg", strings.NewReader(svg))
+	assert.NoError(t, err, "The validation should be skippable")
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestPublicAPI_GetGroupInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/groupinfo/t/e/testgroup/gprofile.json":
+			w.Write([]byte(`{"ownr_msg": "Welcome%20to%20%3Cb%3Ethe%3C%2Fb%3E%20group", "title": "Test%20Group"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	api := NewPublicAPI(context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+
+	info, err := api.GetGroupInfo("TestGroup")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Group", info.GetTitle())
+	assert.Equal(t, "Welcome to <b>the</b> group", info.GetMessage())
+
+	_, err = api.GetGroupInfo("nosuchgroup")
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+}
+
+func TestPrivateAPI_UploadMsgBgImageRateLimit(t *testing.T) {
+	var uploads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads.Add(1)
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	api := NewPrivateAPI("user", "pass", context.Background())
+	api.client = &http.Client{Transport: &redirectTransport{target: target}}
+
+	// An invalid image is not uploaded, so it does not count.
+	assert.ErrorIs(t, api.UploadMsgBgImage("bg.png", strings.NewReader("not an image")), ErrInvalidImage)
+
+	data := encodeTestPNG(t, 10, 10)
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- api.UploadMsgBgImage("bg.png", bytes.NewReader(data)) }()
+	}
+
+	var limited int
+	for i := 0; i < 2; i++ {
+		if err := <-errs; errors.Is(err, ErrRateLimited) {
+			limited++
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+	assert.Equal(t, 1, limited, "Only one of the concurrent uploads should pass")
+	assert.Equal(t, int32(1), uploads.Load())
+}
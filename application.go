@@ -2,11 +2,16 @@ package chadango
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/n0h4rt/chadango/models"
 	"github.com/n0h4rt/chadango/utils"
@@ -19,15 +24,26 @@ import (
 // connecting to groups and private messages, and handling events and errors.
 // The application also manages data persistence and provides access to the public and private APIs.
 type Application struct {
-	Config        *Config                 // Config holds the configuration for the pplication.
-	persistence   Persistence             // Persistence manages data persistence for the application.
-	Private       Private                 // Private represents the private chat functionality of the application.
-	Groups        SyncMap[string, *Group] // Groups stores the groups the application is connected to.
-	eventHandlers []Handler               // eventHandlers contains the registered event handlers for the application.
-	errorHandlers []Handler               // errorHandlers contains the registered error handlers for the application.
-	context       context.Context         // Context for running the application.
-	cancelCtx     context.CancelFunc      // Function for stopping the application.
-	initialized   bool                    // initialized indicates whether the application has been initialized.
+	Config        *Config                        // Config holds the configuration for the pplication.
+	persistence   Persistence                    // Persistence manages data persistence for the application.
+	metrics       Metrics                        // metrics collects the application metrics, see [Application.UseMetrics].
+	Private       Private                        // Private represents the private chat functionality of the application.
+	Groups        SyncMap[string, *Group]        // Groups stores the groups the application is connected to.
+	joining       SyncMap[string, chan struct{}] // joining stores the groups being joined, the channel is closed once the join finishes.
+	eventHandlers []Handler                      // eventHandlers contains the registered event handlers for the application.
+	errorHandlers []Handler                      // errorHandlers contains the registered error handlers for the application.
+	doubleFault   func(any, any)                 // doubleFault is called when an error handler panics while handling an error.
+	giveUp        func(string, bool, int)        // giveUp is called when a reconnection gives up after exhausting the retries.
+	premium       premiumCache                   // premium caches the premium status of the logged-in account.
+	privateAPI    *PrivateAPI                    // privateAPI is the authenticated API client of the account.
+	privateAPIs   SyncMap[string, *PrivateAPI]   // privateAPIs stores the authenticated API clients keyed by the lowercased usernames.
+	publicAPI     *PublicAPI                     // publicAPI is the unauthenticated API client.
+	api           apiConfig                      // api holds the options of the API clients, see [Config.API].
+	readyOnce     sync.Once                      // readyOnce ensures the [OnReady] event is dispatched once.
+	deferReady    atomic.Bool                    // deferReady defers the [OnReady] event until [Application.Start] completes the wait, see [StartOptions.WaitReady].
+	context       context.Context                // Context for running the application.
+	cancelCtx     context.CancelFunc             // Function for stopping the application.
+	initialized   bool                           // initialized indicates whether the application has been initialized.
 }
 
 // AddHandler adds a new handler to the application.
@@ -38,7 +54,7 @@ type Application struct {
 // Returns:
 //   - *Application: The application instance for method chaining.
 func (app *Application) AddHandler(handler Handler) *Application {
-	if ch, ok := handler.(*CommandHandler); ok {
+	if ch, ok := commandHandler(handler); ok {
 		ch.app = app
 	}
 
@@ -68,7 +84,7 @@ func (app *Application) RemoveHandler(handler Handler) *Application {
 // Returns:
 //   - *Application: The application instance for method chaining.
 func (app *Application) AddErrorHandler(handler Handler) *Application {
-	if ch, ok := handler.(*CommandHandler); ok {
+	if ch, ok := commandHandler(handler); ok {
 		ch.app = app
 	}
 
@@ -90,6 +106,210 @@ func (app *Application) RemoveErrorHandler(handler Handler) *Application {
 	return app
 }
 
+// Commands returns the command names of every registered [CommandHandler].
+//
+// Returns:
+//   - []string: The registered command names, in the order their handlers were added.
+func (app *Application) Commands() (commands []string) {
+	for _, handler := range app.eventHandlers {
+		if ch, ok := commandHandler(handler); ok {
+			commands = append(commands, ch.Commands...)
+		}
+	}
+
+	return
+}
+
+// SuggestCommand finds the registered command closest to the given input.
+//
+// The closeness is measured by the [utils.Similarity] of the edit distance.
+// A suggestion is only returned if its similarity reaches [SUGGEST_THRESHOLD].
+//
+// Args:
+//   - input: The (possibly mistyped) command name.
+//
+// Returns:
+//   - string: The closest registered command name.
+//   - bool: True if a suggestion was found, false otherwise.
+func (app *Application) SuggestCommand(input string) (suggestion string, ok bool) {
+	best := 0.0
+	for _, command := range app.Commands() {
+		if score := utils.Similarity(input, command); score >= SUGGEST_THRESHOLD && score > best {
+			best = score
+			suggestion = command
+			ok = true
+		}
+	}
+
+	return
+}
+
+// HelpText builds a help text from every registered [CommandHandler].
+//
+// Each handler produces one line containing its prefixed command names, its usage, and its description.
+//
+// Returns:
+//   - string: The aggregated help text.
+func (app *Application) HelpText() string {
+	var sb strings.Builder
+
+	for _, handler := range app.eventHandlers {
+		ch, ok := commandHandler(handler)
+		if !ok || len(ch.Commands) == 0 {
+			continue
+		}
+
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+
+		for i, command := range ch.Commands {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(app.Config.Prefix + command)
+		}
+		if ch.Usage != "" {
+			sb.WriteString(" " + ch.Usage)
+		}
+		if ch.Description != "" {
+			sb.WriteString(" - " + ch.Description)
+		}
+	}
+
+	return sb.String()
+}
+
+// SetDoubleFaultHandler sets the function to call when an error handler panics while handling an error.
+//
+// This is the last-resort safety net; without it, such failures are only logged.
+//
+// Args:
+//   - handler: The function receiving the original error and the error raised by the error handler.
+//
+// Returns:
+//   - *Application: The application instance for method chaining.
+func (app *Application) SetDoubleFaultHandler(handler func(original, secondary any)) *Application {
+	app.doubleFault = handler
+
+	return app
+}
+
+// SetReconnectGiveUpHandler sets the handler called when a group or private chat gives up reconnecting.
+//
+// Unlike [OnGroupLeft] and [OnPrivateDisconnected], which are also dispatched on an intentional disconnect,
+// the handler is called only after all reconnect attempts have failed.
+// The [OnGroupLeft] or [OnPrivateDisconnected] event is still dispatched afterwards.
+//
+// Args:
+//   - handler: The function to call with the group name (or the private name), whether it is a private chat, and the retry count.
+//
+// Returns:
+//   - *Application: The application instance for method chaining.
+func (app *Application) SetReconnectGiveUpHandler(handler func(name string, isPrivate bool, retries int)) *Application {
+	app.giveUp = handler
+
+	return app
+}
+
+// maxRetries returns the maximum reconnect attempts configured for the application.
+//
+// Returns:
+//   - int: The [Config.MaxRetry] if positive, [MAX_RETRIES] otherwise.
+func (app *Application) maxRetries() int {
+	if app.Config != nil && app.Config.MaxRetry > 0 {
+		return app.Config.MaxRetry
+	}
+
+	return MAX_RETRIES
+}
+
+// connectTimeout returns the timeout of the connect sequence configured for the application.
+//
+// Returns:
+//   - time.Duration: The [Config.ConnectTimeout] if positive, [CONNECT_TIMEOUT] otherwise.
+func (app *Application) connectTimeout() time.Duration {
+	if app.Config != nil && app.Config.ConnectTimeout > 0 {
+		return app.Config.ConnectTimeout
+	}
+
+	return CONNECT_TIMEOUT
+}
+
+// reconnectGaveUp calls the reconnect give-up handler, if any.
+//
+// Args:
+//   - name: The name of the group or the private chat.
+//   - isPrivate: Whether it is a private chat.
+//   - retries: The number of failed reconnect attempts.
+func (app *Application) reconnectGaveUp(name string, isPrivate bool, retries int) {
+	log.Error().Str("Name", name).Int("Retries", retries).Msg("Reconnect gave up")
+
+	if app.giveUp != nil {
+		app.giveUp(name, isPrivate, retries)
+	}
+}
+
+// premiumCache caches the premium status of the logged-in account.
+type premiumCache struct {
+	sync.Mutex
+	expire  time.Time // expire is the expiration time of the premium status.
+	checked time.Time // checked is the time when the premium status was retrieved.
+}
+
+// IsPremium reports whether the logged-in account is premium.
+//
+// Premium is account-wide, so the status is retrieved once from a connected group ([Group.GetPremiumInfo]),
+// or from the account profile if there is no connected group, then cached for [PREMIUM_CACHE_TTL].
+//
+// Returns:
+//   - bool: True if the account is premium.
+//   - time.Time: The expiration time of the premium status.
+//   - error: An error if retrieving the premium status fails.
+func (app *Application) IsPremium() (bool, time.Time, error) {
+	app.premium.Lock()
+	defer app.premium.Unlock()
+
+	if time.Since(app.premium.checked) > PREMIUM_CACHE_TTL {
+		expire, err := app.retrievePremium()
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		app.premium.expire = expire
+		app.premium.checked = time.Now()
+	}
+
+	return app.premium.expire.After(time.Now()), app.premium.expire, nil
+}
+
+// retrievePremium retrieves the expiration time of the premium status of the logged-in account.
+func (app *Application) retrievePremium() (expire time.Time, err error) {
+	var group *Group
+	app.Groups.Range(func(_ string, g *Group) bool {
+		if g.Connected {
+			group = g
+			return false
+		}
+		return true
+	})
+
+	if group != nil {
+		_, expire, err = group.GetPremiumInfo()
+		return
+	}
+
+	if app.Config.Username == "" || app.publicAPI == nil {
+		return expire, ErrNotConnected
+	}
+
+	var profile models.MiniProfile
+	if profile, err = app.publicAPI.GetMiniProfile(app.Config.Username); err != nil {
+		return
+	}
+
+	return time.Time(profile.Premium), nil
+}
+
 // UsePersistence enables the persistence layer for the application.
 //
 // Args:
@@ -103,11 +323,37 @@ func (app *Application) UsePersistence(persistence Persistence) *Application {
 	return app
 }
 
+// UseMetrics sets the metrics hooks for the application.
+//
+// Passing nil restores the default, which discards everything.
+//
+// Args:
+//   - metrics: The metrics hooks to use for the application.
+//
+// Returns:
+//   - *Application: The application instance for method chaining.
+func (app *Application) UseMetrics(metrics Metrics) *Application {
+	app.metrics = metrics
+
+	return app
+}
+
+// getMetrics returns the metrics hooks, or a no-op one if none is set.
+func (app *Application) getMetrics() Metrics {
+	if app.metrics == nil {
+		return noopMetrics{}
+	}
+
+	return app.metrics
+}
+
 // dispatchEvent dispatches an event to the appropriate handler.
 //
 // Args:
 //   - event: The event to dispatch.
 func (app *Application) dispatchEvent(event *Event) {
+	app.getMetrics().IncEvent(event.Type)
+
 	var context *Context
 
 	for _, handler := range app.eventHandlers {
@@ -165,10 +411,14 @@ func (app *Application) dispatchError(event *Event) {
 			func() {
 				defer func() {
 					if err := recover(); err != nil {
+						if app.doubleFault != nil {
+							app.doubleFault(event.Error, err)
+							return
+						}
 						log.Error().
 							Str("Event", event.Type.String()).
-							AnErr("Origin", event.Error.(error)).
-							AnErr("Current", err.(error)).
+							Interface("Origin", event.Error).
+							Interface("Current", err).
 							Msg("Another error occured during handling an error.")
 					}
 				}()
@@ -216,37 +466,204 @@ func (app *Application) checkConfig() {
 	}
 }
 
+// StartOptions holds the options of [Application.Start].
+type StartOptions struct {
+	// WaitReady joins the configured groups before returning, and waits each of them with [Group.WaitReady].
+	// The [OnReady] event is dispatched once the wait completes, regardless of the failed groups,
+	// with the aggregated error of the groups that failed to become ready as its Error.
+	WaitReady bool
+
+	// WaitReadyTimeout is the maximum duration to wait for the groups to be ready, defaults to [WAIT_READY_TIMEOUT] if not positive.
+	WaitReadyTimeout time.Duration
+}
+
 // Start starts the application.
 //
 // Args:
 //   - ctx: The context for running the application.
+//   - opts: Optional settings, only the first one is used, see [StartOptions].
 //
 // Returns:
 //   - *Application: The application instance for method chaining.
-func (app *Application) Start(ctx context.Context) *Application {
+func (app *Application) Start(ctx context.Context, opts ...StartOptions) *Application {
+	var options StartOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	app.run(ctx, options)
+
+	return app
+}
+
+// StartAndWaitReady starts the application and waits until all the configured groups are ready.
+//
+// It is a blocking shorthand for [Application.Start] with [StartOptions.WaitReady],
+// which also returns the error dispatched along with the [OnReady] event.
+//
+// Args:
+//   - ctx: The context for running the application.
+//   - timeout: The maximum duration to wait for the groups to be ready, defaults to [WAIT_READY_TIMEOUT] if not positive.
+//
+// Returns:
+//   - error: An aggregated error listing the groups that failed to become ready, nil if all of them are ready.
+func (app *Application) StartAndWaitReady(ctx context.Context, timeout time.Duration) error {
+	return app.run(ctx, StartOptions{WaitReady: true, WaitReadyTimeout: timeout})
+}
+
+// run starts the application with the options, see [Application.Start].
+//
+// Args:
+//   - ctx: The context for running the application.
+//   - options: The start options.
+//
+// Returns:
+//   - error: The aggregated error of the groups that failed to become ready if [StartOptions.WaitReady] is set, nil otherwise.
+func (app *Application) run(ctx context.Context, options StartOptions) (err error) {
 	if !app.initialized {
 		panic("the application is not initialized")
 	}
 
+	app.start(ctx)
+
+	if options.WaitReady {
+		app.deferReady.Store(true)
+	} else {
+		for _, groupName := range app.Config.Groups {
+			go app.JoinGroup(groupName)
+		}
+	}
+	if app.Config.EnablePM {
+		go app.ConnectPM()
+	}
+
+	app.persistence.Runner(app.context)
+
+	app.dispatchEvent(&Event{Type: OnStart})
+
+	if options.WaitReady {
+		timeout := options.WaitReadyTimeout
+		if timeout <= 0 {
+			timeout = WAIT_READY_TIMEOUT
+		}
+		err = app.joinAndWaitReady(timeout)
+
+		app.deferReady.Store(false)
+		app.dispatchReady(err)
+	}
+
+	return
+}
+
+// start sets up the application context and the API clients.
+func (app *Application) start(ctx context.Context) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	app.context, app.cancelCtx = context.WithCancel(ctx)
 
-	initAPI(app.Config.Username, app.Config.Password, ctx)
+	app.initAPI(ctx)
+}
 
-	for _, groupName := range app.Config.Groups {
-		go app.JoinGroup(groupName)
+// initAPI initializes the API clients of the application with the configured credentials.
+//
+// The API clients and their options belong to the application,
+// so multiple applications with different accounts and options can coexist.
+//
+// Args:
+//   - ctx: The context for the API clients.
+func (app *Application) initAPI(ctx context.Context) {
+	app.api.set(app.Config.API)
+	app.privateAPI = newPrivateAPI(app.Config.Username, app.Config.Password, ctx, &app.api)
+	app.publicAPI = newPublicAPI(ctx, &app.api)
+
+	if app.Config.Username != "" {
+		app.privateAPIs.Set(strings.ToLower(app.Config.Username), app.privateAPI)
 	}
-	if app.Config.EnablePM {
-		go app.ConnectPM()
+}
+
+// addPrivateAPI adds an authenticated API client for the account, unless there is one already.
+//
+// The new API client is logged in before being added, so a concurrent join of the same account waits for it.
+//
+// Args:
+//   - username: The username of the account.
+//   - password: The password of the account.
+//
+// Returns:
+//   - *PrivateAPI: The API client of the account.
+//   - error: An error if the login fails.
+func (app *Application) addPrivateAPI(username, password string) (*PrivateAPI, error) {
+	app.privateAPIs.Lock()
+	defer app.privateAPIs.Unlock()
+
+	key := strings.ToLower(username)
+	if api, ok := app.privateAPIs.M[key]; ok {
+		return api, nil
 	}
 
-	app.persistence.Runner(app.context)
+	api := newPrivateAPI(username, password, app.context, &app.api)
+	if err := api.Login(); err != nil {
+		return nil, err
+	}
+	app.privateAPIs.M[key] = api
 
-	app.dispatchEvent(&Event{Type: OnStart})
+	return api, nil
+}
 
-	return app
+// joinAndWaitReady joins the configured groups and waits until all of them are ready, see [StartOptions.WaitReady].
+//
+// Args:
+//   - timeout: The maximum duration to wait for the groups to be ready.
+//
+// Returns:
+//   - error: An aggregated error listing the groups that failed to become ready, nil if all of them are ready.
+func (app *Application) joinAndWaitReady(timeout time.Duration) error {
+	groupNames := app.Config.Groups
+
+	waitCtx, cancel := context.WithTimeout(app.context, timeout)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make(chan result, len(groupNames))
+	for _, groupName := range groupNames {
+		go func(groupName string) {
+			err := app.JoinGroup(groupName)
+			if err == nil {
+				if group, ok := app.Groups.Get(strings.ToLower(groupName)); ok {
+					err = group.WaitReady(waitCtx)
+				}
+			}
+			results <- result{groupName, err}
+		}(groupName)
+	}
+
+	var errs []error
+	pending := make(map[string]bool, len(groupNames))
+	for _, groupName := range groupNames {
+		pending[groupName] = true
+	}
+
+	for len(pending) > 0 {
+		select {
+		case r := <-results:
+			delete(pending, r.name)
+			if r.err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.name, r.err))
+			}
+		case <-waitCtx.Done():
+			for groupName := range pending {
+				errs = append(errs, fmt.Errorf("%s: %w", groupName, ErrTimeout))
+			}
+			pending = nil
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // Park waits for the application to stop or receive an interrupt signal.
@@ -262,6 +679,8 @@ func (app *Application) Park() {
 }
 
 // Stop stops the application.
+//
+// Each group is disconnected once its in-flight sends finish, waiting up to [DRAIN_TIMEOUT].
 func (app *Application) Stop() {
 	app.dispatchEvent(&Event{Type: OnStop})
 
@@ -274,9 +693,13 @@ func (app *Application) Stop() {
 	}()
 
 	cb := func(_ string, group *Group) bool {
+		if app.Config.PersistMessages {
+			group.saveMessages(app.persistence)
+		}
+
 		wg.Add(1)
 		go func() {
-			group.Disconnect()
+			group.DrainAndDisconnect(DRAIN_TIMEOUT)
 			wg.Done()
 		}()
 		return true
@@ -290,18 +713,74 @@ func (app *Application) Stop() {
 
 // JoinGroup joins a group in the application.
 //
+// The first successful join dispatches the [OnReady] event, unless [Application.Start] is waiting for the groups, see [StartOptions.WaitReady].
+//
 // Args:
 //   - groupName: The name of the group to join.
 //
 // Returns:
 //   - error: An error if the group cannot be joined.
 func (app *Application) JoinGroup(groupName string) error {
+	return app.joinGroup(groupName, app.Config.Username, app.Config.Password)
+}
+
+// JoinGroupAs joins a group in the application with the given credentials instead of the configured ones.
+//
+// This allows a single application to operate several accounts sharing the same handlers and persistence.
+// A group can only be joined once per application, regardless of the account.
+// If the password is set, a logged in API client of the account is added as well, see [Application.PrivateAPIOf].
+//
+// Args:
+//   - groupName: The name of the group to join.
+//   - username: The username to login with.
+//   - password: The password to login with, leave it empty to join with a temporary name.
+//
+// Returns:
+//   - error: [ErrNoArgument] if the username is empty, or an error if the login fails or the group cannot be joined.
+func (app *Application) JoinGroupAs(groupName, username, password string) error {
+	if username == "" {
+		return ErrNoArgument
+	}
+
+	if password != "" {
+		if _, err := app.addPrivateAPI(username, password); err != nil {
+			return err
+		}
+	}
+
+	return app.joinGroup(groupName, username, password)
+}
+
+// joinGroup joins a group in the application with the given credentials.
+//
+// Args:
+//   - groupName: The name of the group to join.
+//   - username: The username to login with.
+//   - password: The password to login with.
+//
+// Returns:
+//   - error: An error if the group cannot be joined.
+func (app *Application) joinGroup(groupName, username, password string) error {
 	groupName = strings.ToLower(groupName)
 	if _, ok := app.Groups.Get(groupName); ok {
 		return ErrAlreadyConnected
 	}
 
-	if isGroup, err := publicAPI.IsGroup(groupName); err != nil || !isGroup {
+	// Coalesce the concurrent joins of the same group into one.
+	done, wait, err := app.beginJoin(groupName)
+	if err != nil {
+		return err
+	}
+	if wait != nil {
+		<-wait
+		if _, ok := app.Groups.Get(groupName); ok {
+			return ErrAlreadyConnected
+		}
+		return ErrNotConnected
+	}
+	defer done()
+
+	if isGroup, err := app.publicAPI.IsGroup(groupName); err != nil || !isGroup {
 		return ErrNotAGroup
 	}
 
@@ -315,7 +794,13 @@ func (app *Application) JoinGroup(groupName string) error {
 		TextFont:  app.Config.TextFont,
 		TextSize:  app.Config.TextSize,
 		SessionID: app.Config.SessionID,
-		LoggedIn:  app.Config.Password != "",
+		LoggedIn:  password != "",
+		username:  username,
+		password:  password,
+
+		BackoffBase:   app.Config.BackoffBase,
+		BackoffMax:    app.Config.BackoffMax,
+		BackoffJitter: app.Config.BackoffJitter,
 	}
 	if err := group.Connect(app.context); err != nil {
 		return err
@@ -323,11 +808,67 @@ func (app *Application) JoinGroup(groupName string) error {
 
 	app.Groups.Set(groupName, &group)
 
+	if !app.deferReady.Load() {
+		app.dispatchReady(nil)
+	}
+
 	return nil
 }
 
+// dispatchReady dispatches the [OnReady] event, once for the lifetime of the application.
+//
+// Args:
+//   - err: The error of the initial connections, set as the event Error if not nil.
+func (app *Application) dispatchReady(err error) {
+	app.readyOnce.Do(func() {
+		event := &Event{
+			Type:        OnReady,
+			GroupCount:  app.Groups.Len(),
+			PMConnected: app.Private.Connected,
+			Error:       err,
+		}
+		app.dispatchEvent(event)
+	})
+}
+
+// beginJoin marks the group as being joined.
+//
+// Args:
+//   - groupName: The name of the group to join.
+//
+// Returns:
+//   - func(): The function to call once the join finishes, nil if the group is already being joined.
+//   - chan struct{}: The channel closed once the ongoing join finishes, nil if there is no ongoing join.
+//   - error: [ErrAlreadyConnected] if the group has been joined meanwhile.
+func (app *Application) beginJoin(groupName string) (done func(), wait chan struct{}, err error) {
+	app.joining.Lock()
+	defer app.joining.Unlock()
+
+	// A join that finished between the caller's check and this lock has already stored the group,
+	// since the group is stored before its join mark is removed.
+	if _, ok := app.Groups.Get(groupName); ok {
+		return nil, nil, ErrAlreadyConnected
+	}
+
+	if wait, ok := app.joining.M[groupName]; ok {
+		return nil, wait, nil
+	}
+
+	ch := make(chan struct{})
+	app.joining.M[groupName] = ch
+
+	done = func() {
+		app.joining.Del(groupName)
+		close(ch)
+	}
+
+	return done, nil, nil
+}
+
 // LeaveGroup leaves a group in the application.
 //
+// The chat data of the group is deleted once it is left if [Config.PurgeChatDataOnLeave] is set.
+//
 // Args:
 //   - groupName: The name of the group to leave.
 //
@@ -337,6 +878,9 @@ func (app *Application) LeaveGroup(groupName string) error {
 	groupName = strings.ToLower(groupName)
 	if group, ok := app.Groups.Get(groupName); ok {
 		// app.Groups.Del(groupName) // Group deletion is handled by the [Group.wsOnError].
+		group.stateMu.Lock()
+		group.leaving = true
+		group.stateMu.Unlock()
 		group.Disconnect()
 		return nil
 	}
@@ -357,8 +901,20 @@ func (app *Application) ConnectPM() error {
 	app.Private.TextFont = app.Config.TextFont
 	app.Private.TextSize = app.Config.TextSize
 	app.Private.SessionID = app.Config.SessionID
+	app.Private.BackoffBase = app.Config.BackoffBase
+	app.Private.BackoffMax = app.Config.BackoffMax
+	app.Private.BackoffJitter = app.Config.BackoffJitter
 
-	return app.Private.Connect(app.context)
+	if err := app.Private.Connect(app.context); err != nil {
+		return err
+	}
+
+	// Without any group to join, the private chat is the initial connection.
+	if len(app.Config.Groups) == 0 && !app.deferReady.Load() {
+		app.dispatchReady(nil)
+	}
+
+	return nil
 }
 
 // DisconnectPM disconnects from private messages.
@@ -379,7 +935,21 @@ func (app *Application) GetContext() context.Context {
 // Returns:
 //   - *PrivateAPI: The private API used in the application.
 func (app *Application) PrivateAPI() *PrivateAPI {
-	return privateAPI
+	return app.privateAPI
+}
+
+// PrivateAPIOf returns the [PrivateAPI] of the account.
+//
+// The API clients are available for the configured account and the accounts joined with [Application.JoinGroupAs].
+//
+// Args:
+//   - username: The username of the account.
+//
+// Returns:
+//   - *PrivateAPI: The private API of the account.
+//   - bool: True if the account has an API client, false otherwise.
+func (app *Application) PrivateAPIOf(username string) (*PrivateAPI, bool) {
+	return app.privateAPIs.Get(strings.ToLower(username))
 }
 
 // PublicAPI returns the [PublicAPI] used in the application.
@@ -387,7 +957,40 @@ func (app *Application) PrivateAPI() *PrivateAPI {
 // Returns:
 //   - *PublicAPI: The public API used in the application.
 func (app *Application) PublicAPI() *PublicAPI {
-	return publicAPI
+	return app.publicAPI
+}
+
+// ConfigureAPI sets the options of the API clients of the application, replacing the [Config.API].
+//
+// Both the private and the public API clients pick the options up on their next request, keeping their cookies.
+//
+// Args:
+//   - opts: The API options, the zero value restores the defaults.
+//
+// Returns:
+//   - *Application: The application instance for method chaining.
+func (app *Application) ConfigureAPI(opts APIOptions) *Application {
+	app.Config.API = copyAPIOptions(opts)
+	app.api.set(opts)
+
+	return app
+}
+
+// SetAPITransport sets the underlying [http.RoundTripper] used by the API clients of the application,
+// e.g. an [http.Transport] with a proxy or a custom TLS config.
+//
+// It is a shorthand for setting [APIOptions.Transport] by [Application.ConfigureAPI], keeping the other options.
+//
+// Args:
+//   - rt: The RoundTripper, [http.DefaultTransport] is used if nil.
+//
+// Returns:
+//   - *Application: The application instance for method chaining.
+func (app *Application) SetAPITransport(rt http.RoundTripper) *Application {
+	app.Config.API.Transport = rt
+	app.api.setTransport(rt)
+
+	return app
 }
 
 // New creates a new instance of the [Application] with the provided configuration.
@@ -401,6 +1004,8 @@ func New(config *Config) *Application {
 	return &Application{
 		eventHandlers: []Handler{},
 		errorHandlers: []Handler{},
+		joining:       NewSyncMap[string, chan struct{}](),
+		privateAPIs:   NewSyncMap[string, *PrivateAPI](),
 		Config:        config,
 	}
 }
@@ -0,0 +1,415 @@
+package chadango
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestApp creates an application with an in-memory persistence, so events can be dispatched without connecting.
+func newTestApp(config *Config) *Application {
+	return New(config).UsePersistence(&GobPersistence{
+		BotData:  NewSyncMap[string, any](),
+		ChatData: NewSyncMap[string, *SyncMap[string, any]](),
+	})
+}
+
+func TestApplication_Commands(t *testing.T) {
+	app := New(&Config{Prefix: "."})
+	app.AddHandler(NewCommandHandler(nil, nil, "echo", "say"))
+	app.AddHandler(NewMessageHandler(nil, nil))
+	app.AddHandler(NewCommandHandler(nil, nil, "help"))
+
+	assert.Equal(t, []string{"echo", "say", "help"}, app.Commands(), "Commands should list every registered command")
+}
+
+func TestApplication_SuggestCommand(t *testing.T) {
+	app := New(&Config{Prefix: "."})
+	app.AddHandler(NewCommandHandler(nil, nil, "echo", "say", "help"))
+
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"ecko", "echo", true},
+		{"hlep", "help", true},
+		{"sy", "say", true},
+		{"xyzxyz", "", false},
+	}
+
+	for _, test := range tests {
+		suggestion, ok := app.SuggestCommand(test.input)
+		assert.Equal(t, test.ok, ok, "SuggestCommand(%q) ok should match", test.input)
+		assert.Equal(t, test.expected, suggestion, "SuggestCommand(%q) should match the expected suggestion", test.input)
+	}
+}
+
+func TestApplication_HelpText(t *testing.T) {
+	app := New(&Config{Prefix: "."})
+	app.AddHandler(NewCommandHandlerWithHelp(nil, nil, "Echoes the text.", "<text>", "echo", "say"))
+	app.AddHandler(NewMessageHandler(nil, nil))
+	app.AddHandler(NewCommandHandler(nil, nil, "ping"))
+
+	expected := ".echo, .say <text> - Echoes the text.\n.ping"
+	assert.Equal(t, expected, app.HelpText(), "HelpText should aggregate every command handler")
+}
+
+func TestApplication_SetDoubleFaultHandler(t *testing.T) {
+	app := New(&Config{Prefix: "."})
+	app.UsePersistence(new(GobPersistence))
+	app.persistence.Initialize()
+
+	var original, secondary any
+	app.SetDoubleFaultHandler(func(o, s any) {
+		original, secondary = o, s
+	})
+	app.AddHandler(NewTypeHandler(func(*Event, *Context) { panic("handler failed") }, nil, OnStart))
+	app.AddErrorHandler(NewTypeHandler(func(*Event, *Context) { panic("error handler failed") }, nil, OnStart))
+
+	app.dispatchEvent(&Event{Type: OnStart})
+
+	assert.Equal(t, "handler failed", original, "The original error should be passed to the double fault handler")
+	assert.Equal(t, "error handler failed", secondary, "The secondary error should be passed to the double fault handler")
+}
+
+func TestApplication_MaxRetries(t *testing.T) {
+	assert.Equal(t, MAX_RETRIES, New(&Config{}).maxRetries(), "maxRetries should default to MAX_RETRIES")
+	assert.Equal(t, 3, New(&Config{MaxRetry: 3}).maxRetries(), "maxRetries should use the configured value")
+}
+
+func TestApplication_SetReconnectGiveUpHandler(t *testing.T) {
+	var (
+		gotName    string
+		gotPrivate bool
+		gotRetries int
+	)
+
+	app := New(&Config{}).SetReconnectGiveUpHandler(func(name string, isPrivate bool, retries int) {
+		gotName, gotPrivate, gotRetries = name, isPrivate, retries
+	})
+	app.reconnectGaveUp("testgroup", false, MAX_RETRIES)
+
+	assert.Equal(t, "testgroup", gotName)
+	assert.False(t, gotPrivate)
+	assert.Equal(t, MAX_RETRIES, gotRetries)
+
+	// Should not panic without a handler.
+	New(&Config{}).reconnectGaveUp("testgroup", false, MAX_RETRIES)
+}
+
+func TestApplication_BeginJoin(t *testing.T) {
+	app := New(&Config{})
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		winners int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if done, _, _ := app.beginJoin("testgroup"); done != nil {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, winners, "Only one join should proceed")
+}
+
+func TestApplication_JoinGroupCoalesce(t *testing.T) {
+	app := New(&Config{})
+	app.Groups = NewSyncMap[string, *Group]()
+
+	done, wait, err := app.beginJoin("testgroup")
+	assert.NoError(t, err)
+	assert.NotNil(t, done)
+	assert.Nil(t, wait)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- app.JoinGroup("TestGroup")
+		}()
+	}
+
+	// Finish the ongoing join successfully, the concurrent joins should share its result.
+	app.Groups.Set("testgroup", &Group{Name: "testgroup"})
+	done()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.ErrorIs(t, err, ErrAlreadyConnected)
+	}
+
+	// A join racing past the caller's check should see the stored group.
+	_, _, err = app.beginJoin("testgroup")
+	assert.ErrorIs(t, err, ErrAlreadyConnected)
+
+	// The guard should be released.
+	app.Groups.Del("testgroup")
+	done, wait, err = app.beginJoin("testgroup")
+	assert.NoError(t, err)
+	assert.NotNil(t, done)
+	assert.Nil(t, wait)
+}
+
+func TestApplication_IsPremiumCached(t *testing.T) {
+	app := New(&Config{})
+
+	expire := time.Now().Add(24 * time.Hour)
+	app.premium.expire = expire
+	app.premium.checked = time.Now()
+
+	premium, got, err := app.IsPremium()
+	assert.NoError(t, err)
+	assert.True(t, premium, "The cached premium status should be used")
+	assert.Equal(t, expire, got)
+
+	app.premium.expire = time.Now().Add(-time.Hour)
+	premium, _, err = app.IsPremium()
+	assert.NoError(t, err)
+	assert.False(t, premium, "An expired premium should not be active")
+
+	// The cache is stale and there is no way to retrieve the status.
+	app.premium.checked = time.Time{}
+	_, _, err = app.IsPremium()
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
+
+func TestApplication_DispatchReady(t *testing.T) {
+	var events []*Event
+
+	app := newTestApp(&Config{})
+	app.Groups = NewSyncMap[string, *Group]()
+	app.Groups.Set("group1", &Group{Name: "group1"})
+	app.Groups.Set("group2", &Group{Name: "group2"})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { events = append(events, event) }, nil, OnReady))
+
+	app.dispatchReady(nil)
+	app.dispatchReady(ErrTimeout)
+
+	if assert.Len(t, events, 1, "OnReady should be dispatched once") {
+		assert.Equal(t, 2, events[0].GroupCount)
+		assert.False(t, events[0].PMConnected)
+		assert.Nil(t, events[0].Error)
+	}
+}
+
+func TestApplication_StartWaitReady(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Not a group, so the join fails before connecting.
+		w.Write([]byte("answer=0"))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	var events []*Event
+	app := newTestApp(&Config{Groups: []string{"testgroup"}}).Initialize()
+	app.SetAPITransport(&redirectTransport{target: target})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { events = append(events, event) }, nil, OnReady))
+
+	app.Start(context.Background(), StartOptions{WaitReady: true, WaitReadyTimeout: time.Second})
+	defer app.cancelCtx()
+
+	if assert.Len(t, events, 1, "OnReady should be dispatched before Start returns") {
+		err, _ := events[0].Error.(error)
+		assert.ErrorIs(t, err, ErrNotAGroup, "The failed groups should be reported")
+		assert.ErrorContains(t, err, "testgroup")
+	}
+
+	app = newTestApp(&Config{Groups: []string{"testgroup"}}).Initialize()
+	err := app.StartAndWaitReady(context.Background(), time.Second)
+	defer app.cancelCtx()
+
+	assert.ErrorIs(t, err, ErrNotAGroup, "The failed groups should be returned")
+	assert.ErrorContains(t, err, "testgroup")
+}
+
+// countingPersistence counts the [Persistence.DelChatData] calls.
+type countingPersistence struct {
+	GobPersistence
+	deleted chan string
+}
+
+func (p *countingPersistence) DelChatData(key string) {
+	p.GobPersistence.DelChatData(key)
+	p.deleted <- key
+}
+
+func TestApplication_LeaveGroupPurgesChatData(t *testing.T) {
+	for _, purge := range []bool{false, true} {
+		persistence := &countingPersistence{
+			GobPersistence: GobPersistence{
+				BotData:  NewSyncMap[string, any](),
+				ChatData: NewSyncMap[string, *SyncMap[string, any]](),
+			},
+			deleted: make(chan string, 2),
+		}
+		left := make(chan struct{})
+
+		app := New(&Config{PurgeChatDataOnLeave: purge}).UsePersistence(persistence)
+		app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { close(left) }, nil, OnGroupLeft))
+
+		group := newServedGroup(t, app, func(head, data string) []string { return nil }, asConnected, func(group *Group) {
+			group.ws.OnError = group.wsOnError
+		})
+		app.Groups = NewSyncMap[string, *Group]()
+		app.Groups.Set(group.Name, group)
+
+		assert.NoError(t, app.LeaveGroup(group.Name))
+		select {
+		case <-left:
+		case <-time.After(time.Second):
+			t.Fatal("OnGroupLeft was not dispatched")
+		}
+
+		if purge {
+			assert.Equal(t, group.Name, <-persistence.deleted)
+		}
+		select {
+		case key := <-persistence.deleted:
+			t.Errorf("DelChatData(%q) was called unexpectedly", key)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// countingMetrics counts the [Metrics] calls.
+type countingMetrics struct {
+	mu         sync.Mutex
+	events     map[EventType]int
+	reconnects map[string]int
+	latencies  []time.Duration
+}
+
+func (m *countingMetrics) IncEvent(eventType EventType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events[eventType]++
+}
+
+func (m *countingMetrics) IncReconnect(group string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects[group]++
+}
+
+func (m *countingMetrics) ObserveSendLatency(group string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies = append(m.latencies, d)
+}
+
+func TestApplication_UseMetrics(t *testing.T) {
+	metrics := &countingMetrics{events: map[EventType]int{}, reconnects: map[string]int{}}
+
+	app := newTestApp(&Config{})
+	assert.Equal(t, noopMetrics{}, app.getMetrics(), "The metrics should default to a no-op")
+	app.dispatchEvent(&Event{Type: OnMessage}) // Should not panic without metrics.
+
+	app.UseMetrics(metrics)
+	app.dispatchEvent(&Event{Type: OnMessage})
+	app.dispatchEvent(&Event{Type: OnMessage})
+	app.dispatchEvent(&Event{Type: OnGroupJoined})
+	assert.Equal(t, map[EventType]int{OnMessage: 2, OnGroupJoined: 1}, metrics.events, "IncEvent should be called on every dispatch")
+
+	app.UseMetrics(nil)
+	assert.Equal(t, noopMetrics{}, app.getMetrics(), "Passing nil should restore the no-op")
+}
+
+func TestApplication_JoinGroupAs(t *testing.T) {
+	app := newTestApp(&Config{Username: "Nekonyan", Password: "secret"})
+	app.initAPI(context.Background())
+
+	assert.ErrorIs(t, app.JoinGroupAs("testgroup", "", "secret"), ErrNoArgument, "An empty username should be rejected")
+
+	api, ok := app.PrivateAPIOf("nekonyan")
+	assert.True(t, ok, "The configured account should have an API client")
+	assert.Same(t, app.PrivateAPI(), api)
+
+	_, ok = app.PrivateAPIOf("unknown")
+	assert.False(t, ok)
+}
+
+func TestApplication_JoinGroupAsLogin(t *testing.T) {
+	var mu sync.Mutex
+	var logins []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			body, _ := io.ReadAll(r.Body)
+			form, _ := url.ParseQuery(string(body))
+			mu.Lock()
+			logins = append(logins, form.Get("user_id"))
+			mu.Unlock()
+		case "/checkname":
+			// Not a group, so the join stops before connecting.
+			w.Write([]byte("answer=0"))
+		}
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	app := newTestApp(&Config{})
+	app.SetAPITransport(&redirectTransport{target: target})
+	app.Groups = NewSyncMap[string, *Group]()
+	app.start(context.Background())
+
+	assert.ErrorIs(t, app.JoinGroupAs("testgroup", "Otherbot", "secret2"), ErrNotAGroup)
+	assert.ErrorIs(t, app.JoinGroupAs("testgroup", "otherbot", "ignored"), ErrNotAGroup)
+
+	api, ok := app.PrivateAPIOf("OTHERBOT")
+	if assert.True(t, ok, "The account should have an API client") {
+		assert.True(t, api.IsLoggedIn(), "The API client should be logged in")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Otherbot"}, logins, "An existing API client should be reused")
+}
+
+func TestApplication_StopDrainsSends(t *testing.T) {
+	received := make(chan struct{})
+
+	app := newTestApp(&Config{})
+	app.Groups = NewSyncMap[string, *Group]()
+	app.context, app.cancelCtx = context.WithCancel(context.Background())
+
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "slow" {
+			return nil
+		}
+		close(received)
+		time.Sleep(100 * time.Millisecond)
+		return []string{"done"}
+	}, asConnected)
+	app.Groups.Set(group.Name, group)
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- group.SyncSend(func(frame string) bool { return frame != "done" }, "slow", "\r\n")
+	}()
+	<-received
+
+	start := time.Now()
+	app.Stop()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "Stop should wait for the in-flight send")
+	assert.NoError(t, <-sent, "The in-flight send should not be cut off")
+	assert.False(t, group.Connected)
+}
@@ -3,6 +3,7 @@ package chadango
 import (
 	"context"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -10,8 +11,42 @@ import (
 type Backoff struct {
 	Duration    time.Duration      // Duration represents the current backoff duration.
 	MaxDuration time.Duration      // MaxDuration is the maximum allowed backoff duration.
+	Jitter      float64            // Jitter randomizes each wait by up to ±Jitter*Duration, within 0..1. See [Backoff.Sleep] for zero.
 	context     context.Context    // context is the context used for cancellation.
 	cancelCtx   context.CancelFunc // cancel is the function to cancel the [Backoff.Sleep] operation.
+	mu          sync.Mutex         // mu guards the [Backoff.context] and [Backoff.cancelCtx].
+}
+
+// newBackoff returns a new [Backoff] starting at the base duration.
+//
+// Args:
+//   - base: The initial backoff duration, defaults to [BASE_BACKOFF_DUR] if not positive.
+//   - limit: The maximum backoff duration, defaults to [MAX_BACKOFF_DUR] if not positive.
+//   - jitter: The jitter of each wait, clamped within 0..1.
+//
+// Returns:
+//   - *Backoff: A new [Backoff].
+func newBackoff(base, limit time.Duration, jitter float64) *Backoff {
+	if base <= 0 {
+		base = BASE_BACKOFF_DUR
+	}
+	if limit <= 0 {
+		limit = MAX_BACKOFF_DUR
+	}
+	if limit < base {
+		limit = base
+	}
+	if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+
+	return &Backoff{
+		Duration:    base,
+		MaxDuration: limit,
+		Jitter:      jitter,
+	}
 }
 
 // increment increases the backoff duration using an exponential strategy
@@ -29,6 +64,9 @@ func (b *Backoff) increment() {
 // Sleep is a mock of [time.Sleep], that is also responsive to the cancel signal.
 // It adds some jitter to the context and waits until the context is done.
 //
+// With a zero [Backoff.Jitter], the wait is lengthened by up to a quarter of the duration.
+// Otherwise, the wait is randomized by up to ±Jitter*Duration, which spreads out the reconnects of many groups.
+//
 // Args:
 //   - ctx: The context used for cancellation.
 //
@@ -38,15 +76,34 @@ func (b *Backoff) Sleep(ctx context.Context) bool {
 	defer b.increment()
 
 	// Add some jitter to the context.
-	b.context, b.cancelCtx = context.WithTimeout(ctx, b.Duration+time.Duration(rand.Int63n(int64(b.Duration)/4)))
-	defer b.cancelCtx()
+	sleepCtx, cancel := context.WithTimeout(ctx, b.wait())
+	defer cancel()
+
+	b.mu.Lock()
+	b.context, b.cancelCtx = sleepCtx, cancel
+	b.mu.Unlock()
+
+	<-sleepCtx.Done()
 
-	<-b.context.Done()
+	return sleepCtx.Err() != context.DeadlineExceeded
+}
+
+// wait returns the jittered duration of the next [Backoff.Sleep].
+func (b *Backoff) wait() time.Duration {
+	if b.Jitter <= 0 {
+		return b.Duration + time.Duration(rand.Int63n(int64(b.Duration)/4+1))
+	}
 
-	return b.context.Err() != context.DeadlineExceeded
+	spread := b.Jitter * float64(b.Duration)
+	return b.Duration + time.Duration(spread*(2*rand.Float64()-1))
 }
 
 // Cancel cancels the ongoing backoff sleep.
 func (b *Backoff) Cancel() {
-	b.cancelCtx()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancelCtx != nil {
+		b.cancelCtx()
+	}
 }
@@ -43,9 +43,3 @@
 //
 // The chadango package aims to streamline the development of Chatango bots, providing a solid foundation for building advanced and scalable messaging solutions.
 package chadango
-
-func init() {
-	// Initializes the [http.Client] with custom headers and a cookie jar.
-	// Every Chatango API calls should made with this client for efficiency.
-	initHttpClient()
-}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 )
 
 // Config represents a configuration object.
@@ -21,6 +22,45 @@ type Config struct {
 	EnablePM  bool     `json:"enablepm"`  // Enable private messages in the configuration.
 	Debug     bool     `json:"debug"`     // Debug mode in the configuration.
 	Prefix    string   `json:"prefix"`    // Prefix for commands in the configuration.
+	MaxRetry  int      `json:"maxretry"`  // Maximum reconnect attempts, defaults to MAX_RETRIES if not positive.
+
+	// BackoffBase and BackoffMax bound the wait between reconnect attempts, which doubles after each failure.
+	// They default to [BASE_BACKOFF_DUR] and [MAX_BACKOFF_DUR] if not positive.
+	BackoffBase time.Duration `json:"backoffbase"`
+	BackoffMax  time.Duration `json:"backoffmax"`
+
+	// BackoffJitter randomizes each wait between reconnect attempts by up to ±BackoffJitter of the wait, within 0..1,
+	// so that many groups do not reconnect all at once. See [Backoff.Jitter].
+	BackoffJitter float64 `json:"backoffjitter"`
+
+	// ConnectTimeout bounds the whole connect sequence of a group (dial, handshake, and login),
+	// defaults to [CONNECT_TIMEOUT] if not positive.
+	ConnectTimeout time.Duration `json:"connecttimeout"`
+
+	// OrderedEvents makes each group handle its frames one at a time, in the order they are received.
+	// This guarantees the order of the dispatched events (e.g. [OnMessage]) at the cost of throughput,
+	// since a slow handler delays every subsequent event of the same group.
+	OrderedEvents bool `json:"orderedevents"`
+
+	// PersistMessages stores the recent messages of each group in the persistence layer on shutdown,
+	// and restores them once the group is joined again, bounded by [MAX_PERSISTED_MESSAGES].
+	PersistMessages bool `json:"persistmessages"`
+
+	// PreserveWhitespace keeps the non-breaking spaces, tabs and carriage returns of the received messages in [models.Message.Text].
+	// By default, they are converted into the regular spaces and newlines.
+	PreserveWhitespace bool `json:"preservewhitespace"`
+
+	// PurgeChatDataOnLeave deletes the chat data of a group from the persistence layer once it is left by [Application.LeaveGroup],
+	// after the [OnGroupLeft] event is dispatched.
+	PurgeChatDataOnLeave bool `json:"purgechatdataonleave"`
+
+	// AutoAddFriends adds the users to the friend list upon their first private message,
+	// see the [OnPrivateFriendRequest] event. The anonymous users are skipped, since they cannot be friends.
+	AutoAddFriends bool `json:"autoaddfriends"`
+
+	// API holds the options of the API clients of the application, applied once it starts.
+	// They can be changed afterwards by [Application.ConfigureAPI] and [Application.SetAPITransport].
+	API APIOptions `json:"-"`
 }
 
 // LoadConfig loads the configuration from the specified file.
@@ -3,6 +3,8 @@ package chadango
 import (
 	"errors"
 	"time"
+
+	"github.com/n0h4rt/chadango/models"
 )
 
 const (
@@ -11,13 +13,46 @@ const (
 	EVENT_BUFFER_SIZE   = 30
 	PING_INTERVAL       = 90 * time.Second
 	MAX_MESSAGE_HISTORY = 100
+	HISTORY_PAGE_SIZE   = 20
 	SYNC_SEND_TIMEOUT   = 5 * time.Second
+	CONNECT_TIMEOUT     = 15 * time.Second
 	BASE_BACKOFF_DUR    = 1 * time.Second
 	MAX_BACKOFF_DUR     = 30 * time.Second
 	MAX_RETRIES         = 10
 	MSG_LENGTH_DEFAULT  = 2900
 	MSG_LENGTH_SHORT    = 850
 	API_TIMEOUT         = 10 * time.Second
+	API_ORIGIN          = "https://st.chatango.com"
+	API_USER_AGENT      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"
+	API_RETRIES         = 2
+	API_RETRY_BACKOFF   = 500 * time.Millisecond
+	API_ERROR_BODY_SIZE = 512
+	SUGGEST_THRESHOLD   = 0.5
+	FRIEND_ADD_INTERVAL = 500 * time.Millisecond
+	UNBAN_INTERVAL      = 500 * time.Millisecond
+	BAN_LIST_PAGE_SIZE  = 100
+	PEOPLE_PAGE_SIZE    = 20
+	MSG_BG_MAX_SIZE     = 1 << 20
+	IMG_MAX_SIZE        = 10 << 20
+	IMG_MAX_DIM         = 4096
+	MSG_BG_MAX_DIM      = 2048
+	MSG_BG_UPLOAD_DELAY = 10 * time.Second
+	MAX_RATE_LIMIT      = 10 * time.Minute
+	PREMIUM_CACHE_TTL   = 10 * time.Minute
+	SEND_TIMINGS_SIZE   = 16
+	RECOUNT_INTERVAL    = 5 * time.Minute
+	TYPING_DEBOUNCE     = 1 * time.Second
+	DEDUPE_WINDOW       = 500
+	CONTACT_MARKS_SIZE  = 1000
+	IDLE_TIMEOUT        = 60 * time.Second
+	MIN_IDLE_TIMEOUT    = 5 * time.Second
+	DRAIN_TIMEOUT       = 5 * time.Second
+	WAIT_READY_TIMEOUT  = 30 * time.Second
+
+	MAX_PERSISTED_MESSAGES = 50
+	PERSISTED_MESSAGES_KEY = "chadango:messages"
+	JSON_TYPE_KEY          = "chadango:type"  // The reserved key of the type name in a [JSONPersistence] typed value.
+	JSON_VALUE_KEY         = "chadango:value" // The reserved key of the value in a [JSONPersistence] typed value.
 )
 
 const (
@@ -37,6 +72,7 @@ const (
 	API_CHECK_GROUP = "https://chatango.com/checkname"
 	API_MINI_XML    = "https://ust.chatango.com/profileimg/%s/%s/%s/mod1.xml"
 	API_FULL_XML    = "https://ust.chatango.com/profileimg/%s/%s/%s/mod2.xml"
+	API_GROUP_INFO  = "https://ust.chatango.com/groupinfo/%s/%s/%s/gprofile.json"
 )
 
 var (
@@ -52,6 +88,7 @@ var (
 	ErrTimeout     = errors.New("timeout")
 
 	ErrRateLimited          = errors.New("rate limited")
+	ErrInvalidRateLimit     = errors.New("invalid rate limit")
 	ErrMessageLength        = errors.New("message length exceeded")
 	ErrFloodWarning         = errors.New("flood warning")
 	ErrFloodBanned          = errors.New("flood banned")
@@ -64,34 +101,58 @@ var (
 	ErrVerificationRequired = errors.New("verification required")
 	ErrOfflineLimit         = errors.New("offline message limit")
 
+	ErrUserNotFound  = errors.New("user not found")
+	ErrGroupNotFound = errors.New("group not found")
+
 	ErrBadAlias = errors.New("bad alias")
 	ErrBadLogin = errors.New("bad login")
 
-	ErrRequestFailed = errors.New("request failed")
+	ErrRequestFailed          = errors.New("request failed")
+	ErrNoPermission           = errors.New("no permission")
+	ErrInsufficientPermission = ErrNoPermission // An alias of [ErrNoPermission], so either matches with [errors.Is].
+	ErrNotOwned               = errors.New("not owned")
+	ErrNoBannableMessage      = errors.New("no bannable message")
+	ErrInvalidImage           = errors.New("invalid image")
+	ErrImageTooLarge          = errors.New("image too large")
+	ErrInvalidColor           = errors.New("invalid color")
+	ErrInvalidChannel         = errors.New("invalid channel")
+
+	ErrNoDatabase = errors.New("no database")
+)
+
+const (
+	FloodModeControlled = "flood controlled" // The group is flood controlled (rate limit is zero).
+	FloodModeSlow       = "slow mode"        // The group is in slow mode (rate limit is greater than zero).
+)
+
+const (
+	RestrictionFloodBan  = "floodban"  // The group is restricted by a flood ban or the auto moderation.
+	RestrictionRateLimit = "ratelimit" // The group is restricted by the rate limit.
 )
 
+// GroupStatuses maps the names of the group flags to their values, the named ones are derived from [models.GroupFlag].
 var GroupStatuses = map[string]int64{
 	"MISSING_1":                1,
-	"NO_ANONS":                 4,
+	"NO_ANONS":                 int64(models.GroupFlagNoAnons),
 	"MISSING_2":                8,
-	"NO_COUNTER":               16,
-	"DISALLOW_IMAGES":          32,
-	"DISALLOW_LINKS":           64,
-	"DISALLOW_VIDEOS":          128,
+	"NO_COUNTER":               int64(models.GroupFlagNoCounter),
+	"DISALLOW_IMAGES":          int64(models.GroupFlagDisallowImages),
+	"DISALLOW_LINKS":           int64(models.GroupFlagDisallowLinks),
+	"DISALLOW_VIDEOS":          int64(models.GroupFlagDisallowVideos),
 	"MISSING_3":                256,
 	"MISSING_4":                512,
-	"BANWORD_ONLY_TO_AUTHOR":   1024,
-	"FLOOD_CONTROLLED":         2048,
-	"ENABLE_CHANNELS":          8192,
-	"BASIC_NONSENSE_DETECTION": 16384, // js: nlp_single_msg
-	"BLOCK_REPETITIOUS_MSGS":   32768, // js: nlp_msg_queue
-	"BROADCAST_MODE":           65536,
-	"CLOSED_NO_MODS":           131072,
-	"GROUP_CLOSED":             262144,
-	"DISPLAY_BADGES":           524288,
-	"MODS_CHOOSE_BADGES":       1048576,
-	"ADV_NONSENSE_DETECTION":   2097152, // js: nlp_ngram
-	"BAN_PROXIES_AND_VPN":      4194304,
+	"BANWORD_ONLY_TO_AUTHOR":   int64(models.GroupFlagBanwordOnlyToAuthor),
+	"FLOOD_CONTROLLED":         int64(models.GroupFlagFloodControlled),
+	"ENABLE_CHANNELS":          int64(models.GroupFlagEnableChannels),
+	"BASIC_NONSENSE_DETECTION": int64(models.GroupFlagBasicNonsenseDetection), // js: nlp_single_msg
+	"BLOCK_REPETITIOUS_MSGS":   int64(models.GroupFlagBlockRepetitiousMsgs),   // js: nlp_msg_queue
+	"BROADCAST_MODE":           int64(models.GroupFlagBroadcastMode),
+	"CLOSED_NO_MODS":           int64(models.GroupFlagClosedNoMods),
+	"GROUP_CLOSED":             int64(models.GroupFlagGroupClosed),
+	"DISPLAY_BADGES":           int64(models.GroupFlagDisplayBadges),
+	"MODS_CHOOSE_BADGES":       int64(models.GroupFlagModsChooseBadges),
+	"ADV_NONSENSE_DETECTION":   int64(models.GroupFlagAdvNonsenseDetection), // js: nlp_ngram
+	"BAN_PROXIES_AND_VPN":      int64(models.GroupFlagBanProxiesAndVPN),
 	"MISSING_5":                8388608,
 	"MISSING_6":                268435456,
 	"MISSING_7":                536870912,
@@ -1,6 +1,12 @@
 package chadango
 
-import "github.com/n0h4rt/chadango/models"
+import (
+	"strings"
+	"time"
+
+	"github.com/n0h4rt/chadango/models"
+	"github.com/n0h4rt/chadango/utils"
+)
 
 // EventType represents the type of an event.
 type EventType int64
@@ -11,6 +17,8 @@ const (
 	OnStart EventType = 1 << iota
 	// Event triggered when the application stops.
 	OnStop
+	// Event triggered once the initial connections are established.
+	OnReady
 	// Event triggered when an error occurs.
 	OnError
 
@@ -20,6 +28,8 @@ const (
 	OnGroupLeft
 	// Event triggered when the bot reconnects to a group.
 	OnGroupReconnected
+	// Event triggered when the connection state of a group changes.
+	OnConnectionStateChange
 	// Event triggered when a user joins a group.
 	OnJoin
 	// Event triggered when a user logs in.
@@ -30,6 +40,8 @@ const (
 	OnLeave
 	// Event triggered when the participant count in a group changes.
 	OnParticipantCountChange
+	// Event triggered when the registered and anonymous participant counts reported by the server for a group change.
+	OnParticipantListUpdate
 	// Event triggered when a message is received.
 	OnMessage
 	// Event triggered when a message is deleted.
@@ -38,6 +50,8 @@ const (
 	OnMessageHistory
 	// Event triggered when a message is updated.
 	OnMessageUpdate
+	// Event triggered when the content of a message is edited.
+	OnMessageEdited
 	// Event triggered when an announcement is received.
 	OnAnnouncement
 	// Event triggered when the group information is updated.
@@ -58,6 +72,14 @@ const (
 	OnUserUnbanned
 	// Event triggered when all users are unbanned.
 	OnAllUserUnbanned
+	// Event triggered when the bot is banned from the group for using a proxy or VPN.
+	OnProxyBanned
+	// Event triggered when the server requires a verification (e.g. a captcha) before the bot can send messages.
+	OnVerificationRequired
+	// Event triggered when sending messages to a group gets rate-limited.
+	OnRateLimited
+	// Event triggered when the flood ban or auto moderation restriction of a group is updated.
+	OnRestricted
 
 	// Event triggered when the bot connects to a private chat.
 	OnPrivateConnected
@@ -81,6 +103,16 @@ const (
 	OnPrivateFriendActive
 	// Event triggered when a friend becomes idle in a private chat.
 	OnPrivateFriendIdle
+	// Event triggered when the status of a tracked user changes.
+	OnPrivateUserStatus
+	// Event triggered when a friend starts or stops typing in a private chat.
+	OnPrivateFriendTyping
+	// Event triggered when a user contacts the bot for the first time in a private chat.
+	OnPrivateFriendRequest
+	// Event triggered when the bot goes idle in a private chat.
+	OnPrivateSelfIdle
+	// Event triggered when the bot goes active in a private chat.
+	OnPrivateSelfActive
 
 	// Event triggered when the user profile is updated.
 	OnUpdateUserProfile
@@ -93,6 +125,8 @@ func (e EventType) String() string {
 		return "OnStart"
 	case OnStop:
 		return "OnStop"
+	case OnReady:
+		return "OnReady"
 	case OnError:
 		return "OnError"
 	case OnGroupJoined:
@@ -101,6 +135,8 @@ func (e EventType) String() string {
 		return "OnGroupLeft"
 	case OnGroupReconnected:
 		return "OnGroupReconnected"
+	case OnConnectionStateChange:
+		return "OnConnectionStateChange"
 	case OnJoin:
 		return "OnJoin"
 	case OnLogin:
@@ -111,6 +147,8 @@ func (e EventType) String() string {
 		return "OnLeave"
 	case OnParticipantCountChange:
 		return "OnParticipantCountChange"
+	case OnParticipantListUpdate:
+		return "OnParticipantListUpdate"
 	case OnMessage:
 		return "OnMessage"
 	case OnMessageDelete:
@@ -119,6 +157,8 @@ func (e EventType) String() string {
 		return "OnMessageHistory"
 	case OnMessageUpdate:
 		return "OnMessageUpdate"
+	case OnMessageEdited:
+		return "OnMessageEdited"
 	case OnAnnouncement:
 		return "OnAnnouncement"
 	case OnUpdateGroupInfo:
@@ -139,6 +179,14 @@ func (e EventType) String() string {
 		return "OnUserUnbanned"
 	case OnAllUserUnbanned:
 		return "OnAllUserUnbanned"
+	case OnProxyBanned:
+		return "OnProxyBanned"
+	case OnVerificationRequired:
+		return "OnVerificationRequired"
+	case OnRateLimited:
+		return "OnRateLimited"
+	case OnRestricted:
+		return "OnRestricted"
 	case OnPrivateConnected:
 		return "OnPrivateConnected"
 	case OnPrivateDisconnected:
@@ -161,6 +209,16 @@ func (e EventType) String() string {
 		return "OnPrivateFriendActive"
 	case OnPrivateFriendIdle:
 		return "OnPrivateFriendIdle"
+	case OnPrivateUserStatus:
+		return "OnPrivateUserStatus"
+	case OnPrivateFriendTyping:
+		return "OnPrivateFriendTyping"
+	case OnPrivateFriendRequest:
+		return "OnPrivateFriendRequest"
+	case OnPrivateSelfIdle:
+		return "OnPrivateSelfIdle"
+	case OnPrivateSelfActive:
+		return "OnPrivateSelfActive"
 	case OnUpdateUserProfile:
 		return "OnUpdateUserProfile"
 	default:
@@ -179,8 +237,9 @@ type Event struct {
 	Command          string              // The command associated with the event.
 	WithArgument     bool                // Indicates if the command has an argument.
 	Argument         string              // The argument associated with the command.
-	Arguments        []string            // The arguments associated with the command.
+	Arguments        []string            // The arguments associated with the command, split with [utils.TokenizeArgs].
 	Participant      *models.Participant // The participant associated with the event.
+	UserStatus       *models.UserStatus  // The user status associated with the event.
 	FlagAdded        int64               // The flags added in the event.
 	FlagRemoved      int64               // The flags removed in the event.
 	Blocked          *models.Blocked     // The blocked user associated with the event.
@@ -188,5 +247,60 @@ type Event struct {
 	GroupInfo        *models.GroupInfo   // The group information associated with the event.
 	ModGrantedAccess int64               // The granted moderator access level associated with the event.
 	ModRevokedAccess int64               // The revoked moderator access level associated with the event.
+	Info             string              // Additional information carried by the frame associated with the event.
+	GroupCount       int                 // The number of connected groups, set for the [OnReady] event.
+	PMConnected      bool                // Indicates if the private chat is connected, set for the [OnReady] event.
+	RestrictUntil    time.Time           // The time when the restriction expires, set for the [OnRateLimited] and [OnRestricted] events, zero once it has ended.
+	Typing           bool                // Indicates if the user is typing, set for the [OnPrivateFriendTyping] event.
+	UserCount        int                 // The count of registered users, set for the [OnParticipantListUpdate] event.
+	AnonCount        int                 // The count of anonymous users, set for the [OnParticipantListUpdate] event.
+	OldState         ConnectionState     // The previous connection state, set for the [OnConnectionStateChange] event.
+	NewState         ConnectionState     // The new connection state, set for the [OnConnectionStateChange] event.
 	Error            any                 // The error associated with the event.
 }
+
+// IsFromSelf checks whether the event's message was sent by the current user.
+//
+// Unlike [models.Message.FromSelf], which is set while parsing and can be wrong for anonymous messages,
+// it cross-checks the sender against the login identity and the anonymous seed of the chat at dispatch time.
+//
+// Returns:
+//   - bool: True if the message was sent by the current user, false otherwise.
+func (e *Event) IsFromSelf() bool {
+	msg := e.Message
+	if msg == nil {
+		return e.User != nil && e.User.IsSelf
+	}
+
+	if msg.FromSelf {
+		return true
+	}
+
+	if msg.IsPrivate {
+		private := msg.Private
+		if private == nil {
+			private = e.Private
+		}
+		return private != nil && msg.User != nil && strings.EqualFold(msg.User.Name, private.LoginName)
+	}
+
+	group := msg.Group
+	if group == nil {
+		group = e.Group
+	}
+	if group == nil || msg.User == nil || group.UserID == 0 || msg.UserID != group.UserID {
+		return false
+	}
+
+	if !msg.FromAnon {
+		return strings.EqualFold(msg.User.Name, group.LoginName)
+	}
+
+	if group.LoggedIn {
+		return false
+	}
+
+	return msg.AnonSeed == utils.CreateAnonSeed(group.AnonName, group.UserID) ||
+		strings.EqualFold(msg.User.Name, group.AnonName) ||
+		strings.EqualFold(msg.User.Name, group.LoginName)
+}
@@ -0,0 +1,59 @@
+package chadango
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/n0h4rt/chadango/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvent_IsFromSelf(t *testing.T) {
+	loggedIn := &Group{LoginName: "Nekonyan", UserID: 48875733, LoggedIn: true}
+	anon := &Group{LoginName: "anon1234", AnonName: "anon1234", UserID: 23361675}
+	seed := utils.CreateAnonSeed(anon.AnonName, anon.UserID)
+
+	tests := []struct {
+		name  string
+		group *Group
+		data  string
+		want  bool
+	}{
+		{
+			name:  "Self",
+			group: loggedIn,
+			data:  "1717866894:Nekonyan::48875733:moderationID:messageID:userIP:0::<n000/>hello",
+			want:  true,
+		},
+		{
+			name:  "Other",
+			group: loggedIn,
+			data:  "1717866894:someuser::12345678:moderationID:messageID:userIP:0::<n000/>hello",
+			want:  false,
+		},
+		{
+			name:  "SameNameOtherID",
+			group: loggedIn,
+			data:  "1717866894:Nekonyan::12345678:moderationID:messageID:userIP:0::<n000/>hello",
+			want:  false,
+		},
+		{
+			name:  "AnonSelf",
+			group: anon,
+			data:  "1717866894:::23361675:moderationID:messageID:userIP:0::<n" + fmt.Sprintf("%04d", seed) + "/>hello",
+			want:  true,
+		},
+		{
+			name:  "AnonOther",
+			group: anon,
+			data:  "1717866894:::12345678:moderationID:messageID:userIP:0::<n3512/>hello",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &Event{Type: OnMessage, Group: tt.group, Message: ParseGroupMessage(tt.data, tt.group)}
+			assert.Equal(t, tt.want, event.IsFromSelf())
+		})
+	}
+}
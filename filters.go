@@ -3,7 +3,9 @@ package chadango
 import (
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/n0h4rt/chadango/models"
 	"github.com/n0h4rt/chadango/utils"
 )
 
@@ -420,3 +422,566 @@ func (f *RegexFilter) Not() Filter {
 func NewRegexFilter(pattern string) Filter {
 	return &RegexFilter{Pattern: regexp.MustCompile(pattern)}
 }
+
+// MediaFilter represents a [Message] filter based on the media content.
+//
+// It filters messages that have the media flag or contain image embeds.
+type MediaFilter struct{}
+
+// Check checks if the event's message has media or image embeds.
+//
+// Args:
+//   - event: The event to check against the filter conditions.
+//
+// Returns:
+//   - bool: True if the event's message has the media flag or contains an image embed, false otherwise.
+func (f *MediaFilter) Check(event *Event) bool {
+	if event.Message == nil {
+		return false
+	}
+	return event.Message.HasMedia() || len(event.Message.ImageEmbeds()) > 0
+}
+
+// And returns a new [CombineFilter] that combines the current filter with the provided filter using logical AND.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical AND of the current filter and the provided filter.
+func (f *MediaFilter) And(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterAnd}
+}
+
+// Or returns a new [CombineFilter] that combines the current filter with the provided filter using logical OR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical OR of the current filter and the provided filter.
+func (f *MediaFilter) Or(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterOr}
+}
+
+// Xor returns a new [CombineFilter] that combines the current filter with the provided filter using logical XOR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical XOR of the current filter and the provided filter.
+func (f *MediaFilter) Xor(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterXor}
+}
+
+// Not returns a new [NotFilter] negating the current filter.
+//
+// Returns:
+//   - Filter: A new [NotFilter] representing the logical NOT of the current filter.
+func (f *MediaFilter) Not() Filter {
+	return &NotFilter{f}
+}
+
+// NewMediaFilter returns a new [MediaFilter].
+//
+// Returns:
+//   - Filter: A new [MediaFilter].
+func NewMediaFilter() Filter {
+	return &MediaFilter{}
+}
+
+// ChannelFilter represents a [Message] filter based on the message channel.
+//
+// It filters messages whose flag contains any of the specified channels.
+type ChannelFilter struct {
+	Channels models.MessageChannel // Channels is the combined mask of the channels to filter messages on.
+}
+
+// Check checks if the event's message is sent to one of the filter's channels.
+//
+// Args:
+//   - event: The event to check against the filter conditions.
+//
+// Returns:
+//   - bool: True if the event's message flag contains any of the filter's channels, false otherwise.
+func (f *ChannelFilter) Check(event *Event) bool {
+	if event.Message == nil {
+		return false
+	}
+	switch event.Type {
+	case OnMessage:
+		return event.Message.Flag&f.Channels != 0
+	}
+	return false
+}
+
+// And returns a new [CombineFilter] that combines the current filter with the provided filter using logical AND.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical AND of the current filter and the provided filter.
+func (f *ChannelFilter) And(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterAnd}
+}
+
+// Or returns a new [CombineFilter] that combines the current filter with the provided filter using logical OR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical OR of the current filter and the provided filter.
+func (f *ChannelFilter) Or(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterOr}
+}
+
+// Xor returns a new [CombineFilter] that combines the current filter with the provided filter using logical XOR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical XOR of the current filter and the provided filter.
+func (f *ChannelFilter) Xor(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterXor}
+}
+
+// Not returns a new [NotFilter] negating the current filter.
+//
+// Returns:
+//   - Filter: A new [NotFilter] representing the logical NOT of the current filter.
+func (f *ChannelFilter) Not() Filter {
+	return &NotFilter{f}
+}
+
+// NewChannelFilter returns a new [ChannelFilter].
+//
+// Args:
+//   - channels: A list of channels to filter messages on, e.g. [models.FlagRedChannel].
+//
+// Returns:
+//   - Filter: A new [ChannelFilter] initialized with the provided channels.
+func NewChannelFilter(channels ...models.MessageChannel) Filter {
+	var mask models.MessageChannel
+	for _, channel := range channels {
+		mask |= channel
+	}
+	return &ChannelFilter{Channels: mask}
+}
+
+// AnonFilter represents a filter for anonymous users.
+//
+// It filters events whose user is an anonymous user.
+type AnonFilter struct{}
+
+// Check checks if the event's user is an anonymous user.
+//
+// Args:
+//   - event: The event to check against the filter conditions.
+//
+// Returns:
+//   - bool: True if the event's user is anonymous, false otherwise.
+func (f *AnonFilter) Check(event *Event) bool {
+	if event.User == nil {
+		return false
+	}
+	return event.User.IsAnon
+}
+
+// And returns a new [CombineFilter] that combines the current filter with the provided filter using logical AND.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical AND of the current filter and the provided filter.
+func (f *AnonFilter) And(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterAnd}
+}
+
+// Or returns a new [CombineFilter] that combines the current filter with the provided filter using logical OR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical OR of the current filter and the provided filter.
+func (f *AnonFilter) Or(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterOr}
+}
+
+// Xor returns a new [CombineFilter] that combines the current filter with the provided filter using logical XOR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical XOR of the current filter and the provided filter.
+func (f *AnonFilter) Xor(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterXor}
+}
+
+// Not returns a new [NotFilter] negating the current filter.
+//
+// Returns:
+//   - Filter: A new [NotFilter] representing the logical NOT of the current filter.
+func (f *AnonFilter) Not() Filter {
+	return &NotFilter{f}
+}
+
+// NewAnonFilter returns a new [AnonFilter].
+//
+// Returns:
+//   - Filter: A new [AnonFilter].
+func NewAnonFilter() Filter {
+	return &AnonFilter{}
+}
+
+// ModFilter represents a filter for the moderators of a group.
+//
+// It filters events whose user is listed in the group's moderators.
+type ModFilter struct {
+	Group *Group // Group is the group whose moderators are used for filtering.
+}
+
+// Check checks if the event's user is a moderator of the filter's group.
+//
+// Args:
+//   - event: The event to check against the filter conditions.
+//
+// Returns:
+//   - bool: True if the event's user is a moderator of the group, false otherwise.
+func (f *ModFilter) Check(event *Event) bool {
+	if f.Group == nil || event.User == nil {
+		return false
+	}
+	_, ok := f.Group.Moderators.Get(strings.ToLower(event.User.Name))
+	return ok
+}
+
+// And returns a new [CombineFilter] that combines the current filter with the provided filter using logical AND.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical AND of the current filter and the provided filter.
+func (f *ModFilter) And(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterAnd}
+}
+
+// Or returns a new [CombineFilter] that combines the current filter with the provided filter using logical OR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical OR of the current filter and the provided filter.
+func (f *ModFilter) Or(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterOr}
+}
+
+// Xor returns a new [CombineFilter] that combines the current filter with the provided filter using logical XOR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical XOR of the current filter and the provided filter.
+func (f *ModFilter) Xor(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterXor}
+}
+
+// Not returns a new [NotFilter] negating the current filter.
+//
+// Returns:
+//   - Filter: A new [NotFilter] representing the logical NOT of the current filter.
+func (f *ModFilter) Not() Filter {
+	return &NotFilter{f}
+}
+
+// NewModFilter returns a new [ModFilter].
+//
+// Args:
+//   - group: The group whose moderators are used for filtering.
+//
+// Returns:
+//   - Filter: A new [ModFilter] bound to the provided group.
+func NewModFilter(group *Group) Filter {
+	return &ModFilter{Group: group}
+}
+
+// FlagFilter represents a [Message] filter based on the message flags.
+//
+// It filters messages that have all of the specified flags set, e.g. the premium or media flag.
+type FlagFilter struct {
+	Flags models.MessageChannel // Flags is the mask of flags that must all be set on the message.
+}
+
+// Check checks if the event's message has all of the filter's flags set.
+//
+// Args:
+//   - event: The event to check against the filter conditions.
+//
+// Returns:
+//   - bool: True if the event's message has all of the filter's flags set, false otherwise.
+func (f *FlagFilter) Check(event *Event) bool {
+	if event.Message == nil {
+		return false
+	}
+	switch event.Type {
+	case OnMessage:
+		return event.Message.Flag&f.Flags == f.Flags
+	}
+	return false
+}
+
+// And returns a new [CombineFilter] that combines the current filter with the provided filter using logical AND.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical AND of the current filter and the provided filter.
+func (f *FlagFilter) And(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterAnd}
+}
+
+// Or returns a new [CombineFilter] that combines the current filter with the provided filter using logical OR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical OR of the current filter and the provided filter.
+func (f *FlagFilter) Or(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterOr}
+}
+
+// Xor returns a new [CombineFilter] that combines the current filter with the provided filter using logical XOR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical XOR of the current filter and the provided filter.
+func (f *FlagFilter) Xor(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterXor}
+}
+
+// Not returns a new [NotFilter] negating the current filter.
+//
+// Returns:
+//   - Filter: A new [NotFilter] representing the logical NOT of the current filter.
+func (f *FlagFilter) Not() Filter {
+	return &NotFilter{f}
+}
+
+// NewFlagFilter returns a new [FlagFilter].
+//
+// Args:
+//   - flags: The flags that must all be set on the message, e.g. [models.FlagPremium] | [models.FlagBackground].
+//
+// Returns:
+//   - Filter: A new [FlagFilter] initialized with the provided flags.
+func NewFlagFilter(flags models.MessageChannel) Filter {
+	return &FlagFilter{Flags: flags}
+}
+
+// TypeFilter represents an event filter based on the event type.
+//
+// It allows composing a type condition with the other filters, e.g. NewUserFilter("admin").And(NewTypeFilter(OnJoin|OnLeave)).
+//
+// Note that the message filters (e.g. [RegexFilter]) only match [OnMessage],
+// so combining them with And only matches [OnMessage] regardless of the types.
+// Use Or to let the other types through, e.g. NewTypeFilter(OnJoin).Or(NewRegexFilter("^hi")).
+type TypeFilter struct {
+	Types EventType // Types is the mask of the event types to match.
+}
+
+// Check checks if the event type is one of the filter's types.
+//
+// Args:
+//   - event: The event to check against the filter conditions.
+//
+// Returns:
+//   - bool: True if the event type is one of the filter's types, false otherwise.
+func (f *TypeFilter) Check(event *Event) bool {
+	return f.Types&event.Type != 0
+}
+
+// And returns a new [CombineFilter] that combines the current filter with the provided filter using logical AND.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical AND of the current filter and the provided filter.
+func (f *TypeFilter) And(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterAnd}
+}
+
+// Or returns a new [CombineFilter] that combines the current filter with the provided filter using logical OR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical OR of the current filter and the provided filter.
+func (f *TypeFilter) Or(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterOr}
+}
+
+// Xor returns a new [CombineFilter] that combines the current filter with the provided filter using logical XOR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical XOR of the current filter and the provided filter.
+func (f *TypeFilter) Xor(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterXor}
+}
+
+// Not returns a new [NotFilter] negating the current filter.
+//
+// Returns:
+//   - Filter: A new [NotFilter] representing the logical NOT of the current filter.
+func (f *TypeFilter) Not() Filter {
+	return &NotFilter{f}
+}
+
+// NewTypeFilter returns a new [TypeFilter].
+//
+// Args:
+//   - types: The event types to match, e.g. [OnJoin] | [OnLeave].
+//
+// Returns:
+//   - Filter: A new [TypeFilter] initialized with the provided types.
+func NewTypeFilter(types EventType) Filter {
+	return &TypeFilter{Types: types}
+}
+
+const (
+	// TextFilterContains matches the messages containing any of the substrings.
+	TextFilterContains int = iota
+	// TextFilterHasPrefix matches the messages starting with any of the substrings.
+	TextFilterHasPrefix
+	// TextFilterHasSuffix matches the messages ending with any of the substrings.
+	TextFilterHasSuffix
+)
+
+// TextFilter represents a [Message] filter based on substrings, matched case-insensitively.
+//
+// It is a lighter alternative to [RegexFilter] for keyword triggers, as it neither compiles nor allocates.
+type TextFilter struct {
+	Mode       int      // Mode is how the substrings are matched, see [TextFilterContains], [TextFilterHasPrefix], and [TextFilterHasSuffix].
+	Substrings []string // Substrings is the list of substrings, any of which must match.
+}
+
+// Check checks if the event's message text matches any of the filter's substrings.
+//
+// Args:
+//   - event: The event to check against the filter conditions.
+//
+// Returns:
+//   - bool: True if the event's message text matches any of the filter's substrings, false otherwise.
+func (f *TextFilter) Check(event *Event) bool {
+	if event.Message == nil {
+		return false
+	}
+	switch event.Type {
+	case OnMessage, OnPrivateMessage:
+		for _, substring := range f.Substrings {
+			if f.match(event.Message.Text, substring) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match checks if the text matches the substring according to the mode, regardless of case.
+//
+// Args:
+//   - text: The text to match.
+//   - substring: The substring to look for.
+//
+// Returns:
+//   - bool: True if the text matches the substring, false otherwise.
+func (f *TextFilter) match(text, substring string) bool {
+	n := len(substring)
+	if len(text) < n {
+		return false
+	}
+
+	switch f.Mode {
+	case TextFilterContains:
+		for i := 0; i+n <= len(text); i++ {
+			if utf8.RuneStart(text[i]) && strings.EqualFold(text[i:i+n], substring) {
+				return true
+			}
+		}
+	case TextFilterHasPrefix:
+		return strings.EqualFold(text[:n], substring)
+	case TextFilterHasSuffix:
+		return strings.EqualFold(text[len(text)-n:], substring)
+	}
+	return false
+}
+
+// And returns a new [CombineFilter] that combines the current filter with the provided filter using logical AND.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical AND of the current filter and the provided filter.
+func (f *TextFilter) And(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterAnd}
+}
+
+// Or returns a new [CombineFilter] that combines the current filter with the provided filter using logical OR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical OR of the current filter and the provided filter.
+func (f *TextFilter) Or(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterOr}
+}
+
+// Xor returns a new [CombineFilter] that combines the current filter with the provided filter using logical XOR.
+//
+// Args:
+//   - filter: The filter to combine with the current filter.
+//
+// Returns:
+//   - Filter: A new [CombineFilter] representing the logical XOR of the current filter and the provided filter.
+func (f *TextFilter) Xor(filter Filter) Filter {
+	return &CombineFilter{f, filter, CombineFilterXor}
+}
+
+// Not returns a new [NotFilter] negating the current filter.
+//
+// Returns:
+//   - Filter: A new [NotFilter] representing the logical NOT of the current filter.
+func (f *TextFilter) Not() Filter {
+	return &NotFilter{f}
+}
+
+// NewTextFilter returns a new [TextFilter].
+//
+// Args:
+//   - mode: How the substrings are matched, see [TextFilterContains], [TextFilterHasPrefix], and [TextFilterHasSuffix].
+//   - substrings: The list of substrings, any of which must match.
+//
+// Returns:
+//   - Filter: A new [TextFilter] initialized with the provided mode and substrings.
+func NewTextFilter(mode int, substrings ...string) Filter {
+	return &TextFilter{Mode: mode, Substrings: substrings}
+}
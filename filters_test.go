@@ -94,6 +94,30 @@ func TestRegexFilter_Check(t *testing.T) {
 	assert.False(t, result2, "RegexFilter should not match event2")
 }
 
+func TestMediaFilter_Check(t *testing.T) {
+	filter := NewMediaFilter()
+	event1 := &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Text: "Look https://i.imgur.com/Ag8wg1F.PNG"}}}
+	event2 := &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Text: "Media", Flag: models.FlagMedia}}}
+	event3 := &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Text: "Just a link https://example.com"}}}
+	event4 := &Event{Type: OnJoin}
+
+	// Check if the filter matches the image embed
+	assert.True(t, filter.Check(event1), "MediaFilter should match event1")
+
+	// Check if the filter matches the media flag
+	assert.True(t, filter.Check(event2), "MediaFilter should match event2")
+
+	// Check if the filter matches a message without media
+	assert.False(t, filter.Check(event3), "MediaFilter should not match event3")
+
+	// Check if the filter matches an event without message
+	assert.False(t, filter.Check(event4), "MediaFilter should not match event4")
+
+	// Check the combinators
+	assert.False(t, filter.Not().Check(event1), "Not(MediaFilter) should not match event1")
+	assert.True(t, filter.And(NewRegexFilter("Look")).Check(event1), "MediaFilter.And should match event1")
+}
+
 func TestCombineFilter_And(t *testing.T) {
 	userFilter := NewUserFilter("user1")
 	chatFilter := NewChatFilter("chat1")
@@ -193,3 +217,94 @@ func TestCombineFilter_Not(t *testing.T) {
 	result2 := notFilter.Check(event2)
 	assert.True(t, result2, "NOT filter should match event2")
 }
+
+func TestChannelFilter_Check(t *testing.T) {
+	filter := NewChannelFilter(models.FlagRedChannel, models.FlagBlueChannel)
+
+	red := &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Flag: models.FlagRedChannel | models.FlagPremium}}}
+	green := &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Flag: models.FlagGreenChannel}}}
+	history := &Event{Type: OnMessageHistory, Message: &Message{nil, nil, models.Message{Flag: models.FlagBlueChannel}}}
+
+	assert.True(t, filter.Check(red), "ChannelFilter should match a red channel message")
+	assert.False(t, filter.Check(green), "ChannelFilter should not match a green channel message")
+	assert.False(t, filter.Check(history), "ChannelFilter should only match OnMessage events")
+	assert.False(t, filter.Check(&Event{Type: OnMessage}), "ChannelFilter should not match an event without a message")
+	assert.True(t, filter.Not().Check(green))
+}
+
+func TestAnonFilter_Check(t *testing.T) {
+	filter := NewAnonFilter()
+
+	assert.True(t, filter.Check(&Event{User: &models.User{Name: "anon1234", IsAnon: true}}), "AnonFilter should match an anonymous user")
+	assert.False(t, filter.Check(&Event{User: &models.User{Name: "user1"}}), "AnonFilter should not match a registered user")
+	assert.False(t, filter.Check(&Event{}), "AnonFilter should not match an event without a user")
+}
+
+func TestModFilter_Check(t *testing.T) {
+	group := &Group{Moderators: NewSyncMap[string, int64]()}
+	group.Moderators.Set("mod1", 0)
+	filter := NewModFilter(group)
+
+	assert.True(t, filter.Check(&Event{User: &models.User{Name: "Mod1"}}), "ModFilter should match a moderator case-insensitively")
+	assert.False(t, filter.Check(&Event{User: &models.User{Name: "user1"}}), "ModFilter should not match a regular user")
+	assert.False(t, filter.Check(&Event{}), "ModFilter should not match an event without a user")
+	assert.False(t, NewModFilter(nil).Check(&Event{User: &models.User{Name: "mod1"}}), "ModFilter should not match without a group")
+	assert.True(t, filter.And(NewAnonFilter().Not()).Check(&Event{User: &models.User{Name: "mod1"}}))
+}
+
+func TestFlagFilter_Check(t *testing.T) {
+	filter := NewFlagFilter(models.FlagPremium | models.FlagBackground)
+	event := func(flag models.MessageChannel) *Event {
+		return &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Flag: flag}}}
+	}
+
+	assert.True(t, filter.Check(event(models.FlagPremium|models.FlagBackground)), "FlagFilter should match when all flags are set")
+	assert.True(t, filter.Check(event(models.FlagPremium|models.FlagBackground|models.FlagRedChannel)), "FlagFilter should ignore extra flags")
+	assert.False(t, filter.Check(event(models.FlagPremium)), "FlagFilter should not match when a flag is missing")
+	assert.False(t, filter.Check(event(0)), "FlagFilter should not match a message without flags")
+	assert.False(t, filter.Check(&Event{Type: OnMessage}), "FlagFilter should not match an event without a message")
+	assert.False(t, filter.Check(&Event{Type: OnJoin, Message: &Message{nil, nil, models.Message{Flag: models.FlagPremium | models.FlagBackground}}}), "FlagFilter should only match OnMessage events")
+	assert.True(t, NewFlagFilter(models.FlagMedia).Or(filter).Check(event(models.FlagMedia)))
+}
+
+func TestTypeFilter_Check(t *testing.T) {
+	filter := NewTypeFilter(OnJoin | OnLeave)
+
+	assert.True(t, filter.Check(&Event{Type: OnJoin}), "TypeFilter should match one of its types")
+	assert.True(t, filter.Check(&Event{Type: OnLeave}))
+	assert.False(t, filter.Check(&Event{Type: OnMessage}), "TypeFilter should not match other types")
+
+	admin := &Event{Type: OnJoin, User: &models.User{Name: "admin"}}
+	assert.True(t, NewUserFilter("admin").And(filter).Check(admin))
+	assert.False(t, NewUserFilter("admin").And(filter).Check(&Event{Type: OnMessage, User: admin.User}))
+
+	regex := NewRegexFilter("^hi")
+	assert.False(t, filter.And(regex).Check(admin), "RegexFilter only matches OnMessage")
+	assert.True(t, filter.Or(regex).Check(admin))
+	assert.True(t, filter.Not().Check(&Event{Type: OnMessage}))
+}
+
+func TestTextFilter_Check(t *testing.T) {
+	event := func(eventType EventType, text string) *Event {
+		return &Event{Type: eventType, Message: &Message{nil, nil, models.Message{Text: text}}}
+	}
+
+	contains := NewTextFilter(TextFilterContains, "hello", "bye")
+	assert.True(t, contains.Check(event(OnMessage, "Well, HeLLo there")), "TextFilter should match regardless of case")
+	assert.True(t, contains.Check(event(OnPrivateMessage, "good bye")), "TextFilter should match private messages")
+	assert.False(t, contains.Check(event(OnMessage, "hell no")))
+	assert.False(t, contains.Check(event(OnMessageUpdate, "hello")), "TextFilter should only match new messages")
+	assert.False(t, contains.Check(&Event{Type: OnMessage}), "TextFilter should not match an event without a message")
+	assert.True(t, NewTextFilter(TextFilterContains, "ÉTÉ").Check(event(OnMessage, "un été chaud")))
+
+	prefix := NewTextFilter(TextFilterHasPrefix, "!quote")
+	assert.True(t, prefix.Check(event(OnMessage, "!Quote me")))
+	assert.False(t, prefix.Check(event(OnMessage, "say !quote")))
+	assert.False(t, prefix.Check(event(OnMessage, "!q")), "A shorter text should not match")
+
+	suffix := NewTextFilter(TextFilterHasSuffix, "?")
+	assert.True(t, suffix.Check(event(OnMessage, "who?")))
+	assert.False(t, suffix.Check(event(OnMessage, "who? me")))
+
+	assert.True(t, prefix.Or(suffix).Check(event(OnMessage, "who?")))
+}
@@ -1,11 +1,17 @@
 package chadango
 
 import (
+	"container/list"
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/n0h4rt/chadango/models"
@@ -13,6 +19,33 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// ConnectionState represents the connection state of a [Group], see [OnConnectionStateChange].
+type ConnectionState int32
+
+// Connection states.
+const (
+	StateDisconnected ConnectionState = iota // The group is not connected.
+	StateConnecting                          // The group is connecting for the first time.
+	StateConnected                           // The group is connected and logged in.
+	StateReconnecting                        // The group lost its connection and is reconnecting.
+)
+
+// String returns a string of said ConnectionState.
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "Disconnected"
+	case StateConnecting:
+		return "Connecting"
+	case StateConnected:
+		return "Connected"
+	case StateReconnecting:
+		return "Reconnecting"
+	default:
+		return "Unknown"
+	}
+}
+
 // Group represents a chat group with various properties and state.
 //
 // It provides methods for connecting, disconnecting, sending messages, retrieving user status, and managing settings.
@@ -25,15 +58,32 @@ type Group struct {
 	TextColor string       // The color for displaying text in the message.
 	TextFont  string       // The font style for displaying text in the message.
 	TextSize  int          // The font size for displaying text in the message.
-
-	WsUrl     string               // The WebSocket URL for connecting to the group.
-	ws        *WebSocket           // The WebSocket connection to the group.
-	Connected bool                 // Indicates if the group is currently connected.
-	events    chan string          // Channel for propagating events back to the listener.
-	takeOver  chan context.Context // Channel for taking over the WebSocket connection.
-	backoff   *Backoff             // Cancelable backoff for reconnection.
-	context   context.Context      // Context for running the group operations.
-	cancelCtx context.CancelFunc   // Function for stopping group operations.
+	username  string       // The login username, overriding the application's [Config.Username] if set.
+	password  string       // The login password paired with [Group.username].
+
+	WsUrl         string               // The WebSocket URL for connecting to the group.
+	ws            *WebSocket           // The WebSocket connection to the group.
+	Connected     bool                 // Indicates if the group is currently connected.
+	state         ConnectionState      // The connection state, guarded by [Group.stateMu], see [Group.State].
+	events        chan string          // Channel for propagating events back to the listener.
+	closeEvents   func()               // Closes the events channel of the current connection, only once.
+	takeOver      chan context.Context // Channel for taking over the WebSocket connection.
+	ready         chan struct{}        // Channel closed when the group has been initialized, guarded by [Group.stateMu].
+	joined        chan struct{}        // Channel closed when the "ok" frame has been handled, guarded by [Group.stateMu], see [Group.WaitUntilConnected].
+	reconnecting  context.CancelFunc   // Cancels the ongoing reconnection, guarded by [Group.stateMu].
+	noRetry       bool                 // Indicates if the auto-reconnect is suspended, guarded by [Group.stateMu].
+	leaving       bool                 // Indicates if the group is being left by [Application.LeaveGroup], guarded by [Group.stateMu].
+	BackoffBase   time.Duration        // The initial reconnect backoff, defaults to [BASE_BACKOFF_DUR] if not positive.
+	BackoffMax    time.Duration        // The maximum reconnect backoff, defaults to [MAX_BACKOFF_DUR] if not positive.
+	BackoffJitter float64              // The reconnect backoff jitter, see [Backoff.Jitter].
+	MaxRetries    int                  // The maximum reconnect attempts, defaults to the application's [Config.MaxRetry] if not positive.
+	autoThrottle  atomic.Bool          // Indicates if sending a message waits for the rate limit to pass.
+	sendMu        sync.Mutex           // Serializes the sent messages while awaiting their echo, see [sendEcho].
+	context       context.Context      // Context for running the group operations.
+	cancelCtx     context.CancelFunc   // Function for stopping group operations.
+	stateMu       sync.Mutex           // Guards the connected state transitions.
+	draining      bool                 // Indicates if new sends are refused by [Group.DrainAndDisconnect].
+	inFlight      sync.WaitGroup       // Tracks the in-flight [Group.SyncSend] operations.
 
 	Version    [2]int                 // The version of the group.
 	Owner      string                 // The owner of the group.
@@ -47,12 +97,24 @@ type Group struct {
 	Moderators SyncMap[string, int64] // Map of moderators and their access levels.
 	Flag       int64                  // The flag value for the group.
 
-	Channel          int64         // The channel flag of the group.
+	Channel          int64         // The channel flag of the group. Prefer [Group.SetDefaultChannel] for a validated update.
 	Restrict         time.Time     // The time when the group is restricted from the flood ban and auto moderation.
 	RateLimit        time.Duration // The rate limit duration for sending messages.
 	RateLimited      time.Time     // The time when the group is rate-limited.
 	MaxMessageLength int           // The maximum allowed length of a message.
 	PremiumExpireAt  time.Time     // The time when the premium membership expires.
+	ProxyBanned      bool          // Indicates if the bot is banned from the group for using a proxy or VPN.
+	proxyBanInfo     string        // The data of the latest "proxybanned" frame, passed as the [Event.Info] of [OnProxyBanned].
+	latency          atomic.Int64  // The last round-trip time in nanoseconds, see [Group.Latency].
+	mediaOn          atomic.Bool   // Indicates if the media feature is enabled on this connection, see [Group.SetMedia].
+	sendTimings      sendTimings   // The recent send times, see [Group.SendTimings].
+	lastSeen         messageMark   // The latest processed message, used to resume after a reconnect.
+	seenIDs          idWindow      // The recently dispatched message IDs, see [Group.SetDedupe].
+	resumePoint      resumePoint   // The messages processed before the current connection, see [Group.eventMessageHistory].
+	historyMu        sync.Mutex    // Guards the history paging.
+	historyOffset    int           // The offset of the next history page, see [Group.LoadMoreHistory].
+	historyDone      bool          // Indicates if the oldest history page has been fetched.
+	historyBuf       []string      // The fetched history frames not handled yet, from newer to older.
 
 	Messages       OrderedSyncMap[string, *Message] // Ordered map of messages history in the group.
 	TempMessages   SyncMap[string, *Message]        // Map of temporary messages in the group.
@@ -62,6 +124,8 @@ type Group struct {
 	ParticipantCount int64                                // The total count of participants in the group.
 	UserCount        int                                  // The count of registered users in the group.
 	AnonCount        int                                  // The count of anonymous users in the group.
+	countMu          sync.RWMutex                         // Guards the [Group.ParticipantCount], [Group.UserCount], and [Group.AnonCount].
+	participantsFeed bool                                 // Indicates if the participant feeds are started by [Group.GetParticipantsStart].
 }
 
 func (g *Group) initFields() {
@@ -70,6 +134,7 @@ func (g *Group) initFields() {
 	g.TempMessages = NewSyncMap[string, *Message]()
 	g.TempMessageIds = NewSyncMap[string, string]()
 	g.Participants = NewSyncMap[string, *models.Participant]()
+	g.ProxyBanned = false
 }
 
 // Connect establishes a connection to the server.
@@ -87,6 +152,7 @@ func (g *Group) Connect(ctx context.Context) (err error) {
 	g.context, g.cancelCtx = context.WithCancel(ctx)
 
 	log.Debug().Str("Name", g.Name).Msg("Connecting")
+	g.setState(StateConnecting)
 
 	defer func() {
 		if err != nil {
@@ -95,16 +161,23 @@ func (g *Group) Connect(ctx context.Context) (err error) {
 			}
 
 			log.Debug().Str("Name", g.Name).Msg("Connect failed")
+			g.setState(StateDisconnected)
 		}
 	}()
 
-	err = g.connect()
+	err = g.connect(g.context)
 	if err != nil {
 		g.cancelCtx()
 		return
 	}
 
+	g.stateMu.Lock()
 	g.Connected = true
+	g.draining = false
+	g.stateMu.Unlock()
+	g.setState(StateConnected)
+
+	go g.recountLoop()
 
 	log.Debug().Str("Name", g.Name).Msg("Connected")
 
@@ -117,19 +190,58 @@ func (g *Group) Connect(ctx context.Context) (err error) {
 // If successful, it sets up the WebSocket to sustain the connection and starts
 // listening for incoming events.
 //
+// The handshake is bounded by [Application.connectTimeout], [ErrTimeout] is returned on expiry.
+// The established connection lives within the group context, regardless of [ctx].
+//
+// Args:
+//   - ctx: The context bounding the handshake, e.g. canceled by [Group.CancelReconnect].
+//
 // Returns:
 //   - error: An error if the connection cannot be established.
-func (g *Group) connect() (err error) {
+func (g *Group) connect(ctx context.Context) (err error) {
 	g.ws = &WebSocket{
 		OnError: g.wsOnError,
 	}
-	if err = g.ws.Connect(g.WsUrl); err != nil {
+	// The participant feeds do not survive the connection.
+	g.countMu.Lock()
+	g.participantsFeed = false
+	g.countMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, g.App.connectTimeout())
+	defer cancel()
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		var netErr net.Error
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			err = ErrTimeout
+		}
+		g.ws.Close()
+	}()
+
+	if err = g.ws.ConnectContext(ctx, g.WsUrl); err != nil {
+		return
+	}
+
+	// The blocking receives below are bounded by the same deadline.
+	deadline, _ := ctx.Deadline()
+	if err = g.ws.SetDeadline(deadline); err != nil {
 		return
 	}
 
 	// Initializing channels.
 	g.events = make(chan string, EVENT_BUFFER_SIZE)
+	g.closeEvents = closeOnce(g.events)
 	g.takeOver = make(chan context.Context)
+	g.stateMu.Lock()
+	g.ready = make(chan struct{})
+	g.joined = make(chan struct{})
+	g.stateMu.Unlock()
+	// The media feature is per connection.
+	g.mediaOn.Store(false)
 
 	var frame string
 
@@ -146,10 +258,11 @@ func (g *Group) connect() (err error) {
 	g.events <- frame
 
 	// Attempting to login to the group chat.
+	username, password := g.credentials()
 	if g.LoggedIn {
-		err = g.Send("bauth", g.Name, g.App.Config.SessionID, g.App.Config.Username, g.App.Config.Password, "\x00")
+		err = g.Send("bauth", g.Name, g.App.Config.SessionID, username, password, "\x00")
 	} else {
-		err = g.Send("bauth", g.Name, g.App.Config.SessionID, g.App.Config.Username, "", "\x00")
+		err = g.Send("bauth", g.Name, g.App.Config.SessionID, username, "", "\x00")
 	}
 	if err != nil {
 		return
@@ -162,6 +275,17 @@ func (g *Group) connect() (err error) {
 	}
 	g.events <- frame
 
+	g.markResumePoint()
+
+	if err = g.ws.SetDeadline(time.Time{}); err != nil {
+		return
+	}
+
+	// Do not start a connection that is no longer wanted.
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	g.initFields()
 	g.ws.Sustain(g.context)
 	go g.listen()
@@ -170,10 +294,47 @@ func (g *Group) connect() (err error) {
 }
 
 // listen listens for incoming messages and events on the WebSocket connection.
+//
+// By default, each frame is handled in its own goroutine.
+// If [Config.OrderedEvents] is enabled, the frames are queued and handled one at a time by a single consumer.
 func (g *Group) listen() {
 	var frame string
 	var ok bool
 	var release context.Context
+
+	// The history sent on login is tracked, so it is stored before the "inited" frame is handled, see [Group.eventInited].
+	history := &sync.WaitGroup{}
+	handle := func(frame string) {
+		switch head, _, _ := strings.Cut(frame, ":"); head {
+		case "i":
+			history.Add(1)
+			go func(history *sync.WaitGroup) {
+				defer history.Done()
+				g.wsOnFrame(frame)
+			}(history)
+		case "inited":
+			go func(history *sync.WaitGroup) {
+				history.Wait()
+				g.wsOnFrame(frame)
+			}(history)
+			history = &sync.WaitGroup{}
+		default:
+			go g.wsOnFrame(frame)
+		}
+	}
+	if g.App.Config.OrderedEvents {
+		queue := make(chan string, EVENT_BUFFER_SIZE)
+		defer close(queue)
+
+		go func() {
+			for frame := range queue {
+				g.wsOnFrame(frame)
+			}
+		}()
+
+		handle = func(frame string) { queue <- frame }
+	}
+
 	for {
 		select {
 		case <-g.context.Done():
@@ -182,12 +343,12 @@ func (g *Group) listen() {
 			if !ok {
 				return
 			}
-			go g.wsOnFrame(frame)
+			handle(frame)
 		case frame, ok = <-g.ws.Events:
 			if !ok {
 				return
 			}
-			go g.wsOnFrame(frame)
+			handle(frame)
 		case release, ok = <-g.takeOver:
 			if !ok {
 				return
@@ -203,7 +364,7 @@ func (g *Group) listen() {
 					if !ok {
 						return
 					}
-					go g.wsOnFrame(frame)
+					handle(frame)
 				}
 			}
 		}
@@ -211,44 +372,253 @@ func (g *Group) listen() {
 }
 
 // Disconnect gracefully closes the connection to the server.
+//
+// It is safe to call it multiple times, even concurrently; only the first call tears the connection down.
 func (g *Group) Disconnect() {
-	if g.backoff != nil {
-		g.backoff.Cancel()
-	}
+	g.CancelReconnect()
 
+	g.stateMu.Lock()
 	if !g.Connected {
+		g.stateMu.Unlock()
 		return
 	}
-
 	g.Connected = false
+	g.stateMu.Unlock()
+
 	g.cancelCtx()
 	g.ws.Close()
+	g.setState(StateDisconnected)
+}
+
+// State returns the connection state of the group.
+//
+// Returns:
+//   - ConnectionState: The current connection state.
+func (g *Group) State() ConnectionState {
+	g.stateMu.Lock()
+	defer g.stateMu.Unlock()
+
+	return g.state
+}
+
+// setState sets the connection state of the group and dispatches the [OnConnectionStateChange] event if it changed.
+//
+// Args:
+//   - state: The new connection state.
+func (g *Group) setState(state ConnectionState) {
+	g.stateMu.Lock()
+	old := g.state
+	g.state = state
+	g.stateMu.Unlock()
+
+	if old == state {
+		return
+	}
+
+	event := &Event{
+		Type:     OnConnectionStateChange,
+		Group:    g,
+		OldState: old,
+		NewState: state,
+	}
+	g.App.dispatchEvent(event)
+}
+
+// DrainAndDisconnect waits for the in-flight sends to finish, then disconnects the group.
+//
+// New sends are refused with [ErrNotConnected] once the drain starts.
+// The group is disconnected anyway once the timeout elapses, which cancels the remaining sends.
+//
+// Args:
+//   - timeout: The maximum duration to wait for the in-flight sends.
+func (g *Group) DrainAndDisconnect(timeout time.Duration) {
+	g.stateMu.Lock()
+	g.draining = true
+	g.stateMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		g.inFlight.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+	case <-timer.C:
+		log.Debug().Str("Name", g.Name).Msg("Drain timed out")
+	}
+
+	g.Disconnect()
+}
+
+// closeOnce returns a function that closes the channel at most once.
+//
+// Args:
+//   - ch: The channel to close.
+//
+// Returns:
+//   - func(): The function closing the channel.
+func closeOnce[T any](ch chan T) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(ch) })
+	}
+}
+
+// saveMessages stores the recent messages into the chat data of the group.
+//
+// At most [MAX_PERSISTED_MESSAGES] of the latest messages are stored.
+//
+// Args:
+//   - persistence: The persistence layer to store the messages in.
+func (g *Group) saveMessages(persistence Persistence) {
+	var messages []models.Message
+	cb := func(_ string, msg *Message) bool {
+		messages = append(messages, msg.Message)
+		return len(messages) < MAX_PERSISTED_MESSAGES
+	}
+	g.Messages.RangeReversed(cb)
+
+	// Reverse back into the chronological order.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	persistence.GetChatData(g.Name).Set(PERSISTED_MESSAGES_KEY, messages)
+}
+
+// restoreMessages restores the messages stored by [Group.saveMessages] into the [Group.Messages].
+//
+// The restored messages are placed before the existing ones, and the messages that are already present are skipped.
+//
+// Args:
+//   - persistence: The persistence layer to restore the messages from.
+func (g *Group) restoreMessages(persistence Persistence) {
+	val, ok := persistence.GetChatData(g.Name).Get(PERSISTED_MESSAGES_KEY)
+	if !ok {
+		return
+	}
+
+	messages, ok := val.([]models.Message)
+	if !ok {
+		return
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if _, ok = g.Messages.Get(messages[i].ID); ok {
+			continue
+		}
+		g.Messages.SetFront(messages[i].ID, &Message{Group: g, Message: messages[i]})
+	}
+	g.Messages.TrimFront(MAX_MESSAGE_HISTORY)
+}
+
+// SuspendReconnect suspends the auto-reconnect.
+//
+// While suspended, a dropped connection is not retried and the [OnGroupLeft] event is dispatched immediately.
+// This is useful for a controlled shutdown or a planned maintenance.
+func (g *Group) SuspendReconnect() {
+	g.stateMu.Lock()
+	defer g.stateMu.Unlock()
+
+	g.noRetry = true
+}
+
+// ResumeReconnect resumes the auto-reconnect suspended by [Group.SuspendReconnect].
+func (g *Group) ResumeReconnect() {
+	g.stateMu.Lock()
+	defer g.stateMu.Unlock()
+
+	g.noRetry = false
+}
+
+// credentials returns the login credentials of the group, falling back to the application's ones.
+//
+// Returns:
+//   - string: The login username.
+//   - string: The login password.
+func (g *Group) credentials() (username, password string) {
+	if g.username != "" {
+		return g.username, g.password
+	}
+
+	return g.App.Config.Username, g.App.Config.Password
 }
 
 // Reconnect reconnects the group to the server.
 //
+// The attempts run under a context derived from the group context,
+// so [Group.CancelReconnect] or [Group.Disconnect] ends them promptly with [ErrRetryEnds].
+// If all the attempts fail, the handler set by [Application.SetReconnectGiveUpHandler] is called.
+//
 // Returns:
 //   - error: An error if the reconnection fails.
 func (g *Group) Reconnect() (err error) {
 	g.ws.Close()
+	g.App.getMetrics().IncReconnect(g.Name)
+	g.setState(StateReconnecting)
+
+	ctx, cancel := context.WithCancel(g.context)
+	g.stateMu.Lock()
+	g.reconnecting = cancel
+	g.stateMu.Unlock()
+	defer func() {
+		g.stateMu.Lock()
+		g.reconnecting = nil
+		g.stateMu.Unlock()
+		cancel()
+	}()
 
-	g.backoff = &Backoff{
-		Duration:    BASE_BACKOFF_DUR,
-		MaxDuration: MAX_BACKOFF_DUR,
+	backoff := newBackoff(g.BackoffBase, g.BackoffMax, g.BackoffJitter)
+	maxRetries := g.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = g.App.maxRetries()
 	}
+	retries := 0
 	defer func() {
-		g.backoff = nil
+		if err != nil {
+			g.setState(StateDisconnected)
+		}
 	}()
-	for retries := 0; retries < MAX_RETRIES && !g.backoff.Sleep(g.context); retries++ {
-		if err = g.connect(); err == nil {
+
+	for ; retries < maxRetries && !backoff.Sleep(ctx); retries++ {
+		err = g.connect(ctx)
+		if err == nil && ctx.Err() == nil {
+			g.setState(StateConnected)
 			return
 		}
+		if ctx.Err() != nil {
+			// Canceled while connecting, do not count it as a give up.
+			return ErrRetryEnds
+		}
 	}
 
 	// Either canceled or reached the maximum retries.
+	if retries == maxRetries {
+		g.App.reconnectGaveUp(g.Name, false, retries)
+	}
+
 	return ErrRetryEnds
 }
 
+// CancelReconnect cancels the ongoing reconnection of the group, if any.
+//
+// The pending backoff and the connection attempt in progress are interrupted,
+// and [Group.Reconnect] returns [ErrRetryEnds] without calling the give-up handler.
+// An automatic reconnection then ends like a failed one, i.e. the group is left.
+func (g *Group) CancelReconnect() {
+	g.stateMu.Lock()
+	cancel := g.reconnecting
+	g.stateMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
 // Send joins the [args] with a ":" separator and then sends it to the server asynchronously.
 //
 // Note:
@@ -261,7 +631,7 @@ func (g *Group) Reconnect() (err error) {
 // Returns:
 //   - error: An error if sending the message fails.
 func (g *Group) Send(args ...string) error {
-	if !g.ws.Connected {
+	if !g.ws.IsConnected() {
 		return ErrNotConnected
 	}
 
@@ -279,6 +649,45 @@ func (g *Group) Send(args ...string) error {
 	return g.ws.Send(command + terminator)
 }
 
+// Ping measures the round-trip time to the server by sending an empty frame and waiting for the pong.
+//
+// The pong would be consumed by the listener otherwise, so it is captured through [Group.SyncSendWithTimeout].
+// The measured round-trip time is also stored, see [Group.Latency].
+//
+// The pongs carry no identifier, so a pong of the [WebSocket] keep-alive ping that is still in flight
+// may be taken for this one, giving a shorter round-trip time. This only happens around the [PING_INTERVAL] ticks.
+//
+// Returns:
+//   - time.Duration: The measured round-trip time.
+//   - error: An error if the pong is not received in time.
+func (g *Group) Ping() (latency time.Duration, err error) {
+	cb := func(frame string) bool {
+		if strings.TrimRight(frame, "\r\n\x00") == "" {
+			return false
+		}
+		// Send the frame back to the listener.
+		g.events <- frame
+		return true
+	}
+
+	start := time.Now()
+	if err = g.SyncSendWithTimeout(cb, SYNC_SEND_TIMEOUT, "\r\n"); err != nil {
+		return
+	}
+	latency = time.Since(start)
+	g.latency.Store(int64(latency))
+
+	return
+}
+
+// Latency returns the last round-trip time measured by [Group.Ping].
+//
+// Returns:
+//   - time.Duration: The last round-trip time, zero if never measured.
+func (g *Group) Latency() time.Duration {
+	return time.Duration(g.latency.Load())
+}
+
 // SyncSendWithTimeout sends the specified arguments and waits for a response or timeout.
 //
 // First, a [Group.takeOver] request will be made and it will wait until the [listener] goroutine catches it.
@@ -297,10 +706,32 @@ func (g *Group) SyncSendWithTimeout(callback func(string) bool, timeout time.Dur
 	ctx, cancel := context.WithTimeout(g.context, timeout)
 	defer cancel()
 
+	return g.syncSend(ctx, callback, args...)
+}
+
+// syncSend sends the specified arguments and waits for a response until the context or the group context is done.
+//
+// See [Group.SyncSendWithTimeout].
+func (g *Group) syncSend(ctx context.Context, callback func(string) bool, args ...string) (err error) {
+	// Register the send while holding the lock, so that a drain never misses it.
+	g.stateMu.Lock()
+	if g.draining {
+		g.stateMu.Unlock()
+		return ErrNotConnected
+	}
+	g.inFlight.Add(1)
+	g.stateMu.Unlock()
+	defer g.inFlight.Done()
+
+	// Bind to the current connection, a reconnect may replace it meanwhile.
+	closeEvents := g.closeEvents
+
 	// Make a takeover request to allow the listener to relinquish the connection.
 	select {
 	case <-ctx.Done():
 		return ErrTimeout
+	case <-g.context.Done():
+		return ErrTimeout
 	case g.takeOver <- ctx:
 	}
 
@@ -314,12 +745,14 @@ func (g *Group) SyncSendWithTimeout(callback func(string) bool, timeout time.Dur
 		select {
 		case <-ctx.Done():
 			return ErrTimeout
+		case <-g.context.Done():
+			return ErrTimeout
 		case frame, ok = <-g.ws.Events:
 			if !ok {
-				close(g.events)
+				closeEvents()
 				return ErrConnectionClosed
 			}
-			if strings.HasPrefix(frame, "climited") {
+			if strings.HasPrefix(frame, "climited") && isClimitedFor(frame, args) {
 				// This response is received when messages are being sent too quickly.
 				// I believe this applies globally to any type of command sent to the server,
 				// not limited to sending messages alone.
@@ -334,6 +767,28 @@ func (g *Group) SyncSendWithTimeout(callback func(string) bool, timeout time.Dur
 	}
 }
 
+// isClimitedFor checks whether the "climited" frame refers to the command in [args].
+//
+// The server echoes the rejected command after the timestamp, e.g. "climited:1485666967794:bm:e8n2:...".
+// The command name and its first argument (the nonce for "bm") are compared.
+func isClimitedFor(frame string, args []string) bool {
+	fields := strings.SplitN(frame, ":", 5)
+	if len(fields) < 3 || len(args) < 2 {
+		return true
+	}
+
+	if fields[2] != args[0] {
+		return false
+	}
+
+	// The last args is the terminator.
+	if len(args) > 2 && len(fields) > 3 {
+		return fields[3] == args[1]
+	}
+
+	return true
+}
+
 // SyncSend will send the [args] and wait until receiving the correct reply or until timeout (default to 5 seconds).
 //
 // For more information, refer to the documentation of [Group.SyncSendWithTimeout].
@@ -357,97 +812,256 @@ func (g *Group) SyncSend(callback func(string) bool, args ...string) error {
 // If the group is anonymous, the message text is modified with the anonymous seed based on the group's [Group.AnonName] and [Group.UserID].
 // The function replaces newlines with the "<br/>" HTML tag to format the message properly.
 //
+// For more information about how the sent message is correlated, refer to [Group.SendMessageAndWaitEcho].
+//
+// Args:
+//   - text: The message text.
+//   - a: Optional arguments to format the message text.
+//
+// Returns:
+//   - *Message: The sent message.
+//   - error: An error if sending the message fails.
+func (g *Group) SendMessage(text string, a ...any) (*Message, error) {
+	return g.SendMessageAndWaitEcho(SYNC_SEND_TIMEOUT, text, a...)
+}
+
+// SendMessageAndWaitEcho sends a message to the group and waits until the server echoes it back or until timeout.
+//
+// Each sent message carries a random nonce which the server sends back along with the "climited" response,
+// so a "climited" response of another command is not mistaken for this one.
+// The nonce is not echoed otherwise, so the echoed "b" frame is correlated by its order, see [sendEcho].
+// The sent messages of the group are serialized, so only one of them awaits its echo at a time.
+//
+// Args:
+//   - timeout: The duration to wait for the echo.
+//   - text: The message text.
+//   - a: Optional arguments to format the message text.
+//
+// Returns:
+//   - *Message: The sent message.
+//   - error: An error if sending the message fails.
+func (g *Group) SendMessageAndWaitEcho(timeout time.Duration, text string, a ...any) (msg *Message, err error) {
+	return g.sendMessage(timeout, g.Channel, text, a...)
+}
+
+// SendMessageToChannel sends a message to the group on the given channel, without changing [Group.Channel].
+//
+// Only the channel and badge flags of the [channel] are used.
+// The mod channel, the mod badge, and the staff badge require the relevant moderator permission.
+//
 // Args:
+//   - channel: The channel flags, e.g. [models.FlagRedChannel] or [models.FlagModChannel].
 //   - text: The message text.
 //   - a: Optional arguments to format the message text.
 //
 // Returns:
 //   - *Message: The sent message.
+//   - error: [ErrNoPermission] if the channel requires a missing permission, or an error if sending the message fails.
+func (g *Group) SendMessageToChannel(channel models.MessageChannel, text string, a ...any) (*Message, error) {
+	channel &= sendableChannels
+
+	for flag, permission := range channelPermissions {
+		if channel&flag != 0 && !g.hasPermission(permission) {
+			return nil, fmt.Errorf("%w: %s", ErrNoPermission, permission)
+		}
+	}
+
+	return g.sendMessage(SYNC_SEND_TIMEOUT, int64(channel), text, a...)
+}
+
+// sendableChannels is the mask of the channel and badge flags accepted by the "bm" command.
+const sendableChannels = models.FlagModIcon | models.FlagStaffIcon | models.FlagRedChannel | models.FlagOrangeChannel |
+	models.FlagGreenChannel | models.FlagBlueChannel | models.FlagAzureChannel | models.FlagPurpleChannel |
+	models.FlagPinkChannel | models.FlagModChannel
+
+// channelPermissions maps the channel flags to the moderator permissions they require.
+var channelPermissions = map[models.MessageChannel]string{
+	models.FlagModChannel: "SEE_MOD_CHANNEL",
+	models.FlagModIcon:    "MOD_ICON_VISIBLE",
+	models.FlagStaffIcon:  "IS_STAFF",
+}
+
+// colorChannels is the mask of the color channel flags, of which at most one can be used at a time.
+const colorChannels = models.FlagRedChannel | models.FlagOrangeChannel | models.FlagGreenChannel | models.FlagBlueChannel |
+	models.FlagAzureChannel | models.FlagPurpleChannel | models.FlagPinkChannel
+
+// SetDefaultChannel validates the [channel] and sets it as [Group.Channel], which is used by [Group.SendMessage].
+//
+// This is the preferred way to change the channel; assigning [Group.Channel] directly skips the validation.
+// The [channel] may combine at most one color channel with the mod channel and the badge flags.
+// The mod channel, the mod badge, and the staff badge require the relevant moderator permission.
+//
+// Args:
+//   - channel: The channel flags, e.g. [models.FlagRedChannel] or [models.FlagModChannel] | [models.FlagModIcon].
+//
+// Returns:
+//   - error: [ErrInvalidChannel] if the combination is invalid, or [ErrNoPermission] if a permission is missing.
+func (g *Group) SetDefaultChannel(channel models.MessageChannel) error {
+	if extra := channel &^ sendableChannels; extra != 0 {
+		return fmt.Errorf("%w: unsupported flags %d", ErrInvalidChannel, extra)
+	}
+
+	if colors := channel & colorChannels; colors&(colors-1) != 0 {
+		return fmt.Errorf("%w: more than one color channel (%d)", ErrInvalidChannel, colors)
+	}
+
+	for flag, permission := range channelPermissions {
+		if channel&flag != 0 && !g.hasPermission(permission) {
+			return fmt.Errorf("%w: %s", ErrNoPermission, permission)
+		}
+	}
+
+	g.Channel = int64(channel)
+	return nil
+}
+
+// SendRaw sends the HTML as-is to the group on the given channel, e.g. an "img123" embed or custom markup.
+//
+// Unlike [Group.SendMessage], the text is neither styled nor has its newlines replaced.
+// The caller is responsible for escaping the HTML.
+//
+// Args:
+//   - channel: The channel flags, see [Group.Channel].
+//   - html: The message HTML.
+//
+// Returns:
+//   - *Message: The sent message.
 //   - error: An error if sending the message fails.
-func (g *Group) SendMessage(text string, a ...any) (msg *Message, err error) {
-	var idBuffer = map[string]string{}
+func (g *Group) SendRaw(channel int64, html string) (*Message, error) {
+	return g.sendHTML(context.Background(), SYNC_SEND_TIMEOUT, channel, html)
+}
+
+// SendMessageWithContext sends a message to the group and waits until the server echoes it back,
+// until [SYNC_SEND_TIMEOUT], or until [ctx] is done, whichever comes first.
+//
+// Args:
+//   - ctx: The context bounding the send.
+//   - text: The message text.
+//   - a: Optional arguments to format the message text.
+//
+// Returns:
+//   - *Message: The sent message.
+//   - error: The [ctx] error if it is done before the echo is received, or an error if sending the message fails.
+func (g *Group) SendMessageWithContext(ctx context.Context, text string, a ...any) (*Message, error) {
+	return g.sendHTML(ctx, SYNC_SEND_TIMEOUT, g.Channel, g.styleText(fmt.Sprintf(text, a...)))
+}
+
+// sendMessage sends a message to the group on the given channel and waits until the server echoes it back or until timeout.
+//
+// See [Group.SendMessageAndWaitEcho].
+func (g *Group) sendMessage(timeout time.Duration, channel int64, text string, a ...any) (*Message, error) {
+	return g.sendHTML(context.Background(), timeout, channel, g.styleText(fmt.Sprintf(text, a...)))
+}
+
+// sendHTML sends the already styled text to the group on the given channel and waits until the server echoes it back,
+// until timeout, or until [ctx] is done.
+func (g *Group) sendHTML(ctx context.Context, timeout time.Duration, channel int64, text string) (msg *Message, err error) {
+	if g.autoThrottle.Load() {
+		if err = g.waitRateLimit(ctx); err != nil {
+			return
+		}
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var echo *sendEcho
 	cb := func(frame string) bool {
 		head, data, _ := strings.Cut(frame, ":")
 		switch head {
 		case "b":
 			g.events <- frame
-			if msg == nil {
-				message := ParseGroupMessage(data, g)
-				if !message.User.IsSelf {
-					return true
-				}
-				msg = message
-				if newID, ok := idBuffer[message.ID]; ok {
-					message.ID = newID
-					return false
-				}
+			if echo.onMessage(ParseGroupMessage(data, g)) {
+				msg = echo.msg
+				return false
 			}
 		case "u":
 			g.events <- frame
 			oldID, newID, _ := strings.Cut(data, ":")
-			if msg != nil && msg.ID == oldID {
-				msg.ID = newID
+			if echo.onUpdate(oldID, newID) {
+				msg = echo.msg
 				return false
 			}
-			idBuffer[oldID] = newID
-		case "show_fw":
-			g.eventRestrictUpdate(data)
-			err = ErrFloodWarning
-			return false
-		case "show_tb", "tb":
-			g.eventRestrictUpdate(data)
-			err = ErrRestricted
-			return false
-		case "show_nlp":
-			if mask, _ := strconv.Atoi(data); mask&2 == 2 {
-				err = ErrSpamWarning
-			} else if mask&8 == 8 {
-				err = ErrShortWarning
-			} else {
-				err = ErrNonSenseWarning
-			}
-			return false
-		case "show_nlp_tb":
-			// The first data in the fields is unknown, so let's leave it as it is for now.
-			// show_nlp_tb:3:900
-			_, min, _ := strings.Cut(data, ":")
-			g.eventRestrictUpdate(min)
-			err = ErrRestricted
-			return false
-		case "nlptb":
-			g.eventRestrictUpdate(data)
-			err = ErrRestricted
-			return false
-		case "msglexceeded":
-			g.MaxMessageLength, _ = strconv.Atoi(data)
-			err = ErrMessageLength
-			return false
-		case "ratelimited":
-			dur, _ := time.ParseDuration(data + "s")
-			g.RateLimited = time.Now().Add(dur)
-			err = ErrRateLimited
-			return false
-		case "mustlogin":
-			err = ErrMustLogin
-			return false
-		case "proxybanned":
-			err = ErrProxyBanned
-			return false
-		case "verificationrequired":
-			err = ErrVerificationRequired
-			return false
 		default:
+			if err = g.sendFailure(head, data); err != nil {
+				return false
+			}
 			// Send the frame back to the listener.
 			g.events <- frame
 		}
 		return true
 	}
 
-	// I'm not sure what it is for, but it gets sent back to the client when "climited" occurs.
-	randomString := strconv.FormatInt(int64(15e5*rand.Float64()), 36)
+	// The nonce gets sent back to the client when "climited" occurs.
+	nonce := strconv.FormatInt(int64(15e5*rand.Float64()), 36)
+
+	echo = newSendEcho(text)
+
+	// Only one message of the group awaits its echo at a time, see [sendEcho].
+	g.sendMu.Lock()
+	start := time.Now()
+	if err2 := g.syncSend(sendCtx, cb, "bm", nonce, fmt.Sprintf("%d", channel), text, "\r\n"); err == nil && err2 != nil {
+		err = err2
+	}
+	g.sendMu.Unlock()
+	// Release the listener, so the handlers of the restriction events may send themselves.
+	cancel()
+	if err == ErrTimeout && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	g.notifyRestriction(err)
+
+	if err == nil && msg != nil {
+		g.sendTimings.record(time.Now())
+		g.App.getMetrics().ObserveSendLatency(g.Name, time.Since(start))
+	}
+
+	return
+}
+
+// SetAutoThrottle enables or disables the client-side throttling.
+//
+// When enabled, sending a message waits until [Group.RateLimited] has passed instead of being rejected by the server.
+//
+// Args:
+//   - enabled: Whether to enable the throttling.
+func (g *Group) SetAutoThrottle(enabled bool) {
+	g.autoThrottle.Store(enabled)
+}
+
+// waitRateLimit waits until [Group.RateLimited] has passed, the group is disconnected, or [ctx] is done.
+//
+// The server reports the rate limit as a duration, which is turned into [Group.RateLimited] against the client clock
+// upon receipt, so the server and client time difference ([Group.TimeDiff]) is already accounted for.
+//
+// Args:
+//   - ctx: The context of the caller.
+//
+// Returns:
+//   - error: [ErrConnectionClosed] if the group is disconnected while waiting, or the [ctx] error if it is done.
+func (g *Group) waitRateLimit(ctx context.Context) error {
+	wait := time.Until(g.RateLimited)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
 
-	text = fmt.Sprintf(text, a...)
+	select {
+	case <-g.context.Done():
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
+// styleText applies the user style (name color, font, or anonymous seed) to the message text,
+// and replaces the newlines with the `<br/>` tags.
+func (g *Group) styleText(text string) string {
 	// Style thing
 	if g.LoggedIn {
 		text = fmt.Sprintf(`<n%s/><f x%02d%s="%s">%s`, g.NameColor, g.TextSize, g.TextColor, g.TextFont, text)
@@ -464,44 +1078,608 @@ func (g *Group) SendMessage(text string, a ...any) (msg *Message, err error) {
 	text = strings.ReplaceAll(text, "\r\n", "<br/>")
 	text = strings.ReplaceAll(text, "\n", "<br/>")
 
-	if err2 := g.SyncSend(cb, "bm", randomString, fmt.Sprintf("%d", g.Channel), text, "\r\n"); err == nil && err2 != nil {
+	return text
+}
+
+// sendFailure interprets the frame received in response to a sent message as an error.
+//
+// Args:
+//   - head: The head of the frame.
+//   - data: The data of the frame.
+//
+// Returns:
+//   - error: The sentinel error of the failure, nil if the frame is not a failure.
+func (g *Group) sendFailure(head, data string) error {
+	switch head {
+	case "show_fw":
+		g.updateRestrict(data)
+		return ErrFloodWarning
+	case "show_tb", "tb":
+		g.updateRestrict(data)
+		return ErrRestricted
+	case "show_nlp":
+		if mask, _ := strconv.Atoi(data); mask&2 == 2 {
+			return ErrSpamWarning
+		} else if mask&8 == 8 {
+			return ErrShortWarning
+		}
+		return ErrNonSenseWarning
+	case "show_nlp_tb":
+		// The first data in the fields is unknown, so let's leave it as it is for now.
+		// show_nlp_tb:3:900
+		_, min, _ := strings.Cut(data, ":")
+		g.updateRestrict(min)
+		return ErrRestricted
+	case "nlptb":
+		g.updateRestrict(data)
+		return ErrRestricted
+	case "msglexceeded":
+		g.MaxMessageLength, _ = strconv.Atoi(data)
+		return ErrMessageLength
+	case "ratelimited":
+		dur, _ := time.ParseDuration(data + "s")
+		g.RateLimited = time.Now().Add(dur)
+		return ErrRateLimited
+	case "mustlogin":
+		return ErrMustLogin
+	case "proxybanned":
+		g.ProxyBanned = true
+		g.proxyBanInfo = data
+		return ErrProxyBanned
+	case "verificationrequired":
+		return ErrVerificationRequired
+	}
+
+	return nil
+}
+
+// notifyRestriction dispatches the restriction event matching the send failure, if any.
+//
+// It must be called after [Group.SyncSend] returns, since the handlers may send messages themselves.
+//
+// Args:
+//   - err: The error returned by [Group.sendFailure].
+func (g *Group) notifyRestriction(err error) {
+	switch err {
+	case ErrRateLimited:
+		g.dispatchRestriction(OnRateLimited, g.RateLimited)
+	case ErrFloodWarning, ErrRestricted:
+		g.dispatchRestriction(OnRestricted, g.Restrict)
+	case ErrProxyBanned:
+		g.dispatchProxyBanned()
+	}
+}
+
+// dispatchRestriction dispatches a restriction event.
+//
+// Args:
+//   - eventType: Either [OnRateLimited] or [OnRestricted].
+//   - until: The time when the restriction expires.
+func (g *Group) dispatchRestriction(eventType EventType, until time.Time) {
+	event := &Event{
+		Type:          eventType,
+		Group:         g,
+		RestrictUntil: until,
+	}
+
+	g.App.dispatchEvent(event)
+}
+
+// EditMessage edits the message sent by the current user, this is a premium feature.
+//
+// The new text is styled the same way as [Group.SendMessage].
+// The edited message gets a new ID, which replaces the old one in [Group.Messages], and keeps the old text as its PreviousText.
+//
+// Args:
+//   - message: The message to edit.
+//   - newText: The new message text.
+//   - a: Optional arguments to format the new message text.
+//
+// Returns:
+//   - *Message: The edited message.
+//   - error: [ErrNotOwned] if the message is not sent by the current user, or an error if editing the message fails.
+func (g *Group) EditMessage(message *Message, newText string, a ...any) (edited *Message, err error) {
+	if !(&Event{Group: g, Message: message}).IsFromSelf() {
+		return nil, ErrNotOwned
+	}
+
+	text := g.styleText(fmt.Sprintf(newText, a...))
+
+	cb := func(frame string) bool {
+		head, data, _ := strings.Cut(frame, ":")
+		switch head {
+		case "updmsg":
+			// updmsg:oldID:newID
+			oldID, newID, _ := strings.Cut(data, ":")
+			if oldID != message.ID {
+				g.events <- frame
+				break
+			}
+			edited = &Message{Group: g, Message: message.Message}
+			edited.ID = newID
+			edited.RawText = text
+			edited.Text = groupText(text, g)
+			edited.Edited = true
+			edited.EditedTime = time.Now()
+			edited.PreviousText = message.Text
+			return false
+		default:
+			if err = g.sendFailure(head, data); err != nil {
+				return false
+			}
+			g.events <- frame
+		}
+		return true
+	}
+
+	if err2 := g.SyncSend(cb, "updmsg", message.ID, text, "\r\n"); err == nil && err2 != nil {
 		err = err2
 	}
-
-	return
+
+	g.notifyRestriction(err)
+
+	if err == nil && edited != nil {
+		// The edited message keeps its position in the history.
+		g.Messages.Replace(message.ID, edited.ID, edited)
+	}
+
+	return
+}
+
+// SendTimings returns the statistics of the recently sent messages, useful for tuning the sending rate.
+//
+// The average interval is computed over the last [SEND_TIMINGS_SIZE] sent messages.
+//
+// Returns:
+//   - int: The total number of the sent messages.
+//   - time.Time: The time of the last sent message, zero if none.
+//   - time.Duration: The average interval between the recently sent messages, zero if less than two.
+func (g *Group) SendTimings() (sent int, lastSend time.Time, avgInterval time.Duration) {
+	return g.sendTimings.stats()
+}
+
+// sendTimings is a ring buffer of the recent send times.
+type sendTimings struct {
+	sync.Mutex
+	times [SEND_TIMINGS_SIZE]time.Time // times holds the recent send times.
+	next  int                          // next is the index of the next entry to write.
+	sent  int                          // sent is the total number of the recorded sends.
+}
+
+// record records a send at the given time.
+func (s *sendTimings) record(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.times[s.next] = t
+	s.next = (s.next + 1) % SEND_TIMINGS_SIZE
+	s.sent++
+}
+
+// stats returns the total sends, the last send time, and the average interval between the buffered sends.
+func (s *sendTimings) stats() (sent int, lastSend time.Time, avgInterval time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	sent = s.sent
+	if sent == 0 {
+		return
+	}
+
+	lastSend = s.times[(s.next+SEND_TIMINGS_SIZE-1)%SEND_TIMINGS_SIZE]
+
+	buffered := utils.Min(sent, SEND_TIMINGS_SIZE)
+	if buffered < 2 {
+		return
+	}
+
+	oldest := s.times[(s.next+SEND_TIMINGS_SIZE-buffered)%SEND_TIMINGS_SIZE]
+	avgInterval = lastSend.Sub(oldest) / time.Duration(buffered-1)
+
+	return
+}
+
+// messageMark tracks the latest processed message.
+type messageMark struct {
+	sync.Mutex
+	id   string    // id is the ID of the latest message.
+	time time.Time // time is the time of the latest message.
+}
+
+// update marks the message if it is newer than the marked one.
+func (m *messageMark) update(message *Message) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.id == "" || message.Time.After(m.time) {
+		m.id, m.time = message.ID, message.Time
+	}
+}
+
+// get returns the ID and the time of the marked message.
+func (m *messageMark) get() (string, time.Time) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.id, m.time
+}
+
+// markResumePoint remembers the messages processed so far, before the [Group.Messages] are reset by a reconnect.
+//
+// The history up to the latest processed message has been seen, see [Group.eventMessageHistory].
+func (g *Group) markResumePoint() {
+	lastID, lastTime := g.lastSeen.get()
+	if lastID == "" {
+		return
+	}
+
+	ids := []string{lastID}
+	g.Messages.Range(func(id string, _ *Message) bool {
+		ids = append(ids, id)
+		return true
+	})
+	g.resumePoint.reset(ids, lastTime)
+}
+
+// resumePoint holds the messages processed before a reconnect, so the reloaded history is not dispatched again.
+type resumePoint struct {
+	sync.Mutex
+	ids  map[string]struct{} // ids holds the IDs of the processed messages, nil before the first reconnect.
+	time time.Time           // time is the time of the latest processed message.
+}
+
+// reset replaces the processed messages.
+func (r *resumePoint) reset(ids []string, latest time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.ids = make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		r.ids[id] = struct{}{}
+	}
+	r.time = latest
+}
+
+// check reports whether the history message was processed before, and whether the history follows a reconnect.
+//
+// The history frames may be handled in any order, so each message is checked on its own.
+// A message older than the latest processed one counts as processed, even if it is not remembered,
+// e.g. the latest one has been deleted meanwhile.
+func (r *resumePoint) check(message *Message) (processed, resuming bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.ids == nil {
+		return false, false
+	}
+
+	_, processed = r.ids[message.ID]
+
+	return processed || message.Time.Before(r.time), true
+}
+
+// SetDedupe enables or disables the deduplication of the dispatched messages.
+//
+// When enabled, the [OnMessage] and [OnMessageHistory] events are dispatched at most once per message ID,
+// e.g. the history reloaded after a reconnect is not dispatched again.
+// Enabling or disabling it forgets the previously seen IDs.
+//
+// Args:
+//   - enabled: Whether to enable the deduplication.
+//   - window: The number of the most recent IDs to remember, defaults to [DEDUPE_WINDOW] if not positive.
+func (g *Group) SetDedupe(enabled bool, window int) {
+	if window <= 0 {
+		window = DEDUPE_WINDOW
+	}
+
+	g.seenIDs.reset(enabled, window)
+}
+
+// idWindow is a bounded LRU set of IDs.
+type idWindow struct {
+	sync.Mutex
+	enabled bool                     // enabled indicates if the IDs are tracked.
+	size    int                      // size is the maximum number of the remembered IDs.
+	order   *list.List               // order holds the IDs from the most to the least recently seen.
+	index   map[string]*list.Element // index maps the IDs to their element in the order.
+}
+
+// reset forgets every ID and applies the new settings.
+func (w *idWindow) reset(enabled bool, size int) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.enabled = enabled
+	w.size = size
+	w.order = list.New()
+	w.index = map[string]*list.Element{}
+}
+
+// seen records the ID and reports whether it has been seen before, always false if disabled.
+func (w *idWindow) seen(id string) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	if !w.enabled {
+		return false
+	}
+
+	if elem, ok := w.index[id]; ok {
+		w.order.MoveToFront(elem)
+		return true
+	}
+
+	w.index[id] = w.order.PushFront(id)
+	if w.order.Len() > w.size {
+		oldest := w.order.Back()
+		w.order.Remove(oldest)
+		delete(w.index, oldest.Value.(string))
+	}
+
+	return false
+}
+
+// sendEcho correlates a sent message with the frames echoed back by the server.
+//
+// The server first echoes the message with a temporary ID in the "b" frame,
+// then sends the permanent ID in the "u" frame; both may arrive in any order.
+//
+// The server returns nothing identifying the sent message, so the echo is a self-sent message within the send window,
+// which holds [Group.sendMu] so that no other message of the group is awaiting its echo meanwhile.
+// A message with the same text is preferred, as another session of the same account may be sending too.
+// Otherwise, the first self-sent message is taken once its permanent ID arrives,
+// since the server may rewrite the text (e.g. the banned words, trimming, or the HTML entities).
+// The late echo of a previous send that timed out may still be taken this way.
+type sendEcho struct {
+	text     string            // text is the plain text of the sent message.
+	msg      *Message          // msg is the echoed message, nil until it is received.
+	fallback *Message          // fallback is the first self-sent message with a different text, see [sendEcho].
+	idBuffer map[string]string // idBuffer holds the "u" frames received before their "b" frame.
+}
+
+// newSendEcho returns a new [sendEcho] for the given raw sent text.
+func newSendEcho(rawText string) *sendEcho {
+	return &sendEcho{
+		text:     normalizeWhitespace(plainText(rawText)),
+		idBuffer: map[string]string{},
+	}
+}
+
+// onMessage handles an echoed message and reports whether the correlation is complete.
+func (e *sendEcho) onMessage(message *Message) bool {
+	if e.msg != nil || !message.User.IsSelf {
+		return false
+	}
+
+	if normalizeWhitespace(message.Text) != e.text {
+		if e.fallback != nil {
+			return false
+		}
+		e.fallback = message
+		if _, ok := e.idBuffer[message.ID]; !ok {
+			return false
+		}
+		// The permanent ID has already arrived.
+	}
+
+	e.msg = message
+	if newID, ok := e.idBuffer[message.ID]; ok {
+		message.ID = newID
+		return true
+	}
+
+	return false
+}
+
+// onUpdate handles a message ID update and reports whether the correlation is complete.
+func (e *sendEcho) onUpdate(oldID, newID string) bool {
+	if e.msg == nil && e.fallback != nil && e.fallback.ID == oldID {
+		e.msg = e.fallback
+	}
+
+	if e.msg != nil && e.msg.ID == oldID {
+		e.msg.ID = newID
+		return true
+	}
+
+	e.idBuffer[oldID] = newID
+
+	return false
+}
+
+// SendMessageChunked sends the chunked [text] with a size of [chunkSize] and returns the sent [[]*Message].
+//
+// In the event of an error, the already sent messages will be returned along with the error for the unsent message.
+//
+// Args:
+//   - text: The large message text.
+//   - chunkSize: The size of each chunk.
+//
+// Returns:
+//   - []*Message: The sent messages.
+//   - error: An error if sending the message in chunks fails.
+func (g *Group) SendMessageChunked(text string, chunkSize int) (msgs []*Message, err error) {
+	var msg *Message
+	for _, chunk := range utils.SplitTextIntoChunks(text, chunkSize) {
+		if msg, err = g.SendMessage(chunk); err != nil {
+			return
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return
+}
+
+// SendTemplate renders the template with the data using [TemplateMessage] and sends the result.
+//
+// Args:
+//   - tmpl: The template text.
+//   - data: The data to render the template with.
+//
+// Returns:
+//   - *Message: The sent message.
+//   - error: An error if rendering the template or sending the message fails.
+func (g *Group) SendTemplate(tmpl string, data any) (*Message, error) {
+	text, err := TemplateMessage(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.SendMessage("%s", text)
+}
+
+// SendLines sends each line as a separate message and returns the results aligned with [lines].
+//
+// Unlike [Group.SendMessageChunked], it does not stop at the first failure, and
+// unlike a single multiline [Group.SendMessage], the lines are not joined with "<br/>".
+// If the group is rate limited, it waits until the rate limit expires before sending the next line.
+//
+// Args:
+//   - lines: The lines to send.
+//
+// Returns:
+//   - []*Message: The sent messages, nil for the failed lines.
+//   - []error: The errors, nil for the successfully sent lines.
+func (g *Group) SendLines(lines []string) (msgs []*Message, errs []error) {
+	msgs = make([]*Message, len(lines))
+	errs = make([]error, len(lines))
+
+	for i, line := range lines {
+		if wait := time.Until(g.RateLimited); wait > 0 {
+			select {
+			case <-g.context.Done():
+			case <-time.After(wait):
+			}
+		}
+
+		msgs[i], errs[i] = g.SendMessage(line)
+	}
+
+	return
+}
+
+// WaitReady waits until the group has been initialized, i.e. the "inited" frame is received and the message history is loaded.
+//
+// The history is loaded once it is stored in [Group.Messages] and its events are dispatched,
+// both the history sent on login and the pages fetched up to [MAX_MESSAGE_HISTORY].
+//
+// Args:
+//   - ctx: The context to cancel the wait.
+//
+// Returns:
+//   - error: [ErrNotConnected] if the group is not connected, [ErrTimeout] if the context is done before the group is ready,
+//     or [ErrConnectionClosed] if the group is disconnected while waiting.
+func (g *Group) WaitReady(ctx context.Context) error {
+	// A reconnect replaces the channel.
+	g.stateMu.Lock()
+	ready := g.ready
+	g.stateMu.Unlock()
+
+	if ready == nil || g.context == nil {
+		return ErrNotConnected
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ErrTimeout
+	case <-g.context.Done():
+		return ErrConnectionClosed
+	}
+}
+
+// WaitUntilConnected waits until the login handshake of the group completes, i.e. the "ok" frame is handled
+// and the [OnGroupJoined] event is about to be dispatched.
+//
+// It returns immediately if the handshake has already completed.
+// Unlike [Group.WaitReady], it does not wait for the message history.
+//
+// Args:
+//   - ctx: The context to cancel the wait.
+//
+// Returns:
+//   - error: [ErrNotConnected] if the group is not connected, [ErrTimeout] if the context is done before the handshake completes,
+//     or [ErrConnectionClosed] if the group is disconnected while waiting.
+func (g *Group) WaitUntilConnected(ctx context.Context) error {
+	g.stateMu.Lock()
+	joined := g.joined
+	g.stateMu.Unlock()
+
+	if joined == nil || g.context == nil {
+		return ErrNotConnected
+	}
+
+	// Prefer the completed handshake over a done context.
+	select {
+	case <-joined:
+		return nil
+	default:
+	}
+
+	select {
+	case <-joined:
+		return nil
+	case <-ctx.Done():
+		return ErrTimeout
+	case <-g.context.Done():
+		return ErrConnectionClosed
+	}
 }
 
-// SendMessageChunked sends the chunked [text] with a size of [chunkSize] and returns the sent [[]*Message].
+// IsRestricted checks if the group is restricted.
 //
-// In the event of an error, the already sent messages will be returned along with the error for the unsent message.
+// The restriction can originate from either a flood ban or a rate limit.
 //
-// Args:
-//   - text: The large message text.
-//   - chunkSize: The size of each chunk.
+// Returns:
+//   - bool: True if the group is restricted.
+func (g *Group) IsRestricted() bool {
+	return g.Restrict.After(time.Now()) || g.RateLimited.After(time.Now())
+}
+
+// RestrictionReason returns the source of the current restriction and its expiry.
+//
+// If both the flood ban and the rate limit are active, the one expiring later is returned,
+// since the restriction lasts until both expire.
 //
 // Returns:
-//   - []*Message: The sent messages.
-//   - error: An error if sending the message in chunks fails.
-func (g *Group) SendMessageChunked(text string, chunkSize int) (msgs []*Message, err error) {
-	var msg *Message
-	for _, chunk := range utils.SplitTextIntoChunks(text, chunkSize) {
-		if msg, err = g.SendMessage(chunk); err != nil {
-			return
-		}
-		msgs = append(msgs, msg)
+//   - string: Either [RestrictionFloodBan] or [RestrictionRateLimit], empty if the group is not restricted.
+//   - time.Time: The time when the restriction expires, zero if the group is not restricted.
+func (g *Group) RestrictionReason() (reason string, until time.Time) {
+	now := time.Now()
+
+	if g.Restrict.After(now) {
+		reason, until = RestrictionFloodBan, g.Restrict
+	}
+	if g.RateLimited.After(now) && g.RateLimited.After(until) {
+		reason, until = RestrictionRateLimit, g.RateLimited
 	}
 
 	return
 }
 
-// IsRestricted checks if the group is restricted.
+// RateLimitRemaining returns the remaining time until the rate limit expires.
 //
-// The restriction can originate from either a flood ban or a rate limit.
+// Returns:
+//   - time.Duration: The remaining time, zero if the group is not rate-limited.
+func (g *Group) RateLimitRemaining() time.Duration {
+	if remaining := time.Until(g.RateLimited); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// RestrictionRemaining returns the remaining time until the restriction (flood ban or auto moderation) expires.
 //
 // Returns:
-//   - bool: True if the group is restricted.
-func (g *Group) IsRestricted() bool {
-	return g.Restrict.After(time.Now()) || g.RateLimited.After(time.Now())
+//   - time.Duration: The remaining time, zero if the group is not restricted.
+func (g *Group) RestrictionRemaining() time.Duration {
+	if remaining := time.Until(g.Restrict); remaining > 0 {
+		return remaining
+	}
+
+	return 0
 }
 
 // GetParticipantsStart initiates the "participant" event feeds and returns the current participants.
@@ -519,34 +1697,57 @@ func (g *Group) GetParticipantsStart() (p *SyncMap[string, *models.Participant],
 		case "gparticipants":
 			g.Participants.Clear()
 			anoncount, entries, _ := strings.Cut(data, ":")
-			g.AnonCount, _ = strconv.Atoi(anoncount)
+			anonCount, _ := strconv.Atoi(anoncount)
 
-			var fields []string
-			var user *models.User
-			var t time.Time
-			var participant *models.Participant
-			for _, entry := range strings.Split(entries, ";") {
-				fields = strings.SplitN(entry, ":", 6)
-				t, _ = utils.ParseTime(fields[1])
-				userID, _ := strconv.Atoi(fields[2])
-				if fields[3] != "None" {
-					user = &models.User{Name: fields[3]}
-				} else if fields[4] != "None" {
-					user = &models.User{Name: fields[4], IsAnon: true}
-				} else {
-					user = &models.User{Name: utils.GetAnonName(int(t.Unix()), userID), IsAnon: true}
-				}
-				user.IsSelf = userID == g.UserID && user.Name == g.LoginName
-				participant = &models.Participant{
-					ParticipantID: fields[0],
-					UserID:        userID,
-					User:          user,
-					Time:          t,
-				}
-				g.Participants.Set(fields[0], participant)
+			for _, participant := range g.parseParticipants(entries) {
+				g.Participants.Set(participant.ParticipantID, participant)
 			}
 			p = &g.Participants
+			g.countMu.Lock()
+			g.AnonCount = anonCount
 			g.UserCount = g.Participants.Len()
+			g.participantsFeed = true
+			g.countMu.Unlock()
+			return false
+		default:
+			g.events <- frame
+		}
+		return true
+	}
+
+	err = g.SyncSend(cb, "gparticipants", "\r\n")
+
+	return
+}
+
+// GetParticipantsSnapshot returns the current participants without leaving the participant event feeds running.
+//
+// Unlike [Group.GetParticipantsStart], [Group.Participants] and the counts are left untouched.
+// If the feeds are already started, they are kept running.
+//
+// Returns:
+//   - []models.Participant: The current participants.
+//   - error: An error if fetching the participants fails.
+func (g *Group) GetParticipantsSnapshot() (participants []models.Participant, err error) {
+	g.countMu.RLock()
+	feedStarted := g.participantsFeed
+	g.countMu.RUnlock()
+
+	cb := func(frame string) bool {
+		head, data, _ := strings.Cut(frame, ":")
+		switch head {
+		case "gparticipants":
+			if !feedStarted {
+				// Stop the feeds right away, so that as few "participant" events as possible slip through.
+				g.GetParticipantsStop()
+			}
+
+			_, entries, _ := strings.Cut(data, ":")
+			parsed := g.parseParticipants(entries)
+			participants = make([]models.Participant, 0, len(parsed))
+			for _, participant := range parsed {
+				participants = append(participants, *participant)
+			}
 			return false
 		default:
 			g.events <- frame
@@ -559,6 +1760,100 @@ func (g *Group) GetParticipantsStart() (p *SyncMap[string, *models.Participant],
 	return
 }
 
+// parseParticipants parses the participant entries of the "gparticipants" frame.
+//
+// Args:
+//   - entries: The ";" separated participant entries.
+//
+// Returns:
+//   - []*models.Participant: The parsed participants.
+func (g *Group) parseParticipants(entries string) (participants []*models.Participant) {
+	if entries == "" {
+		return
+	}
+
+	var fields []string
+	var user *models.User
+	var t time.Time
+	for _, entry := range strings.Split(entries, ";") {
+		fields = strings.SplitN(entry, ":", 6)
+		t, _ = utils.ParseTime(fields[1])
+		userID, _ := strconv.Atoi(fields[2])
+		if fields[3] != "None" {
+			user = &models.User{Name: fields[3]}
+		} else if fields[4] != "None" {
+			user = &models.User{Name: fields[4], IsAnon: true}
+		} else {
+			user = &models.User{Name: utils.GetAnonName(int(t.Unix()), userID), IsAnon: true}
+		}
+		user.IsSelf = userID == g.UserID && user.Name == g.LoginName
+		participants = append(participants, &models.Participant{
+			ParticipantID: fields[0],
+			UserID:        userID,
+			User:          user,
+			Time:          t,
+		})
+	}
+
+	return
+}
+
+// Counts returns a consistent snapshot of the participant counts.
+//
+// Unlike [Group.UserCount] and [Group.AnonCount], which are tracked incrementally and may drift after missed frames,
+// the registered and anonymous counts are recomputed from [Group.Participants].
+// Invoke [Group.GetParticipantsStart] to keep the participants up to date.
+//
+// Returns:
+//   - int: The count of registered users.
+//   - int: The count of anonymous users.
+//   - int64: The total count of participants reported by the server.
+func (g *Group) Counts() (registered, anon int, total int64) {
+	g.Participants.Range(func(_ string, p *models.Participant) bool {
+		if p.User.IsAnon {
+			anon++
+		} else {
+			registered++
+		}
+		return true
+	})
+
+	g.countMu.RLock()
+	total = g.ParticipantCount
+	g.countMu.RUnlock()
+
+	return
+}
+
+// recount reconciles the [Group.UserCount] with [Group.Participants], as set by [Group.GetParticipantsStart].
+//
+// The participants are only complete while the participant feeds are started, so nothing is done otherwise.
+// The [Group.AnonCount] is reported by the server, and is kept as is.
+func (g *Group) recount() {
+	g.countMu.Lock()
+	defer g.countMu.Unlock()
+
+	if !g.participantsFeed {
+		return
+	}
+	g.UserCount = g.Participants.Len()
+}
+
+// recountLoop periodically reconciles the participant counts, every [RECOUNT_INTERVAL], until the group is disconnected.
+func (g *Group) recountLoop() {
+	ticker := time.NewTicker(RECOUNT_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.context.Done():
+			return
+		case <-ticker.C:
+			g.recount()
+		}
+	}
+}
+
 // GetParticipantsStop stops the participant event feeds.
 //
 // This will leave [Group.Participants], [Group.UserCount], [Group.AnonCount] out of date.
@@ -566,6 +1861,10 @@ func (g *Group) GetParticipantsStart() (p *SyncMap[string, *models.Participant],
 // Returns:
 //   - error: An error if stopping the fetch fails.
 func (g *Group) GetParticipantsStop() error {
+	g.countMu.Lock()
+	g.participantsFeed = false
+	g.countMu.Unlock()
+
 	return g.Send("gparticipants", "stop", "\r\n")
 }
 
@@ -597,15 +1896,53 @@ func (g *Group) GetRateLimit() (rate, current time.Duration, err error) {
 	return
 }
 
+// FloodMode retrieves the current flood control configuration of the group.
+//
+// It interprets the rate limit the same way as the "chrl" moderation action:
+// a zero rate limit means the group is flood controlled, otherwise it is in slow mode.
+//
+// Returns:
+//   - string: Either [FloodModeControlled] or [FloodModeSlow].
+//   - int: The slow mode interval in seconds, zero if flood controlled.
+//   - error: An error if retrieving the rate limit fails.
+func (g *Group) FloodMode() (mode string, seconds int, err error) {
+	var rate time.Duration
+	if rate, _, err = g.GetRateLimit(); err != nil {
+		return
+	}
+
+	mode, seconds = floodMode(rate)
+
+	return
+}
+
+// floodMode interprets the rate limit into a flood mode and its interval in seconds.
+func floodMode(rate time.Duration) (string, int) {
+	if seconds := int(rate.Seconds()); seconds > 0 {
+		return FloodModeSlow, seconds
+	}
+
+	return FloodModeControlled, 0
+}
+
 // SetRateLimit sets the rate limit interval for the group.
 //
+// The interval is rounded to seconds and must be within 0 and [MAX_RATE_LIMIT].
+// If the server echoes a different interval (e.g. clamped), [ErrRequestFailed] is returned along with the actual interval.
+// On success, [Group.RateLimit] is updated.
+//
 // Args:
 //   - interval: The new rate limit interval.
 //
 // Returns:
-//   - time.Duration: The set rate limit.
-//   - error: An error if setting the rate limit fails.
+//   - time.Duration: The rate limit set by the server.
+//   - error: [ErrInvalidRateLimit] if the interval is out of range, or an error if setting the rate limit fails.
 func (g *Group) SetRateLimit(interval time.Duration) (rate time.Duration, err error) {
+	interval = interval.Round(time.Second)
+	if interval < 0 || interval > MAX_RATE_LIMIT {
+		return 0, ErrInvalidRateLimit
+	}
+
 	cb := func(frame string) bool {
 		head, data, _ := strings.Cut(frame, ":")
 		switch head {
@@ -618,27 +1955,60 @@ func (g *Group) SetRateLimit(interval time.Duration) (rate time.Duration, err er
 		return true
 	}
 
-	err = g.SyncSend(cb, "setratelimit", fmt.Sprintf("%.0f", interval.Seconds()), "\r\n")
+	if err = g.SyncSend(cb, "setratelimit", fmt.Sprintf("%.0f", interval.Seconds()), "\r\n"); err != nil {
+		return
+	}
+
+	if rate != interval {
+		return rate, fmt.Errorf("%w: the server set the rate limit to %s instead of %s", ErrRequestFailed, rate, interval)
+	}
+
+	g.RateLimit = rate
 
 	return
 }
 
 // GetAnnouncement retrieves the announcement settings for the group.
 //
+// The announcement text is cleaned with [models.CleanAnnouncement], use [Group.GetRawAnnouncement] for the original one.
+//
 // Returns:
 //   - string: The announcement text.
 //   - bool: True if announcements are enabled, otherwise false.
 //   - time.Duration: The interval between announcements.
 //   - error: An error if retrieving the announcement settings fails.
 func (g *Group) GetAnnouncement() (annc string, enabled bool, interval time.Duration, err error) {
+	if annc, enabled, interval, err = g.GetRawAnnouncement(); err == nil {
+		annc = models.CleanAnnouncement(annc)
+	}
+
+	return
+}
+
+// GetRawAnnouncement retrieves the announcement settings for the group, with the original announcement text.
+//
+// Returns:
+//   - string: The original announcement text.
+//   - bool: True if announcements are enabled, otherwise false.
+//   - time.Duration: The interval between announcements.
+//   - error: An error if retrieving the announcement settings fails.
+func (g *Group) GetRawAnnouncement() (annc string, enabled bool, interval time.Duration, err error) {
 	cb := func(frame string) bool {
 		head, data, _ := strings.Cut(frame, ":")
 		switch head {
 		case "getannc":
-			fields := strings.SplitN(data, ":", 6)
-			annc = fields[4]
+			// getannc:enabled:group:?:interval:text
+			// The text may contain colons, so it is kept whole.
+			fields := strings.SplitN(data, ":", 5)
+			if len(fields) < 5 {
+				err = ErrRequestFailed
+				return false
+			}
 			enabled = fields[0] != "0"
-			interval, _ = time.ParseDuration(fields[3] + "s")
+			if seconds, err2 := strconv.Atoi(fields[3]); err2 == nil && seconds > 0 {
+				interval = time.Duration(seconds) * time.Second
+			}
+			annc = fields[4]
 			return false
 		default:
 			g.events <- frame
@@ -696,6 +2066,44 @@ func (g *Group) UpdateGroupFlag(addition, removal int64) (err error) {
 	return
 }
 
+// UpdateGroupFlagTyped updates the group's flag by adding and removing the typed flags.
+//
+// See [Group.UpdateGroupFlag].
+//
+// Args:
+//   - addition: The flags to add.
+//   - removal: The flags to remove.
+//
+// Returns:
+//   - error: An error if updating the group's flag fails.
+func (g *Group) UpdateGroupFlagTyped(addition, removal models.GroupFlag) error {
+	return g.UpdateGroupFlag(int64(addition), int64(removal))
+}
+
+// Flags returns the typed feature flags of the group.
+//
+// Returns:
+//   - models.GroupFlag: The feature flags of the group.
+func (g *Group) Flags() models.GroupFlag {
+	return models.GroupFlag(g.Flag)
+}
+
+// HasBroadcast checks whether the group is in the broadcast mode.
+//
+// Returns:
+//   - bool: True if the broadcast mode is enabled.
+func (g *Group) HasBroadcast() bool {
+	return g.Flags().Has(models.GroupFlagBroadcastMode)
+}
+
+// ChannelsEnabled checks whether the channels are enabled in the group.
+//
+// Returns:
+//   - bool: True if the channels are enabled.
+func (g *Group) ChannelsEnabled() bool {
+	return g.Flags().Has(models.GroupFlagEnableChannels)
+}
+
 // GetPremiumInfo retrieves the premium status and expiration time for the group.
 //
 // This function would activate server validation for the premium status.
@@ -769,7 +2177,31 @@ func (g *Group) SetMedia(enable bool) (err error) {
 		}
 	}
 
-	return g.Send("msgmedia", utils.BoolZeroOrOne(enable), "\r\n")
+	if err = g.Send("msgmedia", utils.BoolZeroOrOne(enable), "\r\n"); err == nil {
+		g.mediaOn.Store(enable)
+	}
+
+	return
+}
+
+// SendImage sends the uploaded image to the group, see [PrivateAPI.UploadImage].
+//
+// The media feature is enabled first if not yet enabled on this connection, which requires a premium membership.
+//
+// Args:
+//   - img: The uploaded image.
+//
+// Returns:
+//   - *Message: The sent message.
+//   - error: [ErrRequestFailed] if the media feature can't be enabled, or an error if sending the message fails.
+func (g *Group) SendImage(img models.UploadedImage) (*Message, error) {
+	if !g.mediaOn.Load() {
+		if err := g.SetMedia(true); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.SendMessage("%s", img.MessageEmbed())
 }
 
 // GetBanList retrieves a list of blocked users (ban list) for the group.
@@ -1011,6 +2443,151 @@ func (g *Group) UnbanAll() (amount int, err error) {
 	return
 }
 
+// UnbanMatching unblocks the blocked users matching the predicate.
+//
+// The whole ban list is fetched page by page first, then the matching users are unblocked
+// one by one with an interval of [UNBAN_INTERVAL] to avoid being rate limited.
+//
+// Args:
+//   - pred: The function reporting whether a blocked user should be unblocked.
+//   - progress: The optional function called after each unblock with the done and total counts.
+//
+// Returns:
+//   - int: The amount of unblocked users.
+//   - error: An error if retrieving the ban list or unblocking a user fails.
+func (g *Group) UnbanMatching(pred func(models.Blocked) bool, progress func(done, total int)) (amount int, err error) {
+	var banList []models.Blocked
+	if banList, err = collectBanList(g.GetBanList); err != nil {
+		return
+	}
+
+	var matches []models.Blocked
+	for _, blocked := range banList {
+		if pred(blocked) {
+			matches = append(matches, blocked)
+		}
+	}
+
+	for i := range matches {
+		if i > 0 {
+			select {
+			case <-g.context.Done():
+				return amount, ErrConnectionClosed
+			case <-time.After(UNBAN_INTERVAL):
+			}
+		}
+
+		if err = g.UnbanUser(&matches[i]); err != nil {
+			return
+		}
+		amount++
+
+		if progress != nil {
+			progress(amount, len(matches))
+		}
+	}
+
+	return
+}
+
+// GetBanListAll retrieves the whole ban list of the group, page by page.
+//
+// The entries sharing the same time may appear on two adjacent pages, so they are deduplicated by the moderation ID.
+//
+// Args:
+//   - ctx: The context checked between the pages.
+//   - pageSize: The number of banned users to retrieve per page, defaults to [BAN_LIST_PAGE_SIZE] if not positive.
+//
+// Returns:
+//   - []Blocked: The whole ban list, from newer to older.
+//   - error: An error if retrieving a page fails, or the [ctx] error if it is done.
+func (g *Group) GetBanListAll(ctx context.Context, pageSize int) ([]models.Blocked, error) {
+	return collectPages(ctx, pageSize, g.GetBanList, func(blocked models.Blocked) (string, time.Time) {
+		return blocked.ModerationID, blocked.Time
+	})
+}
+
+// GetUnbanListAll retrieves the whole unban list of the group, page by page.
+//
+// The entries sharing the same time may appear on two adjacent pages, so they are deduplicated by the moderation ID.
+//
+// Args:
+//   - ctx: The context checked between the pages.
+//   - pageSize: The number of unbanned users to retrieve per page, defaults to [BAN_LIST_PAGE_SIZE] if not positive.
+//
+// Returns:
+//   - []Unblocked: The whole unban list, from newer to older.
+//   - error: An error if retrieving a page fails, or the [ctx] error if it is done.
+func (g *Group) GetUnbanListAll(ctx context.Context, pageSize int) ([]models.Unblocked, error) {
+	return collectPages(ctx, pageSize, g.GetUnbanList, func(unblocked models.Unblocked) (string, time.Time) {
+		return unblocked.ModerationID, unblocked.Time
+	})
+}
+
+// collectBanList fetches the whole ban list page by page, see [Group.GetBanList].
+//
+// Args:
+//   - fetch: The function retrieving a page of the ban list.
+//
+// Returns:
+//   - []Blocked: The whole ban list, from newer to older.
+//   - error: An error if retrieving a page fails.
+func collectBanList(fetch func(time.Time, int) ([]models.Blocked, error)) ([]models.Blocked, error) {
+	return collectPages(context.Background(), BAN_LIST_PAGE_SIZE, fetch, func(blocked models.Blocked) (string, time.Time) {
+		return blocked.ModerationID, blocked.Time
+	})
+}
+
+// collectPages fetches the whole list page by page, using the time of the last entry as the next offset.
+//
+// The entries sharing the same time may appear on two adjacent pages, so they are deduplicated by their IDs.
+// The paging stops once a short page or a page without new entries is returned.
+//
+// Args:
+//   - ctx: The context checked between the pages.
+//   - pageSize: The number of entries to retrieve per page, defaults to [BAN_LIST_PAGE_SIZE] if not positive.
+//   - fetch: The function retrieving a page from the offset.
+//   - key: The function returning the ID and the time of an entry.
+//
+// Returns:
+//   - []T: The whole list.
+//   - error: An error if retrieving a page fails, or the [ctx] error if it is done.
+func collectPages[T any](ctx context.Context, pageSize int, fetch func(time.Time, int) ([]T, error), key func(T) (string, time.Time)) (list []T, err error) {
+	if pageSize <= 0 {
+		pageSize = BAN_LIST_PAGE_SIZE
+	}
+
+	seen := make(map[string]bool)
+
+	var offset time.Time
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var page []T
+		if page, err = fetch(offset, pageSize); err != nil {
+			return
+		}
+
+		added := 0
+		for _, entry := range page {
+			id, _ := key(entry)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			list = append(list, entry)
+			added++
+		}
+
+		if len(page) < pageSize || added == 0 {
+			return
+		}
+		_, offset = key(page[len(page)-1])
+	}
+}
+
 // Login logs in to the group with the provided username and password.
 //
 // If the password is an empty string, it will log in as the named anon instead.
@@ -1340,6 +2917,163 @@ func (g *Group) RemoveModerator(username string) (err error) {
 	return
 }
 
+// RemoveAllModerators removes every moderator of the group, e.g. for a group handover.
+//
+// It requires the current user to be the owner or to have the EDIT_MODS permission.
+// The owner is never removed. The current user is skipped unless [includeSelf] is true,
+// in which case it is removed last, since removing oneself reloads the group state.
+//
+// In the event of an error, the already removed moderators are returned along with the error.
+//
+// Args:
+//   - includeSelf: Whether to remove the current user as well.
+//
+// Returns:
+//   - []string: The removed moderators.
+//   - error: [ErrNoPermission] if the current user is not allowed to edit moderators, or an error if a removal fails.
+func (g *Group) RemoveAllModerators(includeSelf bool) (removed []string, err error) {
+	if !g.canEditModerators() {
+		return nil, ErrNoPermission
+	}
+
+	var self string
+	for _, username := range g.Moderators.Keys() {
+		switch {
+		case strings.EqualFold(username, g.Owner):
+			continue
+		case strings.EqualFold(username, g.LoginName):
+			self = username
+			continue
+		}
+
+		if err = g.RemoveModerator(username); err != nil {
+			return
+		}
+		removed = append(removed, username)
+	}
+
+	if includeSelf && self != "" {
+		if err = g.RemoveModerator(self); err != nil {
+			return
+		}
+		removed = append(removed, self)
+	}
+
+	return
+}
+
+// ExportModerators returns a snapshot of the moderators of the group and their access levels.
+//
+// Returns:
+//   - map[string]int64: The access levels keyed by the moderator names.
+func (g *Group) ExportModerators() map[string]int64 {
+	g.Moderators.RLock()
+	defer g.Moderators.RUnlock()
+
+	mods := make(map[string]int64, len(g.Moderators.M))
+	for username, access := range g.Moderators.M {
+		mods[username] = access
+	}
+
+	return mods
+}
+
+// ImportModerators converges the moderators of the group to the given ones,
+// adding, updating, and removing the moderators as needed.
+//
+// It requires the current user to be the owner or to have the EDIT_MODS permission.
+// The owner is never changed, and the entries already matching are skipped.
+// A change to the current user is applied last, since it reloads the group state.
+//
+// In the event of an error, the count of the already applied changes is returned along with the error.
+//
+// Args:
+//   - mods: The access levels keyed by the moderator names.
+//
+// Returns:
+//   - int: The count of the applied changes.
+//   - error: [ErrNoPermission] if the current user is not allowed to edit moderators, or an error if a change fails.
+func (g *Group) ImportModerators(mods map[string]int64) (applied int, err error) {
+	if !g.canEditModerators() {
+		return 0, ErrNoPermission
+	}
+
+	wanted := make(map[string]int64, len(mods))
+	for username, access := range mods {
+		wanted[strings.ToLower(username)] = access
+	}
+	current := g.ExportModerators()
+
+	var changes []func() error
+	var selfChange func() error
+	plan := func(username string, change func() error) {
+		switch {
+		case strings.EqualFold(username, g.Owner):
+		case strings.EqualFold(username, g.LoginName):
+			selfChange = change
+		default:
+			changes = append(changes, change)
+		}
+	}
+
+	for _, username := range sortedKeys(wanted) {
+		username, access := username, wanted[username]
+		if currentAccess, ok := current[username]; !ok {
+			plan(username, func() error { return g.AddModerator(username, access) })
+		} else if currentAccess != access {
+			plan(username, func() error { return g.UpdateModerator(username, access) })
+		}
+	}
+	for _, username := range sortedKeys(current) {
+		username := username
+		if _, ok := wanted[username]; !ok {
+			plan(username, func() error { return g.RemoveModerator(username) })
+		}
+	}
+
+	if selfChange != nil {
+		changes = append(changes, selfChange)
+	}
+	for _, change := range changes {
+		if err = change(); err != nil {
+			return
+		}
+		applied++
+	}
+
+	return
+}
+
+// sortedKeys returns the keys of the map in ascending order.
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// canEditModerators checks whether the current user is the owner or has the EDIT_MODS permission.
+func (g *Group) canEditModerators() bool {
+	return g.hasPermission("EDIT_MODS")
+}
+
+// hasPermission checks whether the current user is the owner or has the permission.
+//
+// Args:
+//   - permission: The permission name in [models.GroupPermissions].
+func (g *Group) hasPermission(permission string) bool {
+	if g.Owner != "" && strings.EqualFold(g.Owner, g.LoginName) {
+		return true
+	}
+
+	access, _ := g.Moderators.Get(strings.ToLower(g.LoginName))
+
+	return access&models.GroupPermissions[permission] != 0
+}
+
 // GetModActions retrieves a list of moderator actions (mod actions) for the group.
 //
 // Args:
@@ -1393,24 +3127,102 @@ func (g *Group) GetLastUserMessage(username string) (msg *Message, ok bool) {
 	return
 }
 
+// BanUserByName bans the specified username using their latest bannable message in the group.
+//
+// A message is bannable if it carries a moderation ID, which may be missing for the anonymous users' messages,
+// in which case an older message of the same user is used.
+//
+// Args:
+//   - username: The username to ban.
+//
+// Returns:
+//   - error: [ErrNoBannableMessage] if the user has no bannable message in [Group.Messages], or an error if banning the user fails.
+func (g *Group) BanUserByName(username string) error {
+	msg, ok := g.GetLastUserMessage(username)
+	if ok && msg.ModerationID == "" {
+		ok = false
+		g.Messages.RangeReversed(func(_ string, v *Message) bool {
+			if strings.EqualFold(v.User.Name, username) && v.ModerationID != "" {
+				msg, ok = v, true
+				return false
+			}
+			return true
+		})
+	}
+
+	if !ok {
+		return ErrNoBannableMessage
+	}
+
+	return g.BanUser(msg)
+}
+
+// GetUserIP retrieves the IP address of the specified username from their latest message in the group.
+//
+// Note:
+//   - The IP address is only visible to the moderators.
+//
+// Args:
+//   - username: The username to retrieve the IP address for.
+//
+// Returns:
+//   - string: The IP address of the user.
+//   - bool: A boolean indicating if the IP address was found.
+func (g *Group) GetUserIP(username string) (ip string, ok bool) {
+	if msg, found := g.GetLastUserMessage(username); found && msg.UserIP != "" {
+		return msg.UserIP, true
+	}
+
+	return
+}
+
+// GetMessageByID retrieves the message with the specified ID in the group.
+//
+// The ID can be either a permanent ID or a temporary one.
+// A message whose permanent ID ("u" frame) has not arrived yet is looked up by its temporary ID,
+// and a temporary ID already promoted to a permanent one is followed.
+//
+// Args:
+//   - id: The ID of the message.
+//
+// Returns:
+//   - *Message: The message with the specified ID.
+//   - bool: A boolean indicating if a message was found.
+func (g *Group) GetMessageByID(id string) (msg *Message, ok bool) {
+	if msg, ok = g.Messages.Get(id); ok {
+		return
+	}
+
+	if msg, ok = g.TempMessages.Get(id); ok {
+		return
+	}
+
+	if newID, found := g.TempMessageIds.Get(id); found {
+		return g.Messages.Get(newID)
+	}
+
+	return
+}
+
 // getMoreHistory retrieves additional history messages from the group.
 //
-// The offset starts with 0 from the latest messages, then [nextOffset = prevOffset + amount].
+// The offset starts with 0 from the latest messages, then [nextOffset = prevOffset + 1].
 //
 // Args:
 //   - offset: The offset from which to start retrieving messages.
 //   - amount: The number of history messages to fetch.
+//   - onHistory: The function receiving each "i" frame.
 //
 // Returns:
 //   - int: The count of received messages.
 //   - bool: A boolean indicating if there are no more messages to fetch.
 //   - error: An error if the operation encounters any issues.
-func (g *Group) getMoreHistory(offset, amount int) (count int, nomore bool, err error) {
+func (g *Group) getMoreHistory(offset, amount int, onHistory func(string)) (count int, nomore bool, err error) {
 	cb := func(frame string) bool {
 		head, _, _ := strings.Cut(frame, ":")
 		switch head {
 		case "i":
-			g.events <- frame
+			onHistory(frame)
 			count++
 		case "gotmore":
 			return false
@@ -1427,6 +3239,48 @@ func (g *Group) getMoreHistory(offset, amount int) (count int, nomore bool, err
 	return
 }
 
+// LoadMoreHistory loads the older history messages, continuing from the previously loaded ones.
+//
+// The messages are prepended to [Group.Messages] and dispatched as [OnMessageHistory] events, from newer to older.
+//
+// Args:
+//   - amount: The number of history messages to load.
+//
+// Returns:
+//   - int: The count of loaded messages.
+//   - bool: A boolean indicating if the oldest message has been reached.
+//   - error: An error if loading the messages fails.
+func (g *Group) LoadMoreHistory(amount int) (loaded int, noMore bool, err error) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+
+	for len(g.historyBuf) < amount && !g.historyDone {
+		// The server pages by a fixed size, so the surplus is buffered for the next call.
+		var count int
+		count, g.historyDone, err = g.getMoreHistory(g.historyOffset, HISTORY_PAGE_SIZE, func(frame string) {
+			g.historyBuf = append(g.historyBuf, frame)
+		})
+		if err != nil {
+			break
+		}
+		g.historyOffset++
+
+		if count == 0 {
+			break
+		}
+	}
+
+	frames := g.historyBuf[:utils.Min(amount, len(g.historyBuf))]
+	g.historyBuf = g.historyBuf[len(frames):]
+
+	// The frames are handled after the [Group.SyncSend] returns, so the handlers may use it.
+	for _, frame := range frames {
+		g.historyMessage(g.storeHistory(frame[2:]), false)
+	}
+
+	return len(frames), g.historyDone && len(g.historyBuf) == 0, err
+}
+
 // ProfileRefresh notifies the server to refresh the profile.
 //
 // Returns:
@@ -1473,9 +3327,14 @@ func (g *Group) GetContext() context.Context {
 // Args:
 //   - err: The error that occurred.
 func (g *Group) wsOnError(err error) {
-	close(g.events)
+	g.closeEvents()
 	close(g.takeOver)
-	if g.Connected {
+
+	g.stateMu.Lock()
+	retry := g.Connected && !g.noRetry
+	g.stateMu.Unlock()
+
+	if retry {
 		if g.Reconnect() == nil {
 			log.Debug().Str("Name", g.Name).Msg("Reconnected")
 			event := &Event{
@@ -1495,6 +3354,14 @@ func (g *Group) wsOnError(err error) {
 		Group: g,
 	}
 	g.App.dispatchEvent(event)
+
+	g.stateMu.Lock()
+	leaving := g.leaving
+	g.stateMu.Unlock()
+
+	if leaving && g.App.Config.PurgeChatDataOnLeave {
+		g.App.persistence.DelChatData(g.Name)
+	}
 }
 
 // wsOnFrame handles incoming WebSocket frames.
@@ -1534,7 +3401,9 @@ func (g *Group) wsOnFrame(frame string) {
 	case "u":
 		g.eventMessageUpdate(data)
 	case "end_fw", "end_nlp":
-		g.eventRestrictUpdate(data)
+		g.eventRestrictEnd(data)
+	case "ratelimited", "show_fw", "show_tb", "tb":
+		g.eventRestriction(head, data)
 	case "participant":
 		g.eventParticipant(data)
 	case "groupflagsupdate":
@@ -1559,9 +3428,15 @@ func (g *Group) wsOnFrame(frame string) {
 		g.eventUpdateGroupInfo(data)
 	case "miu", "updateprofile":
 		g.eventUpdateUserProfile(data)
-	case "show_fw", "show_tb", "tb", "show_nlp", "show_nlp_tb", "nlptb":
+	case "updmsg":
+		g.eventMessageEdit(data)
+	case "proxybanned":
+		g.eventProxyBanned(data)
+	case "verificationrequired":
+		g.eventVerificationRequired(data)
+	case "show_nlp", "show_nlp_tb", "nlptb":
 		fallthrough
-	case "msglexceeded", "ratelimited", "mustlogin", "proxybanned", "verificationrequired":
+	case "msglexceeded", "mustlogin":
 		fallthrough
 	case "gparticipants", "getratelimit", "ratelimitset", "getannc", "groupflagstoggled":
 		fallthrough
@@ -1635,6 +3510,8 @@ func (g *Group) eventOK(data string) {
 		go g.SetBackground(true)
 	}
 
+	g.markJoined()
+
 	event := &Event{
 		Type:  OnGroupJoined,
 		Group: g,
@@ -1643,12 +3520,51 @@ func (g *Group) eventOK(data string) {
 }
 
 // eventMessageHistory handles the message history event.
+//
+// After a reconnect, the messages processed before are only stored, while the ones missed meanwhile are dispatched as [OnMessage].
 func (g *Group) eventMessageHistory(data string) {
+	g.historyMessage(g.storeHistory(data), true)
+}
+
+// storeHistory parses the history message and prepends it to the [Group.Messages].
+//
+// Args:
+//   - data: The message data.
+//
+// Returns:
+//   - *Message: The stored message.
+func (g *Group) storeHistory(data string) *Message {
 	message := ParseGroupMessage(data, g)
 	g.Messages.SetFront(message.ID, message)
+	g.lastSeen.update(message)
+
+	return message
+}
+
+// historyMessage dispatches the stored history message.
+//
+// Args:
+//   - message: The history message, see [Group.storeHistory].
+//   - resume: Whether to skip dispatching the messages processed before the reconnect.
+func (g *Group) historyMessage(message *Message, resume bool) {
+	eventType := OnMessageHistory
+	if resume {
+		// The messages sent within the same second as the latest processed one are told apart by their IDs.
+		processed, resuming := g.resumePoint.check(message)
+		if processed {
+			return
+		}
+		if resuming {
+			eventType = OnMessage
+		}
+	}
+
+	if g.seenIDs.seen(message.ID) {
+		return
+	}
 
 	event := &Event{
-		Type:    OnMessageHistory,
+		Type:    eventType,
 		Group:   g,
 		Message: message,
 		User:    message.User,
@@ -1657,27 +3573,120 @@ func (g *Group) eventMessageHistory(data string) {
 }
 
 // eventInited handles the initialized event.
+//
+// The history pages are applied to the [Group.Messages] within the [Group.SyncSend] callback.
+// Propagating the "i" frames instead would leave them to the listener, which is blocked here if [Config.OrderedEvents] is enabled.
 func (g *Group) eventInited(string) {
-	// TODO: loading previous messages?
-	var offset int
+	g.historyMu.Lock()
+	g.historyOffset = 0
+	g.historyDone = false
+	g.historyBuf = nil
+
+	var history []*Message
 	var count int
-	var nomore bool
 	var err error
-	for histLen := g.Messages.Len(); histLen < MAX_MESSAGE_HISTORY && err == nil && !nomore; histLen += count {
-		count, nomore, err = g.getMoreHistory(offset, utils.Min(20, MAX_MESSAGE_HISTORY-histLen))
-		offset++
+	histLen := g.Messages.Len()
+	for histLen < MAX_MESSAGE_HISTORY && err == nil && !g.historyDone {
+		// Fetch whole pages, keeping the offset aligned; the surplus is left for [Group.LoadMoreHistory].
+		count, g.historyDone, err = g.getMoreHistory(g.historyOffset, HISTORY_PAGE_SIZE, func(frame string) {
+			if histLen < MAX_MESSAGE_HISTORY {
+				history = append(history, g.storeHistory(frame[2:]))
+				histLen++
+			} else {
+				g.historyBuf = append(g.historyBuf, frame)
+			}
+		})
+		g.historyOffset++
+
+		if count == 0 {
+			break
+		}
+	}
+	g.historyMu.Unlock()
+
+	if g.App.Config.PersistMessages {
+		g.restoreMessages(g.App.persistence)
+	}
+
+	// The events are dispatched after the [Group.SyncSend] returns, so the handlers may use it.
+	for _, message := range history {
+		g.historyMessage(message, true)
+	}
+
+	// Only now is the history in place, see [Group.WaitReady].
+	g.markReady()
+}
+
+// markReady marks the group as ready, releasing the [Group.WaitReady] callers.
+func (g *Group) markReady() {
+	g.stateMu.Lock()
+	defer g.stateMu.Unlock()
+
+	select {
+	case <-g.ready:
+		// Already marked, e.g. re-initialized after a moderator change.
+	default:
+		close(g.ready)
+	}
+}
+
+// markJoined marks the login handshake as completed, releasing the [Group.WaitUntilConnected] callers.
+func (g *Group) markJoined() {
+	g.stateMu.Lock()
+	defer g.stateMu.Unlock()
+
+	if g.joined == nil {
+		return
+	}
+
+	select {
+	case <-g.joined:
+		// Already marked, e.g. a repeated "ok" frame.
+	default:
+		close(g.joined)
 	}
 }
 
 // eventParticipantCount handles the participant count change event.
+//
+// The frame may carry the registered and anonymous counts after the total, which replace the tracked counts.
+// The [OnParticipantListUpdate] event is only dispatched if these counts change.
 func (g *Group) eventParticipantCount(data string) {
-	g.ParticipantCount, _ = strconv.ParseInt(data, 16, 64)
+	// n:total[:users:anons], all in hexadecimal.
+	fields := strings.Split(data, ":")
+	count, _ := strconv.ParseInt(fields[0], 16, 64)
+
+	changed := false
+	g.countMu.Lock()
+	g.ParticipantCount = count
+	if len(fields) >= 3 {
+		users, err1 := strconv.ParseInt(fields[1], 16, 0)
+		anons, err2 := strconv.ParseInt(fields[2], 16, 0)
+		if err1 == nil && err2 == nil && (int(users) != g.UserCount || int(anons) != g.AnonCount) {
+			g.UserCount, g.AnonCount = int(users), int(anons)
+			changed = true
+		}
+	}
+	userCount, anonCount := g.UserCount, g.AnonCount
+	g.countMu.Unlock()
 
 	event := &Event{
 		Type:  OnParticipantCountChange,
 		Group: g,
 	}
 	g.App.dispatchEvent(event)
+
+	if !changed {
+		return
+	}
+
+	event = &Event{
+		Type:      OnParticipantListUpdate,
+		Group:     g,
+		UserCount: userCount,
+		AnonCount: anonCount,
+	}
+	g.App.dispatchEvent(event)
 }
 
 // eventMessage handles the message event.
@@ -1686,16 +3695,7 @@ func (g *Group) eventMessage(data string) {
 	if id, ok := g.TempMessageIds.Get(message.ID); ok {
 		g.TempMessageIds.Del(message.ID)
 		message.ID = id
-		g.Messages.Set(message.ID, message)
-		g.Messages.TrimFront(MAX_MESSAGE_HISTORY)
-
-		event := &Event{
-			Type:    OnMessage,
-			Group:   g,
-			Message: message,
-			User:    message.User,
-		}
-		g.App.dispatchEvent(event)
+		g.commitMessage(message)
 	} else {
 		g.TempMessages.Set(message.ID, message)
 	}
@@ -1706,26 +3706,90 @@ func (g *Group) eventMessageUpdate(data string) {
 	oldID, newID, _ := strings.Cut(data, ":")
 	if message, ok := g.TempMessages.Get(oldID); ok {
 		message.ID = newID
-		g.Messages.Set(newID, message)
-		g.Messages.TrimFront(MAX_MESSAGE_HISTORY)
 		g.TempMessages.Del(oldID)
-
-		event := &Event{
-			Type:    OnMessage,
-			Group:   g,
-			Message: message,
-			User:    message.User,
-		}
-		g.App.dispatchEvent(event)
+		g.commitMessage(message)
 	} else {
 		g.TempMessageIds.Set(oldID, newID)
 	}
 }
 
-// eventRestrictUpdate handles the restrict update event.
-func (g *Group) eventRestrictUpdate(data string) {
+// commitMessage stores the message under its permanent ID and dispatches it.
+func (g *Group) commitMessage(message *Message) {
+	g.Messages.Set(message.ID, message)
+	g.Messages.TrimFront(MAX_MESSAGE_HISTORY)
+	g.lastSeen.update(message)
+
+	if g.seenIDs.seen(message.ID) {
+		return
+	}
+
+	event := &Event{
+		Type:    OnMessage,
+		Group:   g,
+		Message: message,
+		User:    message.User,
+	}
+	g.App.dispatchEvent(event)
+}
+
+// eventMessageEdit handles the message edit event.
+//
+// An edited message gets a new ID, so the stored message is re-keyed, marked as edited,
+// keeps its previous text, and is dispatched as [OnMessageEdited].
+// The frame may carry the new raw text after the IDs, otherwise the text is kept.
+func (g *Group) eventMessageEdit(data string) {
+	// updmsg:oldID:newID[:text]
+	oldID, rest, _ := strings.Cut(data, ":")
+	newID, rawText, hasText := strings.Cut(rest, ":")
+
+	old, ok := g.Messages.Get(oldID)
+	if !ok {
+		log.Debug().Str("Name", g.Name).Str("ID", oldID).Msg("Edited message not found")
+		return
+	}
+
+	edited := &Message{Group: g, Message: old.Message}
+	edited.ID = newID
+	edited.Edited = true
+	edited.EditedTime = time.Now()
+	edited.PreviousText = old.Text
+	if hasText {
+		edited.RawText = rawText
+		edited.Text = groupText(rawText, g)
+	}
+
+	g.Messages.Replace(oldID, newID, edited)
+
+	event := &Event{
+		Type:    OnMessageEdited,
+		Group:   g,
+		Message: edited,
+		User:    edited.User,
+	}
+	g.App.dispatchEvent(event)
+}
+
+// eventRestrictEnd handles the restriction end event, dispatching [OnRestricted] with a zero RestrictUntil.
+func (g *Group) eventRestrictEnd(string) {
+	g.Restrict = time.Time{}
+	g.dispatchRestriction(OnRestricted, g.Restrict)
+}
+
+// eventRestriction handles the restriction frames received outside of a [Group.SendMessage],
+// e.g. in response to a message sent with [Group.Send].
+func (g *Group) eventRestriction(head, data string) {
+	g.notifyRestriction(g.sendFailure(head, data))
+}
+
+// updateRestrict updates the restriction time from the remaining minutes in [data].
+//
+// Returns:
+//   - time.Time: The time when the restriction expires.
+func (g *Group) updateRestrict(data string) time.Time {
 	dur, _ := time.ParseDuration(data + "m")
 	g.Restrict = time.Now().Add(dur)
+
+	return g.Restrict
 }
 
 // eventParticipant handles the participant event.
@@ -1757,6 +3821,7 @@ func (g *Group) eventParticipant(data string) {
 		User:  user,
 	}
 
+	g.countMu.Lock()
 	switch fields[0] {
 	case "1":
 		g.Participants.Set(fields[1], p)
@@ -1770,27 +3835,37 @@ func (g *Group) eventParticipant(data string) {
 	case "2":
 		oldParticipant, ok := g.Participants.Get(fields[1])
 		g.Participants.Set(fields[1], p)
+		// The counters only move if the type changes, an unknown participant is taken as an anonymous one.
+		wasAnon := !ok || oldParticipant.User.IsAnon
 		if !p.User.IsAnon {
 			event.Type = OnLogin
 			event.Participant = p
-			g.AnonCount--
-			g.UserCount++
-		} else if ok && !oldParticipant.User.IsAnon {
+			if wasAnon {
+				g.AnonCount = utils.Max(g.AnonCount-1, 0)
+				g.UserCount++
+			}
+		} else if !wasAnon {
 			event.Type = OnLogout
 			event.Participant = oldParticipant
 			g.AnonCount++
-			g.UserCount--
+			g.UserCount = utils.Max(g.UserCount-1, 0)
 		}
 	case "0":
-		g.Participants.Del(fields[1])
+		// The leaving participant is counted by its stored type, falling back to the frame.
+		isAnon := p.User.IsAnon
+		if oldParticipant, ok := g.Participants.Get(fields[1]); ok {
+			isAnon = oldParticipant.User.IsAnon
+			g.Participants.Del(fields[1])
+		}
 		event.Type = OnLeave
 		event.Participant = p
-		if p.User.IsAnon {
-			g.AnonCount--
+		if isAnon {
+			g.AnonCount = utils.Max(g.AnonCount-1, 0)
 		} else {
-			g.UserCount--
+			g.UserCount = utils.Max(g.UserCount-1, 0)
 		}
 	}
+	g.countMu.Unlock()
 
 	g.App.dispatchEvent(event)
 }
@@ -1827,7 +3902,7 @@ func (g *Group) eventModerators(data string) {
 		newMods                          = make(map[string]int64)
 		events                           []*Event
 		user                             *models.User
-		username, uname, flag            string
+		username, flag                   string
 		newFlag, oldFlag, added, removed int64
 		ok, selfRemoved                  bool
 		event                            *Event
@@ -1875,12 +3950,12 @@ func (g *Group) eventModerators(data string) {
 	}
 
 	// Process moderator removal
+	removedMods := make(map[string]int64)
 	cb := func(username string, oldFlag int64) bool {
-		for uname = range newMods {
-			if uname == username {
-				return false
-			}
+		if _, ok := newMods[username]; ok {
+			return true
 		}
+		removedMods[username] = oldFlag
 
 		// When it reaches this scope, it means the username has been removed.
 		user = &models.User{Name: username, IsSelf: strings.EqualFold(username, g.LoginName)}
@@ -1893,7 +3968,7 @@ func (g *Group) eventModerators(data string) {
 		if user.IsSelf {
 			selfRemoved = true
 		}
-		return false
+		return true
 	}
 	g.Moderators.Range(cb)
 
@@ -1906,10 +3981,13 @@ func (g *Group) eventModerators(data string) {
 			g.Send("reload_init_batch", "\r\n")
 		}()
 	} else {
-		// Update the g.Moderators
-		g.Moderators.Lock()
-		g.Moderators.M = newMods
-		g.Moderators.Unlock()
+		// Update the g.Moderators, the removed ones only if they have not been changed meanwhile.
+		for username, newFlag = range newMods {
+			g.Moderators.Set(username, newFlag)
+		}
+		for username, oldFlag = range removedMods {
+			CompareAndDelete(&g.Moderators, username, oldFlag)
+		}
 	}
 
 	for _, event = range events {
@@ -2032,3 +4110,37 @@ func (g *Group) eventUpdateUserProfile(data string) {
 	}
 	g.App.dispatchEvent(event)
 }
+
+// eventProxyBanned handles the proxy banned event.
+//
+// This event is triggered when the bot connects from a proxy or VPN while the group disallows it.
+// The frame data, if any (e.g. the banned IP), is passed as the [Event.Info].
+func (g *Group) eventProxyBanned(data string) {
+	g.ProxyBanned = true
+	g.proxyBanInfo = data
+
+	g.dispatchProxyBanned()
+}
+
+// dispatchProxyBanned dispatches the [OnProxyBanned] event with the data of the latest "proxybanned" frame.
+func (g *Group) dispatchProxyBanned() {
+	event := &Event{
+		Type:  OnProxyBanned,
+		Group: g,
+		Info:  g.proxyBanInfo,
+	}
+	g.App.dispatchEvent(event)
+}
+
+// eventVerificationRequired handles the verification required event.
+//
+// This event is triggered when the server asks the session to be verified (e.g. by solving a captcha) outside of a send.
+// The frame data, if any, is passed as the [Event.Info].
+func (g *Group) eventVerificationRequired(data string) {
+	event := &Event{
+		Type:  OnVerificationRequired,
+		Group: g,
+		Info:  data,
+	}
+	g.App.dispatchEvent(event)
+}
@@ -0,0 +1,1902 @@
+package chadango
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n0h4rt/chadango/models"
+	"github.com/n0h4rt/chadango/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+func TestFloodMode(t *testing.T) {
+	tests := []struct {
+		rate    time.Duration
+		mode    string
+		seconds int
+	}{
+		{0, FloodModeControlled, 0},
+		{time.Second, FloodModeSlow, 1},
+		{30 * time.Second, FloodModeSlow, 30},
+	}
+
+	for _, test := range tests {
+		mode, seconds := floodMode(test.rate)
+		assert.Equal(t, test.mode, mode, "floodMode(%v) mode should match", test.rate)
+		assert.Equal(t, test.seconds, seconds, "floodMode(%v) seconds should match", test.rate)
+	}
+}
+
+func TestSendEcho_ConcurrentSends(t *testing.T) {
+	group := &Group{
+		LoginName: "Nekonyan",
+		UserID:    48875733,
+	}
+
+	// Two messages are sent at the same time, the server echoes both frames to both senders.
+	frames := []struct {
+		head string
+		data string
+	}{
+		{"b", "1717866894:Nekonyan::48875733:modID1:tmp1:userIP:0::<n000/><f x11000=\"1\">first"},
+		{"u", "tmp2:final2"},
+		{"b", "1717866894:Nekonyan::48875733:modID2:tmp2:userIP:0::<n000/><f x11000=\"1\">second"},
+		{"u", "tmp1:final1"},
+	}
+
+	echoes := []*sendEcho{
+		newSendEcho(`<n000/><f x11000="1">first`),
+		newSendEcho(`<n000/><f x11000="1">second`),
+	}
+
+	done := make(chan *sendEcho, len(echoes))
+	for _, echo := range echoes {
+		go func(echo *sendEcho) {
+			for _, frame := range frames {
+				switch frame.head {
+				case "b":
+					if echo.onMessage(ParseGroupMessage(frame.data, group)) {
+						done <- echo
+						return
+					}
+				case "u":
+					oldID, newID, _ := strings.Cut(frame.data, ":")
+					if echo.onUpdate(oldID, newID) {
+						done <- echo
+						return
+					}
+				}
+			}
+			done <- echo
+		}(echo)
+	}
+	for range echoes {
+		<-done
+	}
+
+	if assert.NotNil(t, echoes[0].msg) {
+		assert.Equal(t, "first", echoes[0].msg.Text)
+		assert.Equal(t, "final1", echoes[0].msg.ID)
+	}
+	if assert.NotNil(t, echoes[1].msg) {
+		assert.Equal(t, "second", echoes[1].msg.Text)
+		assert.Equal(t, "final2", echoes[1].msg.ID)
+	}
+}
+
+func TestSendEcho_RewrittenText(t *testing.T) {
+	group := &Group{
+		LoginName: "Nekonyan",
+		UserID:    48875733,
+	}
+
+	echo := newSendEcho(`<n000/><f x11000="1">some badword here`)
+	assert.False(t, echo.onMessage(ParseGroupMessage("1717866894:someuser::12345678:modID:tmp0:userIP:0::<n000/>other", group)))
+	assert.False(t, echo.onMessage(ParseGroupMessage("1717866894:Nekonyan::48875733:modID:tmp1:userIP:0::<n000/>some *** here", group)))
+	assert.Nil(t, echo.msg, "The rewritten message should wait for its permanent ID")
+
+	assert.False(t, echo.onUpdate("tmp0", "final0"))
+	assert.True(t, echo.onUpdate("tmp1", "final1"), "The self-sent message should be taken despite the rewrite")
+	if assert.NotNil(t, echo.msg) {
+		assert.Equal(t, "final1", echo.msg.ID)
+		assert.Equal(t, "some *** here", echo.msg.Text)
+	}
+
+	echo = newSendEcho(`<n000/><f x11000="1">some badword here`)
+	assert.False(t, echo.onUpdate("tmp2", "final2"))
+	assert.True(t, echo.onMessage(ParseGroupMessage("1717866894:Nekonyan::48875733:modID:tmp2:userIP:0::<n000/>some *** here", group)), "The permanent ID may arrive first")
+	assert.Equal(t, "final2", echo.msg.ID)
+}
+
+func TestGroup_SendMessageSerialized(t *testing.T) {
+	var sent atomic.Int32
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head != "bm" {
+			return nil
+		}
+		// The server rewrites every text, so only the order tells the echoes apart.
+		n := sent.Add(1)
+		return []string{
+			fmt.Sprintf("b:1717866894:Nekonyan::48875733:modID:tmp%d:userIP:0::<n000/>rewritten", n),
+			fmt.Sprintf("u:tmp%d:msg%d", n, n),
+		}
+	})
+
+	const total = 10
+	ids := make(chan string, total)
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg, err := group.SendMessage("message %d", i)
+			if assert.NoError(t, err) && assert.NotNil(t, msg) {
+				ids <- msg.ID
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		assert.False(t, seen[id], "Each send should take its own echo, %s is taken twice", id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, total)
+}
+
+func TestIsClimitedFor(t *testing.T) {
+	frame := `climited:1485666967794:bm:e8n2:0:<n000/><f x9000="1">n`
+
+	assert.True(t, isClimitedFor(frame, []string{"bm", "e8n2", "0", "text", "\r\n"}), "The nonce should match")
+	assert.False(t, isClimitedFor(frame, []string{"bm", "abcd", "0", "text", "\r\n"}), "Another nonce should not match")
+	assert.False(t, isClimitedFor(frame, []string{"getratelimit", "\r\n"}), "Another command should not match")
+}
+
+func TestGroup_OrderedEvents(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+
+	app := newTestApp(&Config{OrderedEvents: true})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		// The first handler is slow, the subsequent events must wait for it.
+		if event.Message.Text == "first" {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, event.Message.Text)
+	}, nil, OnMessage))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := &Group{
+		App:      app,
+		Name:     "testgroup",
+		ws:       &WebSocket{Events: make(chan string)},
+		events:   make(chan string, EVENT_BUFFER_SIZE),
+		takeOver: make(chan context.Context),
+		context:  ctx,
+	}
+	group.initFields()
+	go group.listen()
+
+	for i, text := range []string{"first", "second", "third"} {
+		id := fmt.Sprintf("m%d", i)
+		group.ws.Events <- fmt.Sprintf("u:%s:id%d", id, i)
+		group.ws.Events <- fmt.Sprintf("b:1717866894:someuser::12345678:%s:%s:userIP:0::<n000/>%s", id, id, text)
+	}
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 3
+	}, time.Second, 10*time.Millisecond, "All messages should be dispatched")
+	assert.Equal(t, []string{"first", "second", "third"}, got, "Messages should be dispatched in the received order")
+}
+
+func TestGroup_SendLines(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := &Group{
+		App:      New(&Config{}),
+		Name:     "testgroup",
+		ws:       &WebSocket{Events: make(chan string)},
+		events:   make(chan string, EVENT_BUFFER_SIZE),
+		takeOver: make(chan context.Context),
+		context:  ctx,
+	}
+	group.initFields()
+	go group.listen()
+
+	lines := []string{"first", "second", "third"}
+	msgs, errs := group.SendLines(lines)
+
+	assert.Len(t, msgs, len(lines), "Messages should be aligned with the lines")
+	assert.Len(t, errs, len(lines), "Errors should be aligned with the lines")
+	for i := range lines {
+		// The connection is not established, every line should fail without stopping the rest.
+		assert.Nil(t, msgs[i])
+		assert.ErrorIs(t, errs[i], ErrNotConnected)
+	}
+}
+
+func TestGroup_SetRateLimitValidation(t *testing.T) {
+	group := &Group{}
+
+	for _, interval := range []time.Duration{-time.Second, MAX_RATE_LIMIT + time.Second} {
+		rate, err := group.SetRateLimit(interval)
+		assert.ErrorIs(t, err, ErrInvalidRateLimit, "SetRateLimit(%s) should be rejected", interval)
+		assert.Zero(t, rate)
+	}
+}
+
+func TestGroup_CanEditModerators(t *testing.T) {
+	tests := []struct {
+		name  string
+		owner string
+		mods  map[string]int64
+		want  bool
+	}{
+		{"Owner", "nekonyan", map[string]int64{}, true},
+		{"EditMods", "someone", map[string]int64{"nekonyan": 2}, true},
+		{"NoEditMods", "someone", map[string]int64{"nekonyan": 1024}, false},
+		{"NotAModerator", "someone", map[string]int64{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &Group{LoginName: "Nekonyan", Owner: tt.owner}
+			group.Moderators = NewSyncMap[string, int64]()
+			for username, access := range tt.mods {
+				group.Moderators.Set(username, access)
+			}
+			assert.Equal(t, tt.want, group.canEditModerators())
+		})
+	}
+}
+
+func TestGroup_RemoveAllModeratorsNoPermission(t *testing.T) {
+	group := &Group{LoginName: "Nekonyan", Owner: "someone"}
+	group.Moderators = NewSyncMap[string, int64]()
+	group.Moderators.Set("othermod", 2)
+
+	removed, err := group.RemoveAllModerators(false)
+	assert.ErrorIs(t, err, ErrNoPermission)
+	assert.Empty(t, removed)
+}
+
+func TestGroup_ImportModerators(t *testing.T) {
+	var mu sync.Mutex
+	var commands []string
+	mods := map[string]int64{"a": 1, "b": 2, "d": 2}
+
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch head {
+		case "addmod":
+			// addmod:username:access
+			username, access, _ := strings.Cut(data, ":")
+			mods[username], _ = strconv.ParseInt(access, 10, 64)
+		case "removemod":
+			delete(mods, data)
+		default:
+			return nil
+		}
+		commands = append(commands, head+":"+data)
+
+		var entries []string
+		for username, access := range mods {
+			entries = append(entries, fmt.Sprintf("%s,%d", username, access))
+		}
+		return []string{"mods:" + strings.Join(entries, ":")}
+	})
+	group.Owner = "Nekonyan"
+	group.Moderators.Set("a", 1)
+	group.Moderators.Set("b", 2)
+	group.Moderators.Set("d", 2)
+
+	exported := group.ExportModerators()
+	assert.Equal(t, map[string]int64{"a": 1, "b": 2, "d": 2}, exported)
+	exported["a"] = 9
+	assert.Equal(t, int64(1), group.Moderators.M["a"], "The export should be a snapshot")
+
+	applied, err := group.ImportModerators(map[string]int64{"A": 1, "b": 5, "c": 3, "Nekonyan": 0})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, applied, "Matching entries and the owner should be skipped")
+	assert.Equal(t, []string{"addmod:b:5", "addmod:c:3", "removemod:d"}, commands)
+
+	assert.Eventually(t, func() bool {
+		return len(group.ExportModerators()) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]int64{"a": 1, "b": 5, "c": 3}, group.ExportModerators())
+
+	applied, err = group.ImportModerators(map[string]int64{"a": 1, "b": 5, "c": 3})
+	assert.NoError(t, err)
+	assert.Zero(t, applied, "An already converged import should apply nothing")
+}
+
+func TestGroup_ImportModeratorsNoPermission(t *testing.T) {
+	group := &Group{LoginName: "Nekonyan", Owner: "someone"}
+	group.Moderators = NewSyncMap[string, int64]()
+
+	applied, err := group.ImportModerators(map[string]int64{"othermod": 2})
+	assert.ErrorIs(t, err, ErrNoPermission)
+	assert.Zero(t, applied)
+}
+
+func TestGroup_Credentials(t *testing.T) {
+	group := &Group{App: newTestApp(&Config{Username: "Nekonyan", Password: "secret"})}
+	username, password := group.credentials()
+	assert.Equal(t, "Nekonyan", username, "The configured credentials should be used by default")
+	assert.Equal(t, "secret", password)
+
+	group.username, group.password = "Otherbot", "secret2"
+	username, password = group.credentials()
+	assert.Equal(t, "Otherbot", username, "The group credentials should override the configured ones")
+	assert.Equal(t, "secret2", password)
+}
+
+func TestGroup_DrainAndDisconnectTimeout(t *testing.T) {
+	received := make(chan struct{})
+
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head == "slow" {
+			close(received)
+		}
+		return nil
+	}, asConnected)
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- group.SyncSend(func(frame string) bool { return true }, "slow", "\r\n")
+	}()
+	<-received
+
+	start := time.Now()
+	group.DrainAndDisconnect(50 * time.Millisecond)
+	assert.Less(t, time.Since(start), SYNC_SEND_TIMEOUT, "The drain should give up after the timeout")
+	assert.Error(t, <-sent, "The remaining send should be canceled")
+	assert.False(t, group.Connected)
+
+	assert.ErrorIs(t, group.SyncSend(func(frame string) bool { return false }, "slow", "\r\n"), ErrNotConnected, "New sends should be refused after the drain")
+}
+
+func TestGroup_Ping(t *testing.T) {
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head != "" {
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+		return []string{"n:5", "\r\n"}
+	})
+
+	latency, err := group.Ping()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, latency, 20*time.Millisecond, "The latency should include the server delay")
+	assert.Equal(t, latency, group.Latency(), "The latency should be stored on the group")
+
+	assert.Eventually(t, func() bool {
+		group.countMu.Lock()
+		defer group.countMu.Unlock()
+		return group.ParticipantCount == 5
+	}, time.Second, 10*time.Millisecond, "Other frames should be sent back to the listener")
+}
+
+func TestGroup_GetParticipantsSnapshot(t *testing.T) {
+	var stops atomic.Int32
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head != "gparticipants" {
+			return nil
+		}
+		if data == "stop" {
+			stops.Add(1)
+			return nil
+		}
+		return []string{"gparticipants:1:pid1:1717866894.12:48875733:Nekonyan:None:;pid2:1717866895.5:12345678:None:Tempname:;pid3:1717866896:23456789:None:None:"}
+	})
+	old := &models.Participant{ParticipantID: "old", User: &models.User{Name: "olduser"}}
+	group.Participants.Set("old", old)
+
+	participants, err := group.GetParticipantsSnapshot()
+	assert.NoError(t, err)
+	if assert.Len(t, participants, 3) {
+		assert.Equal(t, "Nekonyan", participants[0].User.Name)
+		assert.True(t, participants[0].User.IsSelf)
+		assert.Equal(t, "Tempname", participants[1].User.Name)
+		assert.True(t, participants[1].User.IsAnon)
+		assert.Equal(t, utils.GetAnonName(1717866896, 23456789), participants[2].User.Name, "The anon name should be resolved")
+	}
+	assert.Equal(t, 1, group.Participants.Len(), "The participants should be left untouched")
+	assert.Eventually(t, func() bool { return stops.Load() == 1 }, time.Second, 10*time.Millisecond, "The feeds should be stopped")
+
+	_, err = group.GetParticipantsStart()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, group.Participants.Len())
+
+	participants, err = group.GetParticipantsSnapshot()
+	assert.NoError(t, err)
+	assert.Len(t, participants, 3)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), stops.Load(), "The started feeds should be kept running")
+}
+
+func TestGroup_WaitUntilConnected(t *testing.T) {
+	assert.ErrorIs(t, (&Group{}).WaitUntilConnected(context.Background()), ErrNotConnected, "An unconnected group should not be waited")
+
+	groupCtx, cancelGroup := context.WithCancel(context.Background())
+	defer cancelGroup()
+
+	group := &Group{App: newTestApp(&Config{}), joined: make(chan struct{}), context: groupCtx}
+	group.initFields()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, group.WaitUntilConnected(ctx), ErrTimeout, "WaitUntilConnected should time out before the handshake")
+
+	done := make(chan error, 1)
+	go func() { done <- group.WaitUntilConnected(context.Background()) }()
+
+	group.wsOnFrame("ok:nekonyan:48875733ABCDEF:M:Nekonyan:1717866894.123:127.0.0.1::0")
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("WaitUntilConnected was not released by the ok frame")
+	}
+
+	assert.NoError(t, group.WaitUntilConnected(ctx), "An already connected group should return immediately")
+
+	cancelGroup()
+	group = &Group{joined: make(chan struct{}), context: groupCtx}
+	assert.ErrorIs(t, group.WaitUntilConnected(context.Background()), ErrConnectionClosed, "WaitUntilConnected should return when the group is disconnected")
+}
+
+func TestGroup_WaitReady(t *testing.T) {
+	assert.ErrorIs(t, (&Group{}).WaitReady(context.Background()), ErrNotConnected, "An unconnected group should not be waited")
+
+	groupCtx, cancelGroup := context.WithCancel(context.Background())
+	defer cancelGroup()
+
+	group := &Group{ready: make(chan struct{}), context: groupCtx}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, group.WaitReady(ctx), ErrTimeout, "WaitReady should time out before the group is ready")
+
+	group.markReady()
+	group.markReady() // Marking twice should not panic.
+	assert.NoError(t, group.WaitReady(context.Background()))
+
+	cancelGroup()
+	group = &Group{ready: make(chan struct{}), context: groupCtx}
+	assert.ErrorIs(t, group.WaitReady(context.Background()), ErrConnectionClosed, "WaitReady should return when the group is disconnected")
+}
+
+func TestGroup_RestrictionRemaining(t *testing.T) {
+	group := &Group{}
+	assert.Zero(t, group.RateLimitRemaining(), "An unrestricted group should have no remaining rate limit")
+	assert.Zero(t, group.RestrictionRemaining(), "An unrestricted group should have no remaining restriction")
+
+	group.RateLimited = time.Now().Add(time.Minute)
+	group.Restrict = time.Now().Add(-time.Minute)
+	assert.InDelta(t, time.Minute, group.RateLimitRemaining(), float64(time.Second))
+	assert.Zero(t, group.RestrictionRemaining(), "An expired restriction should have no remaining time")
+}
+
+func TestGroup_RestrictionReason(t *testing.T) {
+	group := &Group{}
+	reason, until := group.RestrictionReason()
+	assert.Empty(t, reason, "An unrestricted group should have no reason")
+	assert.True(t, until.IsZero())
+
+	group.Restrict = time.Now().Add(time.Minute)
+	reason, until = group.RestrictionReason()
+	assert.Equal(t, RestrictionFloodBan, reason)
+	assert.Equal(t, group.Restrict, until)
+
+	group.RateLimited = time.Now().Add(time.Hour)
+	reason, until = group.RestrictionReason()
+	assert.Equal(t, RestrictionRateLimit, reason, "The later expiry should be reported")
+	assert.Equal(t, group.RateLimited, until)
+
+	group.Restrict = time.Now().Add(-time.Minute)
+	group.RateLimited = time.Now().Add(-time.Minute)
+	reason, _ = group.RestrictionReason()
+	assert.Empty(t, reason, "An expired restriction should have no reason")
+}
+
+func TestGroup_ProxyBannedFrame(t *testing.T) {
+	var got *Event
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { got = event }, nil, OnProxyBanned))
+
+	group := &Group{App: app, Name: "testgroup"}
+	group.wsOnFrame("proxybanned:1.2.3.4")
+
+	assert.True(t, group.ProxyBanned)
+	if assert.NotNil(t, got, "The proxybanned frame should dispatch an event") {
+		assert.Equal(t, OnProxyBanned, got.Type)
+		assert.Equal(t, "1.2.3.4", got.Info)
+	}
+}
+
+func TestGroup_ProxyBannedSend(t *testing.T) {
+	infos := make(chan string, 1)
+	errs := make(chan error, 1)
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		infos <- event.Info
+		// The handlers may send themselves, so the event is dispatched after the send returns.
+		_, _, _, err := event.Group.GetAnnouncement()
+		errs <- err
+	}, nil, OnProxyBanned))
+
+	group := newServedGroup(t, app, func(head, data string) []string {
+		switch head {
+		case "bm":
+			return []string{"proxybanned:1.2.3.4"}
+		case "getannouncement":
+			return []string{"getannc:0:testgroup:0:0:"}
+		}
+		return nil
+	})
+
+	_, err := group.SendMessage("hello")
+	assert.ErrorIs(t, err, ErrProxyBanned)
+	assert.True(t, group.ProxyBanned)
+	assert.Equal(t, "1.2.3.4", <-infos)
+	select {
+	case err = <-errs:
+		assert.NoError(t, err, "The handler should be able to send")
+	case <-time.After(time.Second):
+		t.Fatal("The handler send did not complete")
+	}
+}
+
+func TestGroup_VerificationRequiredFrame(t *testing.T) {
+	var got *Event
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { got = event }, nil, OnVerificationRequired))
+
+	group := &Group{App: app, Name: "testgroup"}
+	group.wsOnFrame("verificationrequired")
+
+	if assert.NotNil(t, got, "The verificationrequired frame should dispatch an event") {
+		assert.Equal(t, OnVerificationRequired, got.Type)
+		assert.Equal(t, group, got.Group)
+		assert.Equal(t, "OnVerificationRequired", got.Type.String())
+	}
+}
+
+func TestGroup_ParticipantListUpdate(t *testing.T) {
+	var got []*Event
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { got = append(got, event) }, nil, OnParticipantCountChange|OnParticipantListUpdate))
+
+	group := &Group{App: app, Name: "testgroup", UserCount: 3, AnonCount: 2}
+	group.wsOnFrame("n:a")
+
+	assert.Equal(t, int64(10), group.ParticipantCount)
+	if assert.Len(t, got, 1, "The list update should not be dispatched without a breakdown") {
+		assert.Equal(t, OnParticipantCountChange, got[0].Type)
+	}
+
+	got = nil
+	group.wsOnFrame("n:1c:b:11")
+
+	assert.Equal(t, int64(28), group.ParticipantCount)
+	assert.Equal(t, 11, group.UserCount, "The breakdown should be parsed as hexadecimal")
+	assert.Equal(t, 17, group.AnonCount)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, OnParticipantCountChange, got[0].Type)
+		assert.Equal(t, OnParticipantListUpdate, got[1].Type)
+		assert.Equal(t, 11, got[1].UserCount)
+		assert.Equal(t, 17, got[1].AnonCount)
+	}
+
+	got = nil
+	group.wsOnFrame("n:1c:b:11")
+	assert.Len(t, got, 1, "The list update should not be dispatched for an unchanged breakdown")
+}
+
+func TestGroup_PersistMessages(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.gob")
+
+	group := &Group{Name: "testgroup"}
+	group.initFields()
+	for i := 0; i < MAX_PERSISTED_MESSAGES+10; i++ {
+		id := fmt.Sprintf("id%d", i)
+		group.Messages.Set(id, &Message{Group: group, Message: models.Message{ID: id, Text: fmt.Sprintf("text%d", i), User: &models.User{Name: "someuser"}}})
+	}
+
+	persistence := &GobPersistence{Filename: filename}
+	persistence.Initialize() // The file does not exist yet.
+	group.saveMessages(persistence)
+	assert.NoError(t, persistence.Save())
+
+	restored := &GobPersistence{Filename: filename}
+	assert.NoError(t, restored.Initialize())
+
+	group = &Group{Name: "testgroup"}
+	group.initFields()
+	group.Messages.Set("id99", &Message{Group: group, Message: models.Message{ID: "id99", Text: "latest"}})
+	group.restoreMessages(restored)
+
+	keys := group.Messages.Keys()
+	assert.Len(t, keys, MAX_PERSISTED_MESSAGES+1, "The persisted messages should be bounded")
+	assert.Equal(t, "id10", keys[0], "The oldest persisted message should be the latest MAX_PERSISTED_MESSAGES")
+	assert.Equal(t, "id99", keys[len(keys)-1], "The existing messages should stay after the restored ones")
+
+	msg, ok := group.Messages.Get("id10")
+	if assert.True(t, ok) {
+		assert.Equal(t, "text10", msg.Text)
+		assert.Equal(t, "someuser", msg.User.Name)
+		assert.Same(t, group, msg.Group)
+	}
+}
+
+func TestGroup_RestoreMessagesAfterHistory(t *testing.T) {
+	var handled atomic.Int32
+	app := newTestApp(&Config{PersistMessages: true})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		if n, _ := strconv.Atoi(event.Message.ID[1:]); n > 30 {
+			// Hold the history sent on login, so it is still being handled when the "inited" frame arrives.
+			time.Sleep(50 * time.Millisecond)
+		}
+		handled.Add(1)
+	}, nil, OnMessageHistory))
+	app.persistence.GetChatData("testgroup").Set(PERSISTED_MESSAGES_KEY, []models.Message{
+		{ID: "p1", Text: "older"},
+		{ID: "p2", Text: "old"},
+	})
+
+	// The first half of the history is sent along with the "inited" frame, the rest is paged.
+	const total = 60
+	history := historyFrames(total)
+	reply := handshakeReply(history, total/2)
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head == "history" {
+			return append(append([]string{}, history[:total/2]...), "inited")
+		}
+		return reply(head, data)
+	}, func(group *Group) {
+		group.ready = make(chan struct{})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, group.Send("history", "\r\n"))
+	assert.NoError(t, group.WaitReady(ctx))
+
+	keys := group.Messages.Keys()
+	if assert.Len(t, keys, total+2, "The whole history should be stored before the group is ready") {
+		assert.Equal(t, []string{"p1", "p2", "m1"}, keys[:3], "The restored messages should stay before the history")
+	}
+	assert.Equal(t, int32(total), handled.Load(), "The whole history should be handled before the group is ready")
+}
+
+func TestSendTimings(t *testing.T) {
+	var timings sendTimings
+
+	sent, last, avg := timings.stats()
+	assert.Zero(t, sent)
+	assert.True(t, last.IsZero())
+	assert.Zero(t, avg)
+
+	start := time.Unix(1700000000, 0)
+	timings.record(start)
+
+	sent, last, avg = timings.stats()
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, start, last)
+	assert.Zero(t, avg, "A single send has no interval")
+
+	// Overflow the buffer with sends every 2 seconds.
+	for i := 1; i < SEND_TIMINGS_SIZE*2; i++ {
+		timings.record(start.Add(time.Duration(i) * 2 * time.Second))
+	}
+
+	sent, last, avg = timings.stats()
+	assert.Equal(t, SEND_TIMINGS_SIZE*2, sent)
+	assert.Equal(t, start.Add(time.Duration(SEND_TIMINGS_SIZE*2-1)*2*time.Second), last)
+	assert.Equal(t, 2*time.Second, avg)
+
+	// A burst should lower the average of the buffered sends.
+	for i := 0; i < SEND_TIMINGS_SIZE; i++ {
+		timings.record(last.Add(time.Duration(i+1) * 500 * time.Millisecond))
+	}
+
+	_, _, avg = timings.stats()
+	assert.Equal(t, 500*time.Millisecond, avg)
+}
+
+func TestGroup_DisconnectConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cancels atomic.Int32
+	group := &Group{
+		Name:      "testgroup",
+		ws:        &WebSocket{},
+		Connected: true,
+		context:   ctx,
+		cancelCtx: func() {
+			cancels.Add(1)
+			cancel()
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NotPanics(t, group.Disconnect)
+		}()
+	}
+	wg.Wait()
+
+	assert.False(t, group.Connected)
+	assert.Equal(t, int32(1), cancels.Load(), "The teardown should run only once")
+
+	// Further calls should be no-op.
+	assert.NotPanics(t, group.Disconnect)
+	assert.Equal(t, int32(1), cancels.Load())
+}
+
+func TestCloseOnce(t *testing.T) {
+	ch := make(chan string)
+	closeCh := closeOnce(ch)
+
+	assert.NotPanics(t, closeCh)
+	assert.NotPanics(t, closeCh, "Closing twice should not panic")
+
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestCollectBanList(t *testing.T) {
+	now := time.Unix(1717866894, 0)
+
+	// Build two full pages and a partial one, sharing an entry on each boundary.
+	var all []models.Blocked
+	for i := 0; i < BAN_LIST_PAGE_SIZE*2+10; i++ {
+		all = append(all, models.Blocked{
+			ModerationID: fmt.Sprintf("mod%d", i),
+			Time:         now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	var offsets []time.Time
+	fetch := func(offset time.Time, amount int) ([]models.Blocked, error) {
+		offsets = append(offsets, offset)
+		start := 0
+		if !offset.IsZero() {
+			for i, blocked := range all {
+				if blocked.Time.Equal(offset) {
+					start = i
+					break
+				}
+			}
+		}
+		end := utils.Min(start+amount, len(all))
+		return all[start:end], nil
+	}
+
+	banList, err := collectBanList(fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, all, banList, "Every entry should be collected once")
+	assert.Len(t, offsets, 3)
+	assert.True(t, offsets[0].IsZero(), "The first page should start from the newest")
+
+	_, err = collectBanList(func(time.Time, int) ([]models.Blocked, error) {
+		return nil, ErrTimeout
+	})
+	assert.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestGroup_GetBanListAll(t *testing.T) {
+	const total = 25
+	now := time.Now().Unix()
+
+	var pages atomic.Int32
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head != "blocklist" {
+			return nil
+		}
+		pages.Add(1)
+		// blocklist:kind:offset:next:amount:anons:1
+		fields := strings.Split(data, ":")
+		offset, _ := strconv.ParseInt(fields[1], 10, 64)
+		amount, _ := strconv.Atoi(fields[3])
+
+		// One entry per second from the newest, the offset entry is repeated on the next page.
+		start := 0
+		if offset > 0 {
+			start = int(now - offset)
+		}
+		var entries []string
+		for i := start; i < total && len(entries) < amount; i++ {
+			entries = append(entries, fmt.Sprintf("mod%d:1.2.3.%d:user%d:%d:Nekonyan", i, i, i, now-int64(i)))
+		}
+		return []string{fields[0] + "list:" + strings.Join(entries, ";")}
+	})
+
+	banList, err := group.GetBanListAll(context.Background(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, banList, total, "Every entry should be collected once") {
+		assert.Equal(t, "mod0", banList[0].ModerationID)
+		assert.Equal(t, "mod24", banList[total-1].ModerationID)
+	}
+	assert.Equal(t, int32(3), pages.Load(), "The paging should stop at the short page")
+
+	unbanList, err := group.GetUnbanListAll(context.Background(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, unbanList, total) {
+		assert.Equal(t, "user1", unbanList[1].Target)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = group.GetBanListAll(ctx, 10)
+	assert.ErrorIs(t, err, context.Canceled, "A done context should stop the paging")
+}
+
+func TestGroup_GetAnnouncement(t *testing.T) {
+	const raw = `<nA149A0/><f xf9f="">Welcome&nbsp;to&nbsp;the&nbsp;group: enjoy`
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head != "getannouncement" {
+			return nil
+		}
+		return []string{"getannc:3:testgroup:0:60:" + raw}
+	})
+
+	annc, enabled, interval, err := group.GetAnnouncement()
+	assert.NoError(t, err)
+	assert.Equal(t, "Welcome to the group: enjoy", annc, "The announcement should be human-readable")
+	assert.True(t, enabled)
+	assert.Equal(t, time.Minute, interval)
+
+	annc, _, _, err = group.GetRawAnnouncement()
+	assert.NoError(t, err)
+	assert.Equal(t, raw, annc, "The original announcement should be kept")
+}
+
+func TestGroup_ResumeHistory(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string]EventType{}
+	dispatched := 0
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[event.Message.ID] = event.Type
+		dispatched++
+	}, nil, OnMessage|OnMessageHistory))
+
+	history := func(id string, ts int) string {
+		return fmt.Sprintf("i:%d:someuser::12345678:%s:%s:userIP:0::<n000/>text", ts, id, id)
+	}
+
+	// The history frames are sent back on demand, so the listener handles them concurrently, in any order.
+	var frames atomic.Pointer[[]string]
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "history" {
+			return nil
+		}
+		return *frames.Load()
+	})
+
+	load := func(want map[string]EventType, history ...string) {
+		mu.Lock()
+		got = map[string]EventType{}
+		dispatched = 0
+		mu.Unlock()
+
+		frames.Store(&history)
+		assert.NoError(t, group.Send("history", "\r\n"))
+
+		assert.Eventually(t, func() bool { return group.Messages.Len() == len(history) }, time.Second, time.Millisecond, "Every message should be stored")
+		// Give the skipped messages a chance to be dispatched wrongly.
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, want, got)
+		assert.Equal(t, len(want), dispatched, "No message should be dispatched twice")
+	}
+
+	// The first connection dispatches the whole history.
+	load(map[string]EventType{"m1": OnMessageHistory, "m2": OnMessageHistory, "m2b": OnMessageHistory},
+		history("m2", 1717866902), history("m2b", 1717866902), history("m1", 1717866901))
+
+	// Simulate a reconnect, as done in [Group.connect].
+	group.markResumePoint()
+	group.Messages.Clear()
+
+	// The latest processed message is deleted meanwhile, and m3 is sent within the same second.
+	latest, _ := group.lastSeen.get()
+	kept := map[string]string{"m2": "m2b", "m2b": "m2"}[latest]
+	load(map[string]EventType{"m3": OnMessage, "m4": OnMessage},
+		history("m4", 1717866904), history("m3", 1717866902), history(kept, 1717866902), history("m1", 1717866901))
+
+	id, _ := group.lastSeen.get()
+	assert.Equal(t, "m4", id)
+}
+
+func TestGroup_ConnectTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		// Accept the socket but never send the version frame.
+		<-done
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	app := newTestApp(&Config{ConnectTimeout: 100 * time.Millisecond})
+	group := &Group{
+		App:   app,
+		Name:  "testgroup",
+		WsUrl: "ws" + strings.TrimPrefix(server.URL, "http"),
+	}
+
+	start := time.Now()
+	err := group.Connect(context.Background())
+
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Less(t, time.Since(start), 5*time.Second, "The connect sequence should be bounded")
+	assert.False(t, group.Connected)
+	assert.False(t, group.ws.IsConnected(), "The WebSocket should be cleaned up")
+}
+
+func TestGroup_ConnectionState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	var got []string
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		got = append(got, event.OldState.String()+">"+event.NewState.String())
+	}, nil, OnConnectionStateChange))
+
+	group := &Group{
+		App:         app,
+		Name:        "testgroup",
+		WsUrl:       "ws" + strings.TrimPrefix(server.URL, "http"),
+		BackoffBase: 10 * time.Millisecond,
+		MaxRetries:  1,
+	}
+	assert.Equal(t, StateDisconnected, group.State())
+
+	assert.Error(t, group.Connect(context.Background()))
+	assert.Equal(t, []string{"Disconnected>Connecting", "Connecting>Disconnected"}, got)
+
+	got = nil
+	assert.ErrorIs(t, group.Reconnect(), ErrRetryEnds)
+	assert.Equal(t, []string{"Disconnected>Reconnecting", "Reconnecting>Disconnected"}, got)
+
+	got = nil
+	group.setState(StateConnected)
+	group.Connected = true
+	group.Disconnect()
+	assert.Equal(t, []string{"Disconnected>Connected", "Connected>Disconnected"}, got)
+	assert.Equal(t, StateDisconnected, group.State())
+}
+
+// newReplyServer starts a WebSocket test server and returns its URL.
+//
+// For each received command, the server sends back the frames returned by the reply function.
+func newReplyServer(t *testing.T, reply func(head, data string) []string) string {
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		var frame string
+		for websocket.Message.Receive(conn, &frame) == nil {
+			head, data, _ := strings.Cut(strings.TrimRight(frame, "\r\n\x00"), ":")
+			for _, frame := range reply(head, data) {
+				websocket.Message.Send(conn, frame)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// handshakeReply returns a reply function answering the connect handshake of a group and its history, see [newReplyServer].
+//
+// The history frames are ordered from newer to older.
+// The first initial frames are sent on login, the rest is paged by the "get_more" command.
+func handshakeReply(history []string, initial int) func(head, data string) []string {
+	return func(head, data string) []string {
+		switch head {
+		case "v":
+			return []string{"v:15:15"}
+		case "bauth":
+			frames := []string{"ok:nekonyan:48875733ABCDEF:M:Nekonyan:1717866894.123:127.0.0.1::0"}
+			frames = append(frames, history[:initial]...)
+			return append(frames, "inited")
+		case "get_more":
+			// get_more:amount:offset, the offset counts the pages of the given amount.
+			amount, offset, _ := strings.Cut(data, ":")
+			size, _ := strconv.Atoi(amount)
+			page, _ := strconv.Atoi(offset)
+			rest := history[initial:]
+			start, end := utils.Min(page*size, len(rest)), utils.Min((page+1)*size, len(rest))
+			frames := append([]string{}, rest[start:end]...)
+			if end == len(rest) {
+				frames = append(frames, "nomore")
+			}
+			return append(frames, "gotmore:"+offset)
+		}
+		return nil
+	}
+}
+
+// historyFrames returns the "i" frames of the messages m<total> to m1, from newer to older, one second apart.
+func historyFrames(total int) []string {
+	frames := make([]string, total)
+	for i := range frames {
+		n := total - i
+		frames[i] = fmt.Sprintf("i:%d:someuser::12345678:m%d:m%d:userIP:0::<n000/>text", 1717866900+n, n, n)
+	}
+
+	return frames
+}
+
+// newServedGroup returns a logged in group connected to a test server.
+//
+// For each received command, the server sends back the frames returned by the reply function.
+// The options are applied before the listener starts, so they can set up the group without racing it.
+func newServedGroup(t *testing.T, app *Application, reply func(head, data string) []string, opts ...func(*Group)) *Group {
+	wsURL := newReplyServer(t, reply)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	group := &Group{
+		App:       app,
+		Name:      "testgroup",
+		LoggedIn:  true,
+		LoginName: "Nekonyan",
+		UserID:    48875733,
+		ws:        &WebSocket{},
+		events:    make(chan string, EVENT_BUFFER_SIZE),
+		takeOver:  make(chan context.Context),
+		context:   ctx,
+	}
+	group.initFields()
+	for _, opt := range opts {
+		opt(group)
+	}
+
+	if !assert.NoError(t, group.ws.Connect(wsURL)) {
+		t.FailNow()
+	}
+	group.ws.Sustain(ctx)
+	go group.listen()
+
+	return group
+}
+
+// asConnected marks the served group as connected with its own cancelable context, so it can be disconnected.
+func asConnected(group *Group) {
+	group.Connected = true
+	group.closeEvents = closeOnce(group.events)
+	group.context, group.cancelCtx = context.WithCancel(group.context)
+}
+
+func TestGroup_EditMessage(t *testing.T) {
+	app := newTestApp(&Config{})
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "updmsg" {
+			return nil
+		}
+		// updmsg:msgID:text
+		id, text, _ := strings.Cut(data, ":")
+		if strings.Contains(text, "too long") {
+			return []string{"msglexceeded:850"}
+		}
+		return []string{"updmsg:" + id + ":newID"}
+	})
+
+	mine := ParseGroupMessage("1717866894:Nekonyan::48875733:modID:msgID:userIP:0::<n000/>hello", group)
+	group.Messages.Set(mine.ID, mine)
+	later := ParseGroupMessage("1717866895:someuser::12345678:modID:laterID:userIP:0::<n000/>later", group)
+	group.Messages.Set(later.ID, later)
+
+	edited, err := group.EditMessage(mine, "hello %s", "world")
+	if assert.NoError(t, err) && assert.NotNil(t, edited) {
+		assert.Equal(t, "newID", edited.ID)
+		assert.Equal(t, "hello world", edited.Text)
+		assert.True(t, edited.Edited)
+		assert.False(t, edited.EditedTime.IsZero())
+		assert.Equal(t, "hello", edited.PreviousText)
+		_, ok := group.Messages.Get("msgID")
+		assert.False(t, ok, "The old ID should be replaced")
+		_, ok = group.Messages.Get("newID")
+		assert.True(t, ok)
+		assert.Equal(t, []string{"newID", "laterID"}, group.Messages.Keys(), "The edited message should keep its position")
+	}
+
+	_, err = group.EditMessage(edited, "too long")
+	assert.ErrorIs(t, err, ErrMessageLength)
+	assert.Equal(t, 850, group.MaxMessageLength)
+
+	theirs := ParseGroupMessage("1717866894:someuser::12345678:modID:otherID:userIP:0::<n000/>hello", group)
+	_, err = group.EditMessage(theirs, "hijacked")
+	assert.ErrorIs(t, err, ErrNotOwned)
+}
+
+func TestGroup_ModeratorsRemoved(t *testing.T) {
+	var mu sync.Mutex
+	var removed []string
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		removed = append(removed, event.User.Name)
+	}, nil, OnModeratorRemoved))
+
+	group := &Group{App: app, Name: "testgroup", LoginName: "nekonyan"}
+	group.initFields()
+	group.Moderators.Set("mod1", 1)
+	group.Moderators.Set("mod2", 2)
+	group.Moderators.Set("mod3", 3)
+
+	group.wsOnFrame("mods:mod2,2")
+
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"mod1", "mod3"}, removed, "Every removed moderator should be reported")
+	mu.Unlock()
+	assert.Equal(t, []string{"mod2"}, group.Moderators.Keys())
+}
+
+func TestGroup_Counts(t *testing.T) {
+	app := newTestApp(&Config{})
+	group := &Group{App: app, Name: "testgroup"}
+	group.initFields()
+
+	group.wsOnFrame("n:1f")
+	group.wsOnFrame("participant:1:p1:11111111:someuser:None:None:1717866894")
+	group.wsOnFrame("participant:1:p2:22222222:None:anonuser:None:1717866894")
+	group.wsOnFrame("participant:1:p3:33333333:None:None:None:1717866894")
+
+	registered, anon, total := group.Counts()
+	assert.Equal(t, 1, registered)
+	assert.Equal(t, 2, anon)
+	assert.Equal(t, int64(31), total)
+
+	// A leave frame of an unknown participant should not desync the counts.
+	group.wsOnFrame("participant:0:p9:99999999:otheruser:None:None:1717866894")
+	group.wsOnFrame("participant:0:p2:22222222:None:anonuser:None:1717866894")
+
+	registered, anon, _ = group.Counts()
+	assert.Equal(t, 1, registered)
+	assert.Equal(t, 1, anon)
+}
+
+func TestGroup_ParticipantCountersByType(t *testing.T) {
+	app := newTestApp(&Config{})
+	group := &Group{App: app, Name: "testgroup"}
+	group.initFields()
+
+	group.wsOnFrame("participant:1:p1:11111111:someuser:None:None:1717866894")
+	group.wsOnFrame("participant:1:p2:22222222:None:anonuser:None:1717866894")
+	assert.Equal(t, 1, group.UserCount)
+	assert.Equal(t, 1, group.AnonCount)
+
+	// A registered participant changing again stays registered.
+	group.wsOnFrame("participant:2:p1:11111111:someuser:None:None:1717866894")
+	assert.Equal(t, 1, group.UserCount)
+	assert.Equal(t, 1, group.AnonCount, "The anonymous count should not move for a registered participant")
+
+	// The anonymous participant logs in.
+	group.wsOnFrame("participant:2:p2:22222222:otheruser:None:None:1717866894")
+	assert.Equal(t, 2, group.UserCount)
+	assert.Equal(t, 0, group.AnonCount)
+
+	// The leave frame is counted by the stored participant.
+	group.wsOnFrame("participant:0:p2:22222222:None:anonuser:None:1717866894")
+	assert.Equal(t, 1, group.UserCount)
+	assert.Equal(t, 0, group.AnonCount)
+	_, ok := group.Participants.Get("p2")
+	assert.False(t, ok)
+}
+
+func TestGroup_ParticipantCountsClamp(t *testing.T) {
+	app := newTestApp(&Config{})
+	group := &Group{App: app, Name: "testgroup"}
+	group.initFields()
+
+	// Leave frames without the matching join frames, e.g. missed before the feed started.
+	group.wsOnFrame("participant:0:p1:11111111:someuser:None:None:1717866894")
+	group.wsOnFrame("participant:0:p2:22222222:None:anonuser:None:1717866894")
+	group.wsOnFrame("participant:2:p3:33333333:otheruser:None:None:1717866894")
+	assert.GreaterOrEqual(t, group.UserCount, 0)
+	assert.GreaterOrEqual(t, group.AnonCount, 0)
+
+	group.wsOnFrame("participant:1:p4:44444444:None:anonuser:None:1717866894")
+	group.wsOnFrame("participant:0:p4:44444444:None:anonuser:None:1717866894")
+	group.wsOnFrame("participant:0:p4:44444444:None:anonuser:None:1717866894")
+	assert.GreaterOrEqual(t, group.UserCount, 0)
+	assert.GreaterOrEqual(t, group.AnonCount, 0)
+
+	group.UserCount, group.AnonCount = 7, 5
+	group.recount()
+	assert.Equal(t, 7, group.UserCount, "The counts should not be reconciled without the participant feeds")
+
+	group.participantsFeed = true
+	group.recount()
+	assert.Equal(t, group.Participants.Len(), group.UserCount, "The counts should be reconciled to the participants")
+	assert.Equal(t, 5, group.AnonCount, "The server reported anonymous count should be kept")
+}
+
+func TestGroup_SendMessageToChannel(t *testing.T) {
+	channels := make(chan string, 10)
+	group := newServedGroup(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head != "bm" {
+			return nil
+		}
+		// bm:nonce:channel:text
+		fields := strings.SplitN(data, ":", 3)
+		channels <- fields[1]
+		return []string{
+			"b:1717866894:Nekonyan::48875733:modID:tempID:userIP:" + fields[1] + "::" + fields[2],
+			"u:tempID:msgID",
+		}
+	})
+
+	_, err := group.SendMessageToChannel(models.FlagModChannel, "secret")
+	assert.ErrorIs(t, err, ErrInsufficientPermission, "The mod channel should require the permission")
+	assert.ErrorIs(t, err, ErrNoPermission, "Either permission error should match")
+	assert.Empty(t, channels, "Nothing should be sent without the permission")
+
+	msg, err := group.SendMessageToChannel(models.FlagRedChannel|models.FlagPremium, "hello")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "256", <-channels, "Only the channel flags should be sent")
+		assert.Equal(t, models.FlagRedChannel, msg.Channel())
+	}
+	assert.Zero(t, group.Channel, "The group channel should be kept")
+
+	group.Moderators.Set("nekonyan", models.GroupPermissions["SEE_MOD_CHANNEL"])
+	_, err = group.SendMessageToChannel(models.FlagModChannel, "secret")
+	if assert.NoError(t, err) {
+		assert.Equal(t, fmt.Sprintf("%d", models.FlagModChannel), <-channels)
+	}
+}
+
+func TestGroup_SetDefaultChannel(t *testing.T) {
+	group := &Group{Moderators: NewSyncMap[string, int64](), LoginName: "nekonyan"}
+
+	err := group.SetDefaultChannel(models.FlagRedChannel | models.FlagBlueChannel)
+	assert.ErrorIs(t, err, ErrInvalidChannel, "Two color channels should be rejected")
+	err = group.SetDefaultChannel(models.FlagPremium)
+	assert.ErrorIs(t, err, ErrInvalidChannel, "Non-channel flags should be rejected")
+	err = group.SetDefaultChannel(models.FlagModChannel)
+	assert.ErrorIs(t, err, ErrInsufficientPermission, "The mod channel should require the permission")
+	assert.Zero(t, group.Channel, "The channel should be kept on error")
+
+	assert.NoError(t, group.SetDefaultChannel(models.FlagGreenChannel))
+	assert.Equal(t, int64(models.FlagGreenChannel), group.Channel)
+
+	group.Moderators.Set("nekonyan", models.GroupPermissions["SEE_MOD_CHANNEL"])
+	assert.NoError(t, group.SetDefaultChannel(models.FlagModChannel|models.FlagPinkChannel))
+	assert.Equal(t, int64(models.FlagModChannel|models.FlagPinkChannel), group.Channel)
+}
+
+func TestGroup_AutoThrottle(t *testing.T) {
+	var cancel context.CancelFunc
+	group, sent := newEchoGroup(t, newTestApp(&Config{}), func(group *Group) {
+		group.context, cancel = context.WithCancel(group.context)
+		group.RateLimited = time.Now().Add(300 * time.Millisecond)
+	})
+	group.SetAutoThrottle(true)
+
+	start := time.Now()
+	_, err := group.SendMessage("hello")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond, "Sending should wait for the rate limit")
+	assert.Len(t, sent, 1)
+
+	group.RateLimited = time.Now().Add(time.Hour)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start = time.Now()
+	_, err = group.SendMessage("hello")
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+	assert.Less(t, time.Since(start), time.Second, "Cancellation should abort the wait immediately")
+}
+
+func TestGroup_MessageEdited(t *testing.T) {
+	var got []*Event
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { got = append(got, event) }, nil, OnMessage|OnMessageEdited))
+
+	group := &Group{App: app, Name: "testgroup"}
+	group.initFields()
+
+	group.wsOnFrame("b:1717866894:someuser::12345678:modID:tempID:userIP:0::<n000/>hello")
+	group.wsOnFrame("u:tempID:msgID")
+	group.wsOnFrame("updmsg:msgID:msgID2:<n000/>bad&nbsp;word")
+	group.wsOnFrame("updmsg:msgID2:msgID3")
+	group.wsOnFrame("updmsg:unknownID:msgID4")
+
+	if assert.Len(t, got, 3, "An edit of an unknown message should be ignored") {
+		assert.Equal(t, OnMessage, got[0].Type)
+		assert.False(t, got[0].Message.Edited)
+
+		assert.Equal(t, OnMessageEdited, got[1].Type)
+		assert.Equal(t, "msgID2", got[1].Message.ID)
+		assert.True(t, got[1].Message.Edited)
+		assert.False(t, got[1].Message.EditedTime.IsZero())
+		assert.Equal(t, "hello", got[1].Message.PreviousText)
+		assert.Equal(t, "bad word", got[1].Message.Text)
+		assert.Equal(t, "someuser", got[1].User.Name)
+
+		assert.Equal(t, "msgID3", got[2].Message.ID)
+		assert.Equal(t, "bad word", got[2].Message.Text, "The text should be kept if the frame has none")
+	}
+
+	_, ok := group.Messages.Get("msgID")
+	assert.False(t, ok, "The old ID should be replaced")
+	msg, _ := group.Messages.Get("msgID3")
+	assert.Equal(t, "bad word", msg.Text, "The stored message should be the edited one")
+}
+
+func TestGroup_Flags(t *testing.T) {
+	group := &Group{Flag: GroupStatuses["BROADCAST_MODE"] | GroupStatuses["NO_ANONS"]}
+
+	assert.True(t, group.HasBroadcast())
+	assert.False(t, group.ChannelsEnabled())
+	assert.True(t, group.Flags().Has(models.GroupFlagNoAnons))
+	assert.False(t, group.Flags().Has(models.GroupFlagNoAnons|models.GroupFlagEnableChannels), "All the flags should be set")
+
+	assert.Equal(t, GroupStatuses["BAN_PROXIES_AND_VPN"], int64(models.GroupFlagBanProxiesAndVPN))
+	assert.Equal(t, GroupStatuses["ENABLE_CHANNELS"], int64(models.GroupFlagEnableChannels))
+}
+
+func TestGroup_GetMessageByID(t *testing.T) {
+	group := &Group{}
+	group.initFields()
+
+	committed := &Message{}
+	pending := &Message{}
+	group.Messages.Set("permID", committed)
+	group.TempMessages.Set("tempID", pending)
+	group.TempMessageIds.Set("oldTempID", "permID")
+	group.TempMessageIds.Set("orphanTempID", "missingID")
+
+	tests := []struct {
+		id   string
+		want *Message
+		ok   bool
+	}{
+		{"permID", committed, true},
+		{"tempID", pending, true},
+		{"oldTempID", committed, true},
+		{"orphanTempID", nil, false},
+		{"unknownID", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := group.GetMessageByID(tt.id)
+		assert.Equal(t, tt.ok, ok, tt.id)
+		assert.Same(t, tt.want, got, tt.id)
+	}
+}
+
+func TestGroup_LoadMoreHistory(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, event.Message.ID)
+	}, nil, OnMessageHistory))
+
+	// The server history, from newer to older.
+	var history []string
+	var ids []string
+	for i := 25; i > 0; i-- {
+		id := fmt.Sprintf("m%02d", i)
+		history = append(history, fmt.Sprintf("i:%d:someuser::12345678:%s:%s:userIP:0::<n000/>text", 1717866900+i, id, id))
+		ids = append(ids, id)
+	}
+
+	var amounts []string
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "get_more" {
+			return nil
+		}
+		// get_more:amount:offset, the offset counts the pages of the given amount.
+		amount, offset, _ := strings.Cut(data, ":")
+		mu.Lock()
+		amounts = append(amounts, amount)
+		mu.Unlock()
+
+		size, _ := strconv.Atoi(amount)
+		page, _ := strconv.Atoi(offset)
+		start, end := utils.Min(page*size, len(history)), utils.Min((page+1)*size, len(history))
+		frames := append([]string{}, history[start:end]...)
+		if end == len(history) {
+			frames = append(frames, "nomore")
+		}
+		return append(frames, "gotmore:"+offset)
+	})
+
+	loaded, noMore, err := group.LoadMoreHistory(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+	assert.False(t, noMore)
+
+	loaded, noMore, err = group.LoadMoreHistory(30)
+	assert.NoError(t, err)
+	assert.Equal(t, 23, loaded, "The buffered surplus should be loaded before the next page")
+	assert.True(t, noMore)
+
+	loaded, noMore, err = group.LoadMoreHistory(5)
+	assert.NoError(t, err, "Loading at the oldest message should not fail")
+	assert.Zero(t, loaded)
+	assert.True(t, noMore)
+
+	reversed := make([]string, len(ids))
+	for i, id := range ids {
+		reversed[len(ids)-1-i] = id
+	}
+	assert.Equal(t, reversed, group.Messages.Keys(), "The messages should be prepended")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, ids, got)
+	assert.Equal(t, []string{"20", "20"}, amounts, "The pages should have a fixed size")
+}
+
+func TestGroup_ReconnectMaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	var gotRetries int
+	metrics := &countingMetrics{events: map[EventType]int{}, reconnects: map[string]int{}}
+	app := newTestApp(&Config{MaxRetry: 5}).UseMetrics(metrics)
+	app.SetReconnectGiveUpHandler(func(name string, isPrivate bool, retries int) {
+		gotRetries = retries
+	})
+
+	group := &Group{
+		App:         app,
+		Name:        "testgroup",
+		WsUrl:       "ws" + strings.TrimPrefix(server.URL, "http"),
+		ws:          &WebSocket{},
+		context:     context.Background(),
+		BackoffBase: 10 * time.Millisecond,
+		MaxRetries:  1,
+	}
+
+	err := group.Reconnect()
+
+	assert.ErrorIs(t, err, ErrRetryEnds)
+	assert.Equal(t, int32(1), attempts.Load(), "Reconnect should give up after a single attempt")
+	assert.Equal(t, 1, gotRetries)
+	assert.Equal(t, map[string]int{"testgroup": 1}, metrics.reconnects, "IncReconnect should be called once per reconnect")
+}
+
+func TestGroup_CancelReconnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	gaveUp := false
+	app := newTestApp(&Config{})
+	app.SetReconnectGiveUpHandler(func(name string, isPrivate bool, retries int) {
+		gaveUp = true
+	})
+
+	group := &Group{
+		App:         app,
+		Name:        "testgroup",
+		WsUrl:       "ws" + strings.TrimPrefix(server.URL, "http"),
+		ws:          &WebSocket{},
+		context:     context.Background(),
+		BackoffBase: time.Hour,
+		MaxRetries:  3,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- group.Reconnect() }()
+
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	group.CancelReconnect()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrRetryEnds)
+		assert.Less(t, time.Since(start), time.Second, "Reconnect should return promptly")
+		assert.False(t, gaveUp, "A canceled reconnect should not call the give-up handler")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reconnect did not return after CancelReconnect")
+	}
+
+	group.CancelReconnect() // No-op once the reconnect has ended.
+}
+
+func TestGroup_CancelReconnectWhileConnecting(t *testing.T) {
+	accepted := make(chan struct{}, 1)
+	done := make(chan struct{})
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		// Accept the socket but never send the version frame.
+		accepted <- struct{}{}
+		<-done
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	group := &Group{
+		App:         newTestApp(&Config{ConnectTimeout: time.Hour}),
+		Name:        "testgroup",
+		WsUrl:       "ws" + strings.TrimPrefix(server.URL, "http"),
+		ws:          &WebSocket{},
+		context:     context.Background(),
+		BackoffBase: time.Millisecond,
+		MaxRetries:  3,
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- group.Reconnect() }()
+
+	<-accepted
+	group.CancelReconnect()
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, ErrRetryEnds)
+		assert.False(t, group.ws.IsConnected(), "The interrupted connection should be closed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("The connection attempt should be interrupted")
+	}
+}
+
+func TestNewBackoff(t *testing.T) {
+	backoff := newBackoff(0, 0, 0)
+	assert.Equal(t, BASE_BACKOFF_DUR, backoff.Duration)
+	assert.Equal(t, MAX_BACKOFF_DUR, backoff.MaxDuration)
+
+	backoff = newBackoff(2*time.Second, time.Second, 0)
+	assert.Equal(t, 2*time.Second, backoff.Duration)
+	assert.Equal(t, 2*time.Second, backoff.MaxDuration, "The maximum should not be below the base")
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	assert.Equal(t, 1.0, newBackoff(0, 0, 2).Jitter, "The jitter should be clamped")
+	assert.Zero(t, newBackoff(0, 0, -1).Jitter)
+
+	backoff := newBackoff(100*time.Millisecond, time.Second, 0.5)
+	for i := 0; i < 1000; i++ {
+		wait := backoff.wait()
+		assert.GreaterOrEqual(t, wait, 50*time.Millisecond)
+		assert.LessOrEqual(t, wait, 150*time.Millisecond)
+	}
+
+	backoff = newBackoff(20*time.Millisecond, time.Second, 0.5)
+	start := time.Now()
+	assert.False(t, backoff.Sleep(context.Background()), "The sleep should run until the deadline")
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 10*time.Millisecond, "The sleep should last at least the lower bound")
+	assert.Equal(t, 40*time.Millisecond, backoff.Duration)
+
+	backoff = newBackoff(time.Minute, time.Minute, 0.5)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		backoff.Cancel()
+	}()
+	start = time.Now()
+	assert.True(t, backoff.Sleep(context.Background()), "Cancel should interrupt the sleep")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestGroup_RestrictionEvents(t *testing.T) {
+	events := make(chan *Event, 4)
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		events <- event
+	}, nil, OnRateLimited|OnRestricted))
+
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "bm" {
+			return nil
+		}
+		// bm:nonce:channel:text
+		if strings.HasSuffix(data, "flood") {
+			return []string{"show_fw:5"}
+		}
+		return []string{"ratelimited:10"}
+	})
+
+	_, err := group.SendMessage("flood")
+	assert.ErrorIs(t, err, ErrFloodWarning)
+	event := <-events
+	assert.Equal(t, OnRestricted, event.Type)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), event.RestrictUntil, time.Second)
+	assert.Equal(t, group.Restrict, event.RestrictUntil)
+
+	_, err = group.SendMessage("spam")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	event = <-events
+	assert.Equal(t, OnRateLimited, event.Type)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), event.RestrictUntil, time.Second)
+
+	// The restriction ending is received by the listener.
+	group.PropagateEvent("end_fw:0")
+	select {
+	case event = <-events:
+		assert.Equal(t, OnRestricted, event.Type)
+		assert.True(t, event.RestrictUntil.IsZero(), "The ended restriction should be zero")
+	case <-time.After(time.Second):
+		t.Fatal("OnRestricted was not dispatched")
+	}
+
+	// The unsolicited restrictions, e.g. after a [Group.Send], are received by the listener too.
+	group.PropagateEvent("ratelimited:20")
+	select {
+	case event = <-events:
+		assert.Equal(t, OnRateLimited, event.Type)
+		assert.WithinDuration(t, time.Now().Add(20*time.Second), event.RestrictUntil, time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("OnRateLimited was not dispatched")
+	}
+
+	group.PropagateEvent("tb:3")
+	select {
+	case event = <-events:
+		assert.Equal(t, OnRestricted, event.Type)
+		assert.WithinDuration(t, time.Now().Add(3*time.Minute), event.RestrictUntil, time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("OnRestricted was not dispatched")
+	}
+}
+
+func TestGroup_SendRaw(t *testing.T) {
+	app := newTestApp(&Config{})
+	group, sent := newEchoGroup(t, app)
+
+	msg, err := group.SendRaw(0, "<b>bold</b>\nimg123")
+	assert.NoError(t, err)
+	assert.Equal(t, "<b>bold</b>\nimg123", <-sent, "The HTML should be sent as-is")
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "msgID", msg.ID, "The permanent ID should be resolved")
+	}
+}
+
+func TestGroup_SendImage(t *testing.T) {
+	heads := make(chan string, 10)
+
+	app := newTestApp(&Config{})
+	group := newServedGroup(t, app, func(head, data string) []string {
+		heads <- head + ":" + data
+		if head != "bm" {
+			return nil
+		}
+		// bm:nonce:channel:text
+		fields := strings.SplitN(data, ":", 3)
+		return []string{
+			"b:1717866894:Nekonyan::48875733:modID:tempID:userIP:0::" + fields[2],
+			"u:tempID:msgID",
+		}
+	})
+
+	img := models.UploadedImage{ID: 123, Username: "nekonyan"}
+
+	group.PremiumExpireAt = time.Now().Add(-time.Hour)
+	_, err := group.SendImage(img)
+	assert.ErrorIs(t, err, ErrRequestFailed, "The image should not be sent without a premium membership")
+
+	group.PremiumExpireAt = time.Now().Add(time.Hour)
+	msg, err := group.SendImage(img)
+	assert.NoError(t, err)
+	assert.Equal(t, "msgmedia:1", <-heads, "The media feature should be enabled first")
+	assert.True(t, strings.HasSuffix(<-heads, "img123"))
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "msgID", msg.ID)
+	}
+
+	_, err = group.SendImage(img)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(<-heads, "bm:"), "The media feature should be enabled only once")
+}
+
+func TestGroup_SendMessageWithContext(t *testing.T) {
+	app := newTestApp(&Config{})
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "bm" {
+			return nil
+		}
+		// Echo only the messages marked as such, the others are never acknowledged.
+		fields := strings.SplitN(data, ":", 3)
+		if !strings.HasSuffix(fields[2], "echo") {
+			return nil
+		}
+		return []string{
+			"b:1717866894:Nekonyan::48875733:modID:tempID:userIP:0::" + fields[2],
+			"u:tempID:msgID",
+		}
+	})
+
+	msg, err := group.SendMessageWithContext(context.Background(), "echo")
+	assert.NoError(t, err)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "msgID", msg.ID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = group.SendMessageWithContext(ctx, "lost")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), SYNC_SEND_TIMEOUT, "The send should abort once the context is canceled")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = group.SendMessageWithContext(ctx, "lost")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The group stays usable afterwards.
+	_, err = group.SendMessage("echo")
+	assert.NoError(t, err)
+}
+
+func TestGroup_Dedupe(t *testing.T) {
+	var mu sync.Mutex
+	dispatched := map[string]int{}
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		dispatched[event.Message.ID]++
+	}, nil, OnMessage|OnMessageHistory))
+
+	group := &Group{App: app, Name: "testgroup"}
+	group.initFields()
+	group.SetDedupe(true, 10)
+
+	frames := []string{
+		"b:1717866901:someuser::12345678:modID:temp1:userIP:0::<n000/>one",
+		"u:temp1:msg1",
+		"u:temp2:msg2",
+		"b:1717866902:someuser::12345678:modID:temp2:userIP:0::<n000/>two",
+		"i:1717866900:someuser::12345678:modID:msg0:userIP:0::<n000/>zero",
+	}
+	for _, frame := range frames {
+		group.wsOnFrame(frame)
+	}
+
+	// A reconnect clears the messages, then the server replays the same frames.
+	group.initFields()
+	for _, frame := range frames {
+		group.wsOnFrame(frame)
+	}
+
+	assert.Equal(t, map[string]int{"msg0": 1, "msg1": 1, "msg2": 1}, dispatched)
+
+	// Without the deduplication, the replayed frames are dispatched again.
+	group.SetDedupe(false, 0)
+	group.initFields()
+	for _, frame := range frames {
+		group.wsOnFrame(frame)
+	}
+	assert.Equal(t, map[string]int{"msg0": 2, "msg1": 2, "msg2": 2}, dispatched)
+}
+
+func TestIDWindow(t *testing.T) {
+	var w idWindow
+	assert.False(t, w.seen("a"), "A disabled window should never report a duplicate")
+	assert.False(t, w.seen("a"))
+
+	w.reset(true, 2)
+	assert.False(t, w.seen("a"))
+	assert.False(t, w.seen("b"))
+	assert.True(t, w.seen("a"), "The seen ID should be remembered")
+	assert.False(t, w.seen("c"), "The least recently seen ID (b) should be evicted")
+	assert.False(t, w.seen("b"))
+	assert.True(t, w.seen("c"))
+}
+
+func TestGroup_GetUserIP(t *testing.T) {
+	group := &Group{App: newTestApp(&Config{}), Name: "testgroup"}
+	group.initFields()
+
+	group.Messages.Set("msg1", ParseGroupMessage("1717866901:someuser::12345678:modID1:msg1:1.2.3.4:0::<n000/>one", group))
+	group.Messages.Set("msg2", ParseGroupMessage("1717866902:someuser::12345678:modID2:msg2:5.6.7.8:0::<n000/>two", group))
+	group.Messages.Set("msg3", ParseGroupMessage("1717866903:otheruser::12345679:modID3:msg3::0::<n000/>three", group))
+
+	ip, ok := group.GetUserIP("SomeUser")
+	assert.True(t, ok)
+	assert.Equal(t, "5.6.7.8", ip, "The IP address of the latest message should be returned")
+
+	_, ok = group.GetUserIP("otheruser")
+	assert.False(t, ok, "An empty IP address should not be found")
+
+	_, ok = group.GetUserIP("nobody")
+	assert.False(t, ok)
+}
+
+func TestGroup_BanUserByName(t *testing.T) {
+	blocked := make(chan string, 1)
+
+	app := newTestApp(&Config{})
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "block" {
+			return nil
+		}
+		// block:moderationID:ip:username
+		blocked <- data
+		moderationID, _, _ := strings.Cut(data, ":")
+		return []string{"blocked:" + moderationID + ":1.2.3.4:anon1234:Nekonyan:1717866905"}
+	})
+
+	group.Messages.Set("msg1", ParseGroupMessage("1717866901:::12345678:modID1:msg1:1.2.3.4:0::<n1234/>one", group))
+	group.Messages.Set("msg2", ParseGroupMessage("1717866902:::12345678::msg2:1.2.3.4:0::<n1234/>two", group))
+	name := group.Messages.Newest(1)[0].User.Name
+
+	assert.NoError(t, group.BanUserByName(name))
+	assert.Equal(t, "modID1:1.2.3.4:"+name, <-blocked, "An older message with a moderation ID should be used")
+
+	assert.ErrorIs(t, group.BanUserByName("nobody"), ErrNoBannableMessage)
+}
+
+func TestGroup_OrderedHistory(t *testing.T) {
+	var history atomic.Int32
+	app := newTestApp(&Config{OrderedEvents: true})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { history.Add(1) }, nil, OnMessageHistory))
+
+	group := &Group{App: app, Name: "testgroup", WsUrl: newReplyServer(t, handshakeReply(historyFrames(MAX_MESSAGE_HISTORY), 10))}
+	if !assert.NoError(t, group.Connect(context.Background())) {
+		return
+	}
+	defer group.Disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	assert.NoError(t, group.WaitReady(ctx), "The history paging should not block the ordered consumer")
+	assert.Less(t, time.Since(start), SYNC_SEND_TIMEOUT)
+	assert.Equal(t, MAX_MESSAGE_HISTORY, group.Messages.Len(), "The whole history should be stored")
+	assert.Equal(t, int32(MAX_MESSAGE_HISTORY), history.Load(), "The whole history should be dispatched")
+	if oldest := group.Messages.Oldest(1); assert.Len(t, oldest, 1) {
+		assert.Equal(t, "m1", oldest[0].ID, "The history should be ordered from older to newer")
+	}
+}
@@ -1,7 +1,10 @@
 package chadango
 
 import (
+	"html"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/n0h4rt/chadango/utils"
 )
@@ -25,10 +28,17 @@ type Callback func(*Event, *Context)
 // It filters events based on a command prefix and a list of commands, and invokes a callback function when a matching command is found.
 // The handler also supports filtering events using a [Filter] object.
 type CommandHandler struct {
-	Callback Callback     // Callback is the function that will be invoked when a command event is triggered.
-	Filter   Filter       // Filter is the filter that will be applied to the events before invoking the callback.
-	Commands []string     // Commands is a list of command names that this handler will respond to.
-	app      *Application // app is a reference to the application where this handler is registered.
+	Callback    Callback     // Callback is the function that will be invoked when a command event is triggered.
+	Filter      Filter       // Filter is the filter that will be applied to the events before invoking the callback.
+	Commands    []string     // Commands is a list of command names that this handler will respond to.
+	Description string       // Description is a short explanation of what the command does, used in the help text.
+	Usage       string       // Usage describes the arguments of the command, e.g. "<text>".
+	MinArgs     int          // MinArgs is the minimum number of arguments the command requires.
+	ReplyUsage  bool         // ReplyUsage replies with the usage instead of invoking the callback when there are fewer than [MinArgs] arguments.
+	app         *Application // app is a reference to the application where this handler is registered.
+
+	CaseInsensitive bool              // CaseInsensitive matches the prefix, commands, and aliases regardless of case.
+	Aliases         map[string]string // Aliases maps alternative names to the command names, e.g. "q" to "quote".
 }
 
 // Check checks if the event is a command event that matches the prefix and command.
@@ -43,18 +53,24 @@ func (ch *CommandHandler) Check(event *Event) bool {
 		return false
 	}
 
-	if event.Message.FromSelf {
+	if event.IsFromSelf() {
 		return false
 	}
 
 	text := strings.TrimLeft(event.Message.Text, "\r\n\t ")
-	if !strings.HasPrefix(text, ch.app.Config.Prefix) {
+	prefix := ch.app.Config.Prefix
+	if len(text) < len(prefix) || !ch.equal(text[:len(prefix)], prefix) {
 		return false
 	}
 
-	text = strings.TrimLeft(text[len(ch.app.Config.Prefix):], "\r\n\t ")
+	text = strings.TrimLeft(text[len(prefix):], "\r\n\t ")
 	fields := strings.Fields(text)
-	if len(fields) == 0 || (len(fields) > 0 && !utils.Contains(ch.Commands, fields[0])) {
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, found := ch.resolveCommand(fields[0])
+	if !found {
 		return false
 	}
 
@@ -64,24 +80,74 @@ func (ch *CommandHandler) Check(event *Event) bool {
 	}
 
 	if ok {
-		event.Command = fields[0]
-		event.Arguments = fields[1:]
+		event.Command = command
 		event.Argument = strings.TrimLeft(text[len(fields[0]):], "\r\n\t ")
+		event.Arguments = utils.TokenizeArgs(event.Argument)
 		event.WithArgument = len(fields) > 1
 	}
 
 	return ok
 }
 
+// resolveCommand resolves the name to one of the [CommandHandler.Commands], following the aliases.
+//
+// Args:
+//   - name: The command name or alias from the message.
+//
+// Returns:
+//   - string: The command name as listed in [CommandHandler.Commands].
+//   - bool: True if the name resolves to a command, false otherwise.
+func (ch *CommandHandler) resolveCommand(name string) (string, bool) {
+	for alias, command := range ch.Aliases {
+		if ch.equal(alias, name) {
+			name = command
+			break
+		}
+	}
+
+	for _, command := range ch.Commands {
+		if ch.equal(command, name) {
+			return command, true
+		}
+	}
+
+	return "", false
+}
+
+// equal compares the strings, regardless of case if [CommandHandler.CaseInsensitive] is set.
+func (ch *CommandHandler) equal(a, b string) bool {
+	if ch.CaseInsensitive {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
 // Invoke executes the callback function for the command event.
 //
 // Args:
 //   - event: The event to handle.
 //   - context: The context for the event.
 func (ch *CommandHandler) Invoke(event *Event, context *Context) {
+	if ch.ReplyUsage && len(event.Arguments) < ch.MinArgs {
+		event.Message.Reply("%s", html.EscapeString(ch.usageText(event.Command)))
+		return
+	}
+
 	ch.Callback(event, context)
 }
 
+// usageText returns the usage reply for the command.
+//
+// Args:
+//   - command: The invoked command name.
+//
+// Returns:
+//   - string: The usage reply, e.g. "Usage: !echo <text>".
+func (ch *CommandHandler) usageText(command string) string {
+	return strings.TrimSpace("Usage: " + ch.app.Config.Prefix + command + " " + ch.Usage)
+}
+
 // NewCommandHandler returns a new [CommandHandler].
 //
 // Args:
@@ -99,6 +165,52 @@ func NewCommandHandler(callback Callback, filter Filter, commands ...string) Han
 	}
 }
 
+// NewCommandHandlerWithHelp returns a new [CommandHandler] with a description and usage for the help text.
+//
+// Args:
+//   - callback: The callback function to invoke when a command event is triggered.
+//   - filter: The filter to apply to the events before invoking the callback.
+//   - description: A short explanation of what the command does.
+//   - usage: The arguments of the command, e.g. "<text>".
+//   - commands: A list of command names that this handler will respond to.
+//
+// Returns:
+//   - Handler: A new [CommandHandler] instance.
+func NewCommandHandlerWithHelp(callback Callback, filter Filter, description, usage string, commands ...string) Handler {
+	return &CommandHandler{
+		Callback:    callback,
+		Filter:      filter,
+		Commands:    commands,
+		Description: description,
+		Usage:       usage,
+	}
+}
+
+// NewCommandHandlerWithUsage returns a new [CommandHandler] that replies with the usage
+// when the command is invoked with fewer than [minArgs] arguments.
+//
+// Args:
+//   - callback: The callback function to invoke when a command event is triggered.
+//   - filter: The filter to apply to the events before invoking the callback.
+//   - description: A short explanation of what the command does.
+//   - usage: The arguments of the command, e.g. "<text>".
+//   - minArgs: The minimum number of arguments the command requires.
+//   - commands: A list of command names that this handler will respond to.
+//
+// Returns:
+//   - Handler: A new [CommandHandler] instance.
+func NewCommandHandlerWithUsage(callback Callback, filter Filter, description, usage string, minArgs int, commands ...string) Handler {
+	return &CommandHandler{
+		Callback:    callback,
+		Filter:      filter,
+		Commands:    commands,
+		Description: description,
+		Usage:       usage,
+		MinArgs:     minArgs,
+		ReplyUsage:  true,
+	}
+}
+
 // MessageHandler is a struct that implements the [Handler] interface for handling message events.
 //
 // It filters events based on a [Filter] object and invokes a callback function when a matching message event is found.
@@ -119,7 +231,7 @@ func (mh *MessageHandler) Check(event *Event) bool {
 		return false
 	}
 
-	if event.Message.FromSelf {
+	if event.IsFromSelf() {
 		return false
 	}
 
@@ -209,3 +321,135 @@ func NewTypeHandler(callback Callback, filter Filter, eventType EventType) Handl
 		Type:     eventType,
 	}
 }
+
+// CooldownHandler is a struct that implements the [Handler] interface for enforcing a cooldown on another handler.
+//
+// It delegates to the wrapped handler, but rejects the events whose key is still cooling down.
+// This is useful for preventing command spam, e.g. allowing a user to invoke a command once every few seconds.
+type CooldownHandler struct {
+	Handler Handler                    // Handler is the wrapped handler.
+	Per     time.Duration              // Per is the cooldown duration for each key.
+	KeyFn   func(*Event) string        // KeyFn derives the cooldown key of the event, see [CooldownKey] for the default.
+	last    SyncMap[string, time.Time] // last stores the last accepted time of each key.
+	cleaned atomic.Int64               // cleaned is the Unix nano time of the last cleanup of the expired keys.
+}
+
+// Check checks if the wrapped handler accepts the event and its key is not cooling down.
+//
+// The wrapped handler is checked first, so that the key can be derived from the populated event (e.g. [Event.Command]).
+// Once accepted, the key starts cooling down.
+//
+// Args:
+//   - event: The event to check.
+//
+// Returns:
+//   - bool: True if the event is accepted, false otherwise.
+func (ch *CooldownHandler) Check(event *Event) bool {
+	if !ch.Handler.Check(event) {
+		return false
+	}
+
+	keyFn := ch.KeyFn
+	if keyFn == nil {
+		keyFn = CooldownKey
+	}
+	key := keyFn(event)
+	now := time.Now()
+
+	ch.cleanup(now)
+
+	ch.last.Lock()
+	defer ch.last.Unlock()
+
+	if ch.last.M == nil {
+		ch.last.M = make(map[string]time.Time)
+	}
+	if last, ok := ch.last.M[key]; ok && now.Sub(last) < ch.Per {
+		return false
+	}
+	ch.last.M[key] = now
+
+	return true
+}
+
+// cleanup removes the keys whose cooldown has expired, at most once per cooldown duration.
+//
+// Args:
+//   - now: The current time.
+func (ch *CooldownHandler) cleanup(now time.Time) {
+	cleaned := ch.cleaned.Load()
+	if now.UnixNano()-cleaned < int64(ch.Per) || !ch.cleaned.CompareAndSwap(cleaned, now.UnixNano()) {
+		return
+	}
+
+	ch.last.Lock()
+	defer ch.last.Unlock()
+
+	for key, last := range ch.last.M {
+		if now.Sub(last) >= ch.Per {
+			delete(ch.last.M, key)
+		}
+	}
+}
+
+// Invoke executes the wrapped handler.
+//
+// Args:
+//   - event: The event to handle.
+//   - context: The context for the event.
+func (ch *CooldownHandler) Invoke(event *Event, context *Context) {
+	ch.Handler.Invoke(event, context)
+}
+
+// CooldownKey is the default cooldown key of [CooldownHandler], which is the lowercased username and the command.
+//
+// Args:
+//   - event: The event to derive the key from.
+//
+// Returns:
+//   - string: The cooldown key, e.g. "someuser:echo".
+func CooldownKey(event *Event) string {
+	var username string
+	if event.User != nil {
+		username = strings.ToLower(event.User.Name)
+	} else if event.Message != nil && event.Message.User != nil {
+		username = strings.ToLower(event.Message.User.Name)
+	}
+
+	return username + ":" + event.Command
+}
+
+// NewCooldownHandler returns a new [CooldownHandler].
+//
+// Args:
+//   - inner: The handler to wrap.
+//   - per: The cooldown duration for each key.
+//   - keyFn: The function deriving the cooldown key of the event, nil for [CooldownKey].
+//
+// Returns:
+//   - Handler: A new [CooldownHandler] instance.
+func NewCooldownHandler(inner Handler, per time.Duration, keyFn func(*Event) string) Handler {
+	return &CooldownHandler{
+		Handler: inner,
+		Per:     per,
+		KeyFn:   keyFn,
+		last:    NewSyncMap[string, time.Time](),
+	}
+}
+
+// commandHandler returns the [CommandHandler] of the handler, unwrapping a [CooldownHandler] if needed.
+//
+// Args:
+//   - handler: The handler to unwrap.
+//
+// Returns:
+//   - *CommandHandler: The command handler.
+//   - bool: True if the handler is or wraps a command handler, false otherwise.
+func commandHandler(handler Handler) (*CommandHandler, bool) {
+	if cooldown, ok := handler.(*CooldownHandler); ok {
+		handler = cooldown.Handler
+	}
+
+	ch, ok := handler.(*CommandHandler)
+	return ch, ok
+}
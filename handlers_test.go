@@ -0,0 +1,147 @@
+package chadango
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0h4rt/chadango/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newEchoGroup returns a logged in group connected to a test server which echoes back every sent message.
+//
+// The raw text of each sent message is pushed into the returned channel.
+func newEchoGroup(t *testing.T, app *Application, opts ...func(*Group)) (*Group, <-chan string) {
+	sent := make(chan string, 10)
+	group := newServedGroup(t, app, func(head, data string) []string {
+		if head != "bm" {
+			return nil
+		}
+		// bm:nonce:channel:text
+		fields := strings.SplitN(data, ":", 3)
+		sent <- fields[2]
+		return []string{
+			"b:1717866894:Nekonyan::48875733:modID:tempID:userIP:0::" + fields[2],
+			"u:tempID:msgID",
+		}
+	}, opts...)
+
+	return group, sent
+}
+
+func TestCommandHandler_ReplyUsage(t *testing.T) {
+	app := newTestApp(&Config{Prefix: "!"})
+
+	invoked := false
+	handler := NewCommandHandlerWithUsage(func(*Event, *Context) { invoked = true }, nil, "Echoes the text.", "<text>", 1, "echo")
+	app.AddHandler(handler)
+
+	group, sent := newEchoGroup(t, app)
+
+	msg := ParseGroupMessage("1717866894:someuser::12345678:modID:msgID:userIP:0::<n000/>!echo", group)
+	event := &Event{Type: OnMessage, Group: group, Message: msg}
+	assert.True(t, handler.Check(event), "The command should match even without arguments")
+
+	handler.Invoke(event, nil)
+	assert.False(t, invoked, "The callback should not be invoked with too few arguments")
+	select {
+	case text := <-sent:
+		assert.True(t, strings.HasSuffix(text, "Usage: !echo &lt;text&gt;"), "The usage should be replied, got %q", text)
+	case <-time.After(time.Second):
+		assert.Fail(t, "The usage should be replied")
+	}
+
+	msg = ParseGroupMessage("1717866894:someuser::12345678:modID:msgID:userIP:0::<n000/>!echo hello", group)
+	event = &Event{Type: OnMessage, Group: group, Message: msg}
+	assert.True(t, handler.Check(event))
+
+	handler.Invoke(event, nil)
+	assert.True(t, invoked, "The callback should be invoked with enough arguments")
+	assert.Empty(t, sent, "The usage should not be replied")
+}
+
+func TestCommandHandler_Aliases(t *testing.T) {
+	app := newTestApp(&Config{Prefix: "b!"})
+
+	handler := &CommandHandler{
+		Commands:        []string{"quote"},
+		CaseInsensitive: true,
+		Aliases:         map[string]string{"q": "quote"},
+	}
+	app.AddHandler(handler)
+
+	check := func(text string) *Event {
+		event := &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Text: text, User: &models.User{Name: "someuser"}}}}
+		if handler.Check(event) {
+			return event
+		}
+		return nil
+	}
+
+	event := check("B!Q hello world")
+	if assert.NotNil(t, event, "A mixed-case prefix and alias should match") {
+		assert.Equal(t, "quote", event.Command, "The alias should resolve to the command name")
+		assert.Equal(t, []string{"hello", "world"}, event.Arguments)
+		assert.Equal(t, "hello world", event.Argument)
+	}
+	event = check("b!QUOTE")
+	if assert.NotNil(t, event) {
+		assert.Equal(t, "quote", event.Command)
+	}
+	assert.Nil(t, check("b!quotes"), "An unknown command should not match")
+
+	handler.CaseInsensitive = false
+	assert.Nil(t, check("B!q"), "A case-sensitive handler should not match a mixed-case prefix")
+	assert.Nil(t, check("b!Q"), "A case-sensitive handler should not match a mixed-case alias")
+	event = check("b!q")
+	if assert.NotNil(t, event) {
+		assert.Equal(t, "quote", event.Command)
+	}
+
+	handler.Aliases = nil
+	assert.Nil(t, check("b!q"), "An alias should not match without the aliases")
+	assert.NotNil(t, check("b!quote"))
+}
+
+func TestCommandHandler_QuotedArguments(t *testing.T) {
+	app := newTestApp(&Config{Prefix: "!"})
+
+	handler := NewCommandHandler(nil, nil, "say")
+	app.AddHandler(handler)
+
+	event := &Event{Type: OnMessage, Message: &Message{nil, nil, models.Message{Text: `!say "hello world" foo`, User: &models.User{Name: "someuser"}}}}
+	assert.True(t, handler.Check(event))
+	assert.Equal(t, []string{"hello world", "foo"}, event.Arguments, "The quoted argument should not be split")
+	assert.Equal(t, `"hello world" foo`, event.Argument, "The raw argument should be kept")
+	assert.True(t, event.WithArgument)
+}
+
+func TestCooldownHandler(t *testing.T) {
+	app := newTestApp(&Config{Prefix: "!"})
+
+	handler := NewCooldownHandler(NewCommandHandler(nil, nil, "echo"), 50*time.Millisecond, nil)
+	app.AddHandler(handler)
+	assert.Equal(t, []string{"echo"}, app.Commands(), "The wrapped command should be listed")
+
+	check := func(username, text string) bool {
+		event := &Event{Type: OnMessage, User: &models.User{Name: username}, Message: &Message{nil, nil, models.Message{Text: text, User: &models.User{Name: username}}}}
+		return handler.Check(event)
+	}
+
+	passed := 0
+	for i := 0; i < 10; i++ {
+		if check("someuser", "!echo hello") {
+			passed++
+		}
+	}
+	assert.Equal(t, 1, passed, "Only the first check within the cooldown should pass")
+	assert.True(t, check("otheruser", "!echo hello"), "Another user should have a separate cooldown")
+	assert.False(t, check("someuser", "!other"), "A rejected event should not pass regardless of the cooldown")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, check("SomeUser", "!echo hello"), "The check should pass once the cooldown expires")
+
+	cooldown := handler.(*CooldownHandler)
+	assert.Equal(t, 1, cooldown.last.Len(), "The expired keys should be cleaned up")
+}
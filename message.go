@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/n0h4rt/chadango/models"
@@ -20,6 +21,10 @@ var (
 	// Then the `ReplaceAllString(text, "$1")` method will then keep the content matched by group 1
 	// and remove the content matched by group 2.
 	HtmlTagRe = regexp.MustCompile(`(<br\s*\/?>)|(<[^>]+>)`)
+
+	// whitespaceReplacer converts the non-breaking spaces and tabs into regular spaces,
+	// and the carriage returns into newlines.
+	whitespaceReplacer = strings.NewReplacer("\u00a0", " ", "\t", " ", "\r\n", "\n", "\r", "\n")
 )
 
 type Message struct {
@@ -148,13 +153,68 @@ func ParseGroupMessage(data string, group *Group) *Message {
 	msg.Flag = models.MessageChannel(flag)
 	// _ = fields[8]  // Omitted for now
 	msg.RawText = fields[9]
-	text := HtmlTagRe.ReplaceAllString(fields[9], "$1")
-	text = strings.ReplaceAll(text, "<br/>", "\n")
-	msg.Text = html.UnescapeString(text)
+	msg.Text = groupText(fields[9], group)
 
 	return msg
 }
 
+// groupText converts the raw text of a group message into its plain text,
+// normalizing the whitespaces unless [Config.PreserveWhitespace] is set.
+func groupText(rawText string, group *Group) string {
+	text := plainText(rawText)
+	if group.App == nil || !group.App.Config.PreserveWhitespace {
+		text = normalizeWhitespace(text)
+	}
+
+	return text
+}
+
+// plainText converts the raw text of a group message into its plain text.
+//
+// It removes the HTML tags, converts the `<br/>` tags into newlines, and unescapes the HTML entities.
+func plainText(rawText string) string {
+	text := HtmlTagRe.ReplaceAllString(rawText, "$1")
+	text = strings.ReplaceAll(text, "<br/>", "\n")
+
+	return html.UnescapeString(text)
+}
+
+// normalizeWhitespace converts the whitespace artifacts in the plain text (e.g. the non-breaking spaces from `&nbsp;`)
+// into the regular whitespaces, so that the text can be parsed naively (e.g. matching the command prefix).
+func normalizeWhitespace(text string) string {
+	return whitespaceReplacer.Replace(text)
+}
+
+// TemplateMessage renders the [text/template] template with the data into a Chatango-safe message text.
+//
+// The rendered output is HTML-escaped, so neither the template nor the data can inject markup,
+// then the newlines are converted into `<br/>` tags.
+//
+// Args:
+//   - tmpl: The template text.
+//   - data: The data to render the template with.
+//
+// Returns:
+//   - string: The rendered message text.
+//   - error: An error if parsing or executing the template fails.
+func TemplateMessage(tmpl string, data any) (string, error) {
+	t, err := template.New("message").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err = t.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	text := html.EscapeString(b.String())
+	text = strings.ReplaceAll(text, "\r\n", "<br/>")
+	text = strings.ReplaceAll(text, "\n", "<br/>")
+
+	return text, nil
+}
+
 // ParsePrivateMessage parses a private message data.
 //
 // It extracts information about the sender, the content, the time of sending, and the channel flags from the provided data.
@@ -181,6 +241,9 @@ func ParsePrivateMessage(data string, private *Private) *Message {
 
 	text := HtmlTagRe.ReplaceAllString(fields[5], "$1")
 	msg.Text = html.UnescapeString(text)
+	if private.App == nil || !private.App.Config.PreserveWhitespace {
+		msg.Text = normalizeWhitespace(msg.Text)
+	}
 
 	return msg
 }
@@ -1,6 +1,7 @@
 package chadango
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -38,7 +39,7 @@ func TestParseGroupMessage(t *testing.T) {
 			name: "NamedAnonMessage",
 			data: "1721913578.62::anonName:23361675:moderationID:messageID:userIP:0::<n3512/>asdfghjkl",
 			want: &models.Message{
-				Time:         time.Unix(1721913578, int64(.62*1e6)),
+				Time:         time.Unix(1721913578, 620000000),
 				UserID:       23361675,
 				ModerationID: "moderationID",
 				ID:           "messageID",
@@ -53,7 +54,7 @@ func TestParseGroupMessage(t *testing.T) {
 			name: "UnnamedAnonMessage",
 			data: "1721913578.62:::23361675:moderationID:messageID:userIP:0::<n3512/>asdfghjkl",
 			want: &models.Message{
-				Time:         time.Unix(1721913578, int64(.62*1e6)),
+				Time:         time.Unix(1721913578, 620000000),
 				UserID:       23361675,
 				ModerationID: "moderationID",
 				ID:           "messageID",
@@ -98,7 +99,7 @@ func TestParsePrivateMessage(t *testing.T) {
 			name: "NormalMessage",
 			data: "clonerxyz:clonerxyz:unknown:1723029464.85:0:<m v=\"1\">text</m>",
 			want: &models.Message{
-				Time:      time.Unix(1723029464, 850000),
+				Time:      time.Unix(1723029464, 850000000),
 				ID:        "1723029464",
 				RawText:   "<m v=\"1\">text</m>",
 				Text:      "text",
@@ -148,3 +149,127 @@ func TestParseAnnouncement(t *testing.T) {
 		})
 	}
 }
+
+func TestMessage_BadgeType(t *testing.T) {
+	group := &Group{
+		LoginName: "Nekonyan",
+		UserID:    48875733,
+	}
+	tests := []struct {
+		name  string
+		frame string
+		want  string
+	}{
+		{
+			name:  "NoBadge",
+			frame: `b:1717866894.51:someuser::12345678:b0d2e4a6:1717866894510001:10.0.0.1:0::<n000/><f x11000="0">hello`,
+			want:  models.BadgeNone,
+		},
+		{
+			name:  "ModBadge",
+			frame: `b:1717866894.52:somemod::23456789:c1e3f5a7:1717866894520002:10.0.0.2:64::<n39b/><f x12f00="1">hello`,
+			want:  models.BadgeMod,
+		},
+		{
+			name:  "StaffBadge",
+			frame: `b:1717866894.53:someowner::34567890:d2f4a6b8:1717866894530003:10.0.0.3:128::<n900/><f x11000="0">hello`,
+			want:  models.BadgeStaff,
+		},
+		{
+			name:  "StaffBadgeWithChannel",
+			frame: `i:1717866894.54:someowner::34567890:e3a5b7c9:1717866894540004:10.0.0.3:2496::<n900/>hello`,
+			want:  models.BadgeStaff,
+		},
+		{
+			name:  "ChosenModBadge",
+			frame: `b:1717866894.55:someowner::34567890:f4b6c8d0:1717866894550005:10.0.0.3:128::<badge v="mod"/><n900/>hello`,
+			want:  models.BadgeMod,
+		},
+		{
+			name:  "ChosenNoBadge",
+			frame: `b:1717866894.56:somemod::23456789:a5c7d9e1:1717866894560006:10.0.0.2:64::<badge v="none"/><n39b/>hello`,
+			want:  models.BadgeNone,
+		},
+		{
+			name:  "ChosenStaffBadge",
+			frame: `i:1717866894.57:someowner::34567890:b6d8e0f2:1717866894570007:10.0.0.3:0::<n900/><badge v="staff" />hello`,
+			want:  models.BadgeStaff,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, data, _ := strings.Cut(tt.frame, ":")
+			got := ParseGroupMessage(data, group)
+			assert.Equal(t, tt.want, got.BadgeType())
+			assert.Equal(t, "hello", got.Text)
+		})
+	}
+}
+
+func TestTemplateMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		data    any
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "PlainData",
+			tmpl: "Hello, {{.Name}}!",
+			data: map[string]string{"Name": "Nekonyan"},
+			want: "Hello, Nekonyan!",
+		},
+		{
+			name: "EscapedData",
+			tmpl: "Hello, {{.Name}}!",
+			data: map[string]string{"Name": `<b>Tom & "Jerry"</b>`},
+			want: "Hello, &lt;b&gt;Tom &amp; &#34;Jerry&#34;&lt;/b&gt;!",
+		},
+		{
+			name: "Newlines",
+			tmpl: "{{range .}}{{.}}\n{{end}}",
+			data: []string{"a<", "b>"},
+			want: "a&lt;<br/>b&gt;<br/>",
+		},
+		{
+			name:    "BadTemplate",
+			tmpl:    "{{.Name",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TemplateMessage(tt.tmpl, tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseGroupMessage_Whitespace(t *testing.T) {
+	data := "1717866894:someuser::12345678:moderationID:messageID:userIP:0::<n000/>!help&nbsp;&nbsp;me\tplease\r<br/>now"
+
+	group := &Group{App: New(&Config{})}
+	got := ParseGroupMessage(data, group)
+	assert.Equal(t, "!help  me please\nnow", got.Text, "Whitespaces should be normalized by default")
+	assert.Equal(t, "<n000/>!help&nbsp;&nbsp;me\tplease\r<br/>now", got.RawText, "Raw text should be kept as is")
+
+	group = &Group{App: New(&Config{PreserveWhitespace: true})}
+	got = ParseGroupMessage(data, group)
+	assert.Equal(t, "!help\u00a0\u00a0me\tplease\r\nnow", got.Text, "Whitespaces should be preserved if configured")
+}
+
+func TestParsePrivateMessage_Whitespace(t *testing.T) {
+	data := "clonerxyz:clonerxyz:unknown:1723029464.85:0:<m v=\"1\">!help&nbsp;me</m>"
+
+	got := ParsePrivateMessage(data, &Private{App: New(&Config{})})
+	assert.Equal(t, "!help me", got.Text)
+
+	got = ParsePrivateMessage(data, &Private{App: New(&Config{PreserveWhitespace: true})})
+	assert.Equal(t, "!help\u00a0me", got.Text)
+}
@@ -0,0 +1,20 @@
+package chadango
+
+import "time"
+
+// Metrics is an interface that defines the hooks for collecting the application metrics.
+//
+// It allows wiring a metrics backend (e.g., Prometheus) without patching the library.
+// The methods are called synchronously, so they should return quickly.
+type Metrics interface {
+	IncEvent(EventType)                               // IncEvent is called for every dispatched event.
+	IncReconnect(group string)                        // IncReconnect is called when a group starts reconnecting.
+	ObserveSendLatency(group string, d time.Duration) // ObserveSendLatency is called when a sent message is echoed back by the server.
+}
+
+// noopMetrics is the default [Metrics] which discards everything.
+type noopMetrics struct{}
+
+func (noopMetrics) IncEvent(EventType)                       {}
+func (noopMetrics) IncReconnect(string)                      {}
+func (noopMetrics) ObserveSendLatency(string, time.Duration) {}
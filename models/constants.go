@@ -1,8 +1,15 @@
 package models
 
+import "errors"
+
 const (
 	API_PHOTO_FULL_IMG = "https://fp.chatango.com/profileimg/%s/%s/%s/full.jpg"
 	API_MSG_BG_IMG     = "https://ust.chatango.com/profileimg/%s/%s/%s/msgbg.jpg"
 	API_UM_THUMB       = "https://ust.chatango.com/um/%s/%s/%s/img/t_%%d.jpg"
 	API_UM_LARGE       = "https://ust.chatango.com/um/%s/%s/%s/img/l_%%d.jpg"
 )
+
+var (
+	ErrNoImage    = errors.New("no image")
+	ErrNoUsername = errors.New("no username")
+)
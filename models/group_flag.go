@@ -0,0 +1,35 @@
+package models
+
+// GroupFlag represents the feature flags of a group.
+type GroupFlag int64
+
+const (
+	GroupFlagNoAnons                GroupFlag = 4
+	GroupFlagNoCounter              GroupFlag = 16
+	GroupFlagDisallowImages         GroupFlag = 32
+	GroupFlagDisallowLinks          GroupFlag = 64
+	GroupFlagDisallowVideos         GroupFlag = 128
+	GroupFlagBanwordOnlyToAuthor    GroupFlag = 1024
+	GroupFlagFloodControlled        GroupFlag = 2048
+	GroupFlagEnableChannels         GroupFlag = 8192
+	GroupFlagBasicNonsenseDetection GroupFlag = 16384 // js: nlp_single_msg
+	GroupFlagBlockRepetitiousMsgs   GroupFlag = 32768 // js: nlp_msg_queue
+	GroupFlagBroadcastMode          GroupFlag = 65536
+	GroupFlagClosedNoMods           GroupFlag = 131072
+	GroupFlagGroupClosed            GroupFlag = 262144
+	GroupFlagDisplayBadges          GroupFlag = 524288
+	GroupFlagModsChooseBadges       GroupFlag = 1048576
+	GroupFlagAdvNonsenseDetection   GroupFlag = 2097152 // js: nlp_ngram
+	GroupFlagBanProxiesAndVPN       GroupFlag = 4194304
+)
+
+// Has checks whether all the given flags are set.
+//
+// Args:
+//   - flag: The flags to check.
+//
+// Returns:
+//   - bool: True if all the flags are set, false otherwise.
+func (f GroupFlag) Has(flag GroupFlag) bool {
+	return f&flag == flag
+}
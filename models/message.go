@@ -31,6 +31,14 @@ var (
 	NameColorRe        = regexp.MustCompile(`<n([\da-fA-F]{1,6})\/>`)
 	FontStyleRe        = regexp.MustCompile(`<f x([\da-fA-F]+)?="([\d\w]+)?">`)
 	PrivateFontStyleRe = regexp.MustCompile(`<g x(\d+)?s([\da-fA-F]+)?="([\d\w]+)?">`)
+	ImageEmbedRe       = regexp.MustCompile(`(?i)https?://\S+\.(?:png|jpe?g|gif|bmp|webp)(?:\?\S*)?`)
+	BadgeRe            = regexp.MustCompile(`<badge v="(none|mod|staff)"\s*/>`)
+)
+
+const (
+	BadgeNone  = ""      // BadgeNone indicates that the message shows no badge.
+	BadgeMod   = "mod"   // BadgeMod indicates that the message shows the moderator badge.
+	BadgeStaff = "staff" // BadgeStaff indicates that the message shows the staff badge.
 )
 
 const (
@@ -59,6 +67,9 @@ type Message struct {
 	FromSelf     bool           // FromSelf indicates whether the message was sent by the current user.
 	FromAnon     bool           // FromAnon indicates whether the message was sent by an anonymous user.
 	AnonSeed     int            // AnonSeed represents the seed value used for anonymous user identification.
+	Edited       bool           // Edited indicates whether the content of the message has been edited.
+	EditedTime   time.Time      // EditedTime represents the time when the edited content was received.
+	PreviousText string         // PreviousText contains the parsed text of the message before the edit.
 }
 
 // Channel returns the channel flag of the message.
@@ -117,6 +128,14 @@ func (m *Message) HasMedia() bool {
 	return m.Flag&FlagMedia != 0
 }
 
+// ImageEmbeds returns the image urls in the message text, which are displayed as embeds by the client.
+//
+// Returns:
+//   - []string: The image urls, nil if there is none.
+func (m *Message) ImageEmbeds() []string {
+	return ImageEmbedRe.FindAllString(m.Text, -1)
+}
+
 // IsCensored checks if the message contains censor.
 //
 // Returns:
@@ -141,6 +160,32 @@ func (m *Message) HasStaffIcon() bool {
 	return m.Flag&FlagStaffIcon != 0
 }
 
+// BadgeType returns the badge displayed along with the message.
+//
+// An explicit badge visibility choice encoded in the raw text (see [BadgeRe]) takes precedence.
+// Otherwise, the badge is carried by the [FlagModIcon] and [FlagStaffIcon] flags,
+// where the staff badge takes precedence over the moderator badge.
+//
+// Returns:
+//   - string: [BadgeStaff], [BadgeMod], or [BadgeNone] if no badge is shown.
+func (m *Message) BadgeType() string {
+	if reResult := BadgeRe.FindStringSubmatch(m.RawText); len(reResult) > 1 {
+		if reResult[1] == "none" {
+			return BadgeNone
+		}
+		return reResult[1]
+	}
+
+	switch {
+	case m.HasStaffIcon():
+		return BadgeStaff
+	case m.HasModIcon():
+		return BadgeMod
+	default:
+		return BadgeNone
+	}
+}
+
 // IsInModChannel checks if the message is in a mod channel.
 //
 // Returns:
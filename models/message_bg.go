@@ -3,6 +3,7 @@ package models
 import (
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/n0h4rt/chadango/utils"
 )
@@ -54,3 +55,29 @@ func (mb *MessageBackground) GetForm() url.Values {
 func (mb *MessageBackground) GetImageURL() string {
 	return utils.UsernameToURL(API_MSG_BG_IMG, mb.Username)
 }
+
+// IsCustom checks whether the message background differs from the default one.
+//
+// The default message background has no image and a white (or unset) color.
+func (mb *MessageBackground) IsCustom() bool {
+	return mb.UseImage || (mb.Color != "" && !strings.EqualFold(mb.Color, "ffffff"))
+}
+
+// ImageURL returns a validated url of the message background image.
+//
+// Unlike [MessageBackground.GetImageURL], it does not produce a broken url
+// when the background image is not used or the username is unknown.
+//
+// Returns:
+//   - string: The url of the message background image.
+//   - error: [ErrNoImage] if the background image is not used, or [ErrNoUsername] if the username is not set.
+func (mb *MessageBackground) ImageURL() (string, error) {
+	if !mb.UseImage {
+		return "", ErrNoImage
+	}
+	if mb.Username == "" {
+		return "", ErrNoUsername
+	}
+
+	return mb.GetImageURL(), nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBackground_IsCustom(t *testing.T) {
+	assert.False(t, (&MessageBackground{}).IsCustom(), "An empty background should not be custom")
+	assert.False(t, (&MessageBackground{Color: "FFFFFF"}).IsCustom(), "A white background should not be custom")
+	assert.True(t, (&MessageBackground{Color: "000000"}).IsCustom(), "A colored background should be custom")
+	assert.True(t, (&MessageBackground{UseImage: true}).IsCustom(), "A background image should be custom")
+}
+
+func TestMessageBackground_ImageURL(t *testing.T) {
+	_, err := (&MessageBackground{Username: "nekonyan"}).ImageURL()
+	assert.ErrorIs(t, err, ErrNoImage)
+
+	_, err = (&MessageBackground{UseImage: true}).ImageURL()
+	assert.ErrorIs(t, err, ErrNoUsername)
+
+	url, err := (&MessageBackground{UseImage: true, Username: "nekonyan"}).ImageURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://ust.chatango.com/profileimg/n/e/nekonyan/msgbg.jpg", url)
+}
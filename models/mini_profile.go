@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/xml"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/n0h4rt/chadango/utils"
@@ -23,6 +24,14 @@ func (m MiniProfile) PhotoLargeURL() string {
 	return utils.UsernameToURL(API_PHOTO_FULL_IMG, m.Username)
 }
 
+// Age returns the age of the user computed from the birth date.
+//
+// Returns:
+//   - int: The age in years, or 0 if the birth date is unset.
+func (m MiniProfile) Age() int {
+	return m.Birth.ageAt(time.Now())
+}
+
 // QueryEscaped represents a query-escaped string.
 type QueryEscaped string
 
@@ -55,6 +64,21 @@ func (c *BirthDate) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+// ageAt computes the age in years at the specified time, or 0 if the birth date is unset.
+func (c BirthDate) ageAt(now time.Time) int {
+	birth := time.Time(c)
+	if birth.IsZero() || birth.After(now) {
+		return 0
+	}
+
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || (now.Month() == birth.Month() && now.Day() < birth.Day()) {
+		age--
+	}
+
+	return age
+}
+
 // Location represents the location information of a user.
 type Location struct {
 	Country   string  `xml:"c,attr"`    // Country name or US postal code
@@ -64,6 +88,38 @@ type Location struct {
 	Text      string  `xml:",chardata"` // String text of the location
 }
 
+// CountryName returns the human-readable country name of the location.
+//
+// A US postal code is mapped to "United States", and an ISO 3166-1 alpha-2 code is mapped using [CountryNames].
+// Otherwise, the raw country value is returned as is.
+//
+// Returns:
+//   - string: The country name, or an empty string if unset.
+func (l Location) CountryName() string {
+	country := strings.TrimSpace(l.Country)
+	if country == "" {
+		return ""
+	}
+
+	if utils.IsDigit(country) {
+		return CountryNames["US"]
+	}
+
+	if name, ok := CountryNames[strings.ToUpper(country)]; ok {
+		return name
+	}
+
+	return country
+}
+
+// HasCoordinates checks if the location has the latitude and longitude set.
+//
+// Returns:
+//   - bool: True if the coordinates are set, otherwise false.
+func (l Location) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
 // PremiumDate represents a premium date of a user.
 type PremiumDate time.Time
 
@@ -79,3 +135,11 @@ func (c *PremiumDate) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error
 	*c = PremiumDate(parsedTimestamp)
 	return nil
 }
+
+// IsActive checks if the premium has not yet expired.
+//
+// Returns:
+//   - bool: True if the premium is active, otherwise false.
+func (c PremiumDate) IsActive() bool {
+	return time.Time(c).After(time.Now())
+}
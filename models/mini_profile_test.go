@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocation_CountryName(t *testing.T) {
+	tests := []struct {
+		country  string
+		expected string
+	}{
+		{"", ""},
+		{"ID", "Indonesia"},
+		{"gb", "United Kingdom"},
+		{"90210", "United States"},
+		{"Atlantis", "Atlantis"},
+	}
+
+	for _, test := range tests {
+		location := Location{Country: test.country}
+		assert.Equal(t, test.expected, location.CountryName(), "CountryName(%q) should match the expected result", test.country)
+	}
+}
+
+func TestLocation_HasCoordinates(t *testing.T) {
+	assert.False(t, Location{}.HasCoordinates(), "An empty location should not have coordinates")
+	assert.True(t, Location{Latitude: -6.2, Longitude: 106.8}.HasCoordinates(), "A location with lat/lon should have coordinates")
+}
+
+func TestBirthDate_ageAt(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		birth    BirthDate
+		expected int
+	}{
+		{BirthDate{}, 0},
+		{BirthDate(time.Date(2000, time.June, 15, 0, 0, 0, 0, time.UTC)), 24},
+		{BirthDate(time.Date(2000, time.June, 16, 0, 0, 0, 0, time.UTC)), 23},
+		{BirthDate(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)), 24},
+		{BirthDate(time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)), 0},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.birth.ageAt(now), "ageAt should match the expected result")
+	}
+}
+
+func TestPremiumDate_IsActive(t *testing.T) {
+	assert.False(t, PremiumDate{}.IsActive(), "An unset premium date should not be active")
+	assert.False(t, PremiumDate(time.Now().Add(-time.Hour)).IsActive(), "An expired premium should not be active")
+	assert.True(t, PremiumDate(time.Now().Add(time.Hour)).IsActive(), "An unexpired premium should be active")
+}
@@ -266,9 +266,7 @@ func (ma *ModAction) String() (actionDesc string) {
 		extra := ma.ExtraAsSliceInterface()
 		if extra[0].(float64) > 0 {
 			actionDesc = strings.Replace(actionDesc, "*didenable*", ModactionTmpl["enabled"], 1)
-			announcement, _ := url.QueryUnescape(extra[2].(string))
-			announcement = NameFontTag.ReplaceAllString(announcement, "")
-			announcement = strings.ReplaceAll(announcement, "&nbsp;", " ")
+			announcement := CleanAnnouncement(extra[2].(string))
 			actionDesc += " " + ModactionTmpl["enable_annc"]
 			actionDesc = strings.Replace(actionDesc, "*n*", fmt.Sprintf("%d", int(extra[1].(float64))), 1)
 			actionDesc = strings.Replace(actionDesc, "*msg*", announcement, 1)
@@ -323,6 +321,26 @@ func (ma *ModAction) String() (actionDesc string) {
 	return
 }
 
+// CleanAnnouncement makes the raw announcement text human-readable.
+//
+// The text is URL-unescaped if possible, then the name and font tags are stripped and the "&nbsp;" are turned into spaces.
+//
+// Args:
+//   - raw: The raw announcement text.
+//
+// Returns:
+//   - string: The cleaned announcement text.
+func CleanAnnouncement(raw string) string {
+	announcement, err := url.QueryUnescape(raw)
+	if err != nil {
+		announcement = raw
+	}
+	announcement = NameFontTag.ReplaceAllString(announcement, "")
+	announcement = strings.ReplaceAll(announcement, "&nbsp;", " ")
+
+	return announcement
+}
+
 // ParseModActions parses a string data and returns a slice of [ModAction] objects
 //
 // Args:
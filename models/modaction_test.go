@@ -138,3 +138,9 @@ func TestParseModActions_UniqueTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestCleanAnnouncement(t *testing.T) {
+	assert.Equal(t, "3 kata terserah", CleanAnnouncement("%3CnA149A0/%3E%3Cf%20xf9f%3D%22%22%3E3%26nbsp%3Bkata%26nbsp%3Bterserah"), "The URL-escaped text should be unescaped")
+	assert.Equal(t, "3 kata terserah", CleanAnnouncement(`<nA149A0/><f xf9f="">3&nbsp;kata&nbsp;terserah`))
+	assert.Equal(t, "100% sure", CleanAnnouncement("100% sure"), "An invalid escape should be kept as is")
+}
@@ -25,3 +25,10 @@ func (i UploadedImage) ThumbURL() string {
 func (i UploadedImage) LargeURL() string {
 	return fmt.Sprintf(utils.UsernameToURL(API_UM_LARGE, i.Username), i.ID)
 }
+
+// MessageEmbed returns the markup that embeds the image in a message.
+//
+// It formated as img{ID}.
+func (i UploadedImage) MessageEmbed() string {
+	return fmt.Sprintf("img%d", i.ID)
+}
@@ -2,13 +2,24 @@ package chadango
 
 import (
 	"context"
+	"database/sql"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"os"
+	"reflect"
 	"time"
 
+	"github.com/n0h4rt/chadango/models"
 	"github.com/rs/zerolog/log"
 )
 
+func init() {
+	// Registered for the [Config.PersistMessages], which stores the messages in the chat data.
+	gob.Register([]models.Message{})
+	RegisterJSONType([]models.Message{})
+}
+
 // Persistence is an interface that defines the methods for managing data persistence.
 //
 // It provides methods for initializing, running, closing, and accessing data stored in the persistence layer.
@@ -188,3 +199,477 @@ func (p *GobPersistence) GetChatData(key string) *SyncMap[string, any] {
 func (p *GobPersistence) DelChatData(key string) {
 	p.ChatData.Del(key)
 }
+
+// SQLitePersistence is responsible for loading and saving data to a SQLite database.
+//
+// The bot data and the chat data of each chat are stored as gob encoded blobs keyed by name.
+// The chat data is loaded lazily on the first [SQLitePersistence.GetChatData] call,
+// and every loaded data is checkpointed periodically, so a crash loses at most one interval of changes.
+// If the interval is set to less than 1 minute, it will be adjusted to 30 minutes.
+//
+// The database connection is provided by the caller, opened with any SQLite driver of choice, e.g.:
+//
+//	db, _ := sql.Open("sqlite3", "chadango.db")
+//	app.UsePersistence(&SQLitePersistence{DB: db, Interval: 10 * time.Minute})
+type SQLitePersistence struct {
+	DB        *sql.DB                                // Database connection, closed by [SQLitePersistence.Close].
+	Interval  time.Duration                          // Interval for the checkpoints.
+	BotData   SyncMap[string, any]                   // Map to store bot-related data.
+	ChatData  SyncMap[string, *SyncMap[string, any]] // Map to store the loaded chat-related data.
+	context   context.Context                        // Context for running the checkpoint operations.
+	cancelCtx context.CancelFunc                     // Function for stopping checkpoint operations.
+}
+
+const (
+	sqliteCreateBotData  = `CREATE TABLE IF NOT EXISTS chadango_bot_data (name TEXT PRIMARY KEY, data BLOB NOT NULL)`
+	sqliteCreateChatData = `CREATE TABLE IF NOT EXISTS chadango_chat_data (name TEXT PRIMARY KEY, data BLOB NOT NULL)`
+	sqliteSelectBotData  = `SELECT data FROM chadango_bot_data WHERE name = ?`
+	sqliteSelectChatData = `SELECT data FROM chadango_chat_data WHERE name = ?`
+	sqliteUpsertBotData  = `INSERT OR REPLACE INTO chadango_bot_data (name, data) VALUES (?, ?)`
+	sqliteUpsertChatData = `INSERT OR REPLACE INTO chadango_chat_data (name, data) VALUES (?, ?)`
+	sqliteDeleteChatData = `DELETE FROM chadango_chat_data WHERE name = ?`
+
+	sqliteBotDataName = "bot" // The name of the bot data row.
+)
+
+// Initialize creates the tables if absent and loads the bot data.
+//
+// Returns:
+//   - error: An error if initialization fails.
+func (p *SQLitePersistence) Initialize() error {
+	p.BotData = NewSyncMap[string, any]()
+	p.ChatData = NewSyncMap[string, *SyncMap[string, any]]()
+
+	if p.DB == nil {
+		return ErrNoDatabase
+	}
+
+	for _, query := range []string{sqliteCreateBotData, sqliteCreateChatData} {
+		if _, err := p.DB.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return p.load(sqliteSelectBotData, sqliteBotDataName, &p.BotData)
+}
+
+// Runner starts a goroutine that checkpoints the data periodically.
+//
+// Args:
+//   - ctx: The context for running the checkpoint operations.
+func (p *SQLitePersistence) Runner(ctx context.Context) {
+	if p.DB == nil {
+		return
+	}
+
+	p.context, p.cancelCtx = context.WithCancel(ctx)
+
+	if p.Interval.Minutes() < 1 {
+		p.Interval = 30 * time.Minute
+	}
+
+	go p.autoSave()
+}
+
+// Close stops the checkpoint routine, saves the data, and closes the database.
+//
+// Returns:
+//   - error: An error if closing fails.
+func (p *SQLitePersistence) Close() error {
+	if p.cancelCtx != nil {
+		p.cancelCtx()
+	}
+
+	if p.DB == nil {
+		return nil
+	}
+
+	err := p.Save()
+	if err2 := p.DB.Close(); err == nil {
+		err = err2
+	}
+
+	return err
+}
+
+// Save writes the bot data and the loaded chat data to the database in a single transaction.
+//
+// Returns:
+//   - error: An error if saving fails.
+func (p *SQLitePersistence) Save() (err error) {
+	if p.DB == nil {
+		return ErrNoDatabase
+	}
+
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = p.save(tx, sqliteUpsertBotData, sqliteBotDataName, &p.BotData); err != nil {
+		return
+	}
+
+	p.ChatData.Range(func(key string, chatData *SyncMap[string, any]) bool {
+		err = p.save(tx, sqliteUpsertChatData, key, chatData)
+		return err == nil
+	})
+
+	return
+}
+
+// autoSave is a goroutine that periodically saves the data to the database.
+func (p *SQLitePersistence) autoSave() {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			log.Debug().Msg("SQLitePersistence checkpoint.")
+			if err := p.Save(); err != nil {
+				log.Error().Err(err).Msg("SQLitePersistence checkpoint error.")
+			}
+		case <-p.context.Done():
+			return
+		}
+	}
+}
+
+// GetBotData returns a pointer to the BotData.
+//
+// Returns:
+//   - *SyncMap[string, any]: A pointer to the bot-related data.
+func (p *SQLitePersistence) GetBotData() *SyncMap[string, any] {
+	return &p.BotData
+}
+
+// GetChatData returns a pointer to the ChatData for the given key.
+// If the ChatData is not loaded yet, it is loaded from the database, or created if absent.
+//
+// Args:
+//   - key: The key to retrieve the ChatData for.
+//
+// Returns:
+//   - *SyncMap[string, any]: A pointer to the chat-related data for the given key.
+func (p *SQLitePersistence) GetChatData(key string) *SyncMap[string, any] {
+	if chatData, ok := p.ChatData.Get(key); ok {
+		return chatData
+	}
+
+	// Hold the lock while loading, so concurrent callers get the same map.
+	p.ChatData.Lock()
+	defer p.ChatData.Unlock()
+
+	chatData, ok := p.ChatData.M[key]
+	if !ok {
+		chatData = &SyncMap[string, any]{M: map[string]any{}}
+		if p.DB != nil {
+			if err := p.load(sqliteSelectChatData, key, chatData); err != nil {
+				log.Error().Str("Name", key).Err(err).Msg("SQLitePersistence load ChatData error.")
+			}
+		}
+		p.ChatData.M[key] = chatData
+	}
+
+	return chatData
+}
+
+// DelChatData deletes the ChatData for the given key.
+//
+// Args:
+//   - key: The key to delete the ChatData for.
+func (p *SQLitePersistence) DelChatData(key string) {
+	p.ChatData.Del(key)
+
+	if p.DB == nil {
+		return
+	}
+
+	if _, err := p.DB.Exec(sqliteDeleteChatData, key); err != nil {
+		log.Error().Str("Name", key).Err(err).Msg("SQLitePersistence delete ChatData error.")
+	}
+}
+
+// load reads a data row into the map, leaving it untouched if the row is absent.
+func (p *SQLitePersistence) load(query, name string, data *SyncMap[string, any]) error {
+	var blob []byte
+	if err := p.DB.QueryRow(query, name).Scan(&blob); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	return data.GobDecode(blob)
+}
+
+// save writes a data row within the transaction.
+func (p *SQLitePersistence) save(tx *sql.Tx, query, name string, data *SyncMap[string, any]) error {
+	blob, err := data.GobEncode()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(query, name, blob)
+
+	return err
+}
+
+// jsonTypes holds the types registered by [RegisterJSONType], keyed by the type name.
+var jsonTypes = NewSyncMap[string, reflect.Type]()
+
+// RegisterJSONType records the concrete type of the value, so [JSONPersistence] decodes the values of that type back into it.
+//
+// It is the JSON counterpart of [gob.Register]. The values of unregistered types are decoded as the generic JSON types,
+// e.g. map[string]any for objects and float64 for numbers.
+//
+// Args:
+//   - value: A value of the type to register.
+func RegisterJSONType(value any) {
+	t := reflect.TypeOf(value)
+	jsonTypes.Set(t.String(), t)
+}
+
+// jsonData is the on-disk form of the [JSONPersistence] data.
+type jsonData struct {
+	BotData  map[string]json.RawMessage            `json:"botdata"`
+	ChatData map[string]map[string]json.RawMessage `json:"chatdata"`
+}
+
+// JSONPersistence is responsible for loading and saving data to an indented JSON file periodically.
+// If the filename is set to an empty string, it will disable auto-saving.
+// If the interval is set to less than 1 minute, it will be adjusted to 30 minutes.
+//
+// The values of the types registered by [RegisterJSONType] are stored along with their type name,
+// so they are decoded back into the same type.
+type JSONPersistence struct {
+	Filename  string                                 // File name for the data.
+	Interval  time.Duration                          // Interval for auto-saving.
+	BotData   SyncMap[string, any]                   // Map to store bot-related data.
+	ChatData  SyncMap[string, *SyncMap[string, any]] // Map to store chat-related data.
+	context   context.Context                        // Context for running the auto save operations.
+	cancelCtx context.CancelFunc                     // Function for stopping auto save operations.
+}
+
+// Load loads the data from the file into the JSONPersistence struct.
+//
+// Returns:
+//   - error: An error if loading fails.
+func (p *JSONPersistence) Load() error {
+	if p.Filename == "" {
+		return nil
+	}
+
+	raw, err := os.ReadFile(p.Filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var data jsonData
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+
+	p.BotData.M = decodeJSONMap(data.BotData)
+	for key, chatData := range data.ChatData {
+		p.ChatData.Set(key, &SyncMap[string, any]{M: decodeJSONMap(chatData)})
+	}
+
+	return nil
+}
+
+// Save saves the data from the JSONPersistence struct to the file.
+//
+// Returns:
+//   - error: An error if saving fails.
+func (p *JSONPersistence) Save() (err error) {
+	if p.Filename == "" {
+		return nil
+	}
+
+	data := jsonData{ChatData: map[string]map[string]json.RawMessage{}}
+	if data.BotData, err = encodeJSONMap(&p.BotData); err != nil {
+		return
+	}
+
+	p.ChatData.Range(func(key string, chatData *SyncMap[string, any]) bool {
+		data.ChatData[key], err = encodeJSONMap(chatData)
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return os.WriteFile(p.Filename, raw, 0644)
+}
+
+// Initialize initializes the JSONPersistence struct by loading the data from the file.
+//
+// Returns:
+//   - error: An error if initialization fails.
+func (p *JSONPersistence) Initialize() error {
+	p.BotData = NewSyncMap[string, any]()
+	p.ChatData = NewSyncMap[string, *SyncMap[string, any]]()
+
+	return p.Load()
+}
+
+// Runner starts a goroutine that manages the persistence layer.
+//
+// Args:
+//   - ctx: The context for running the auto save operations.
+func (p *JSONPersistence) Runner(ctx context.Context) {
+	if p.Filename == "" {
+		return
+	}
+
+	p.context, p.cancelCtx = context.WithCancel(ctx)
+
+	if p.Interval.Minutes() < 1 {
+		p.Interval = 30 * time.Minute
+	}
+
+	go p.autoSave()
+}
+
+// Close stops the auto save routine and saves the data to the file.
+//
+// Returns:
+//   - error: An error if closing fails.
+func (p *JSONPersistence) Close() error {
+	if p.cancelCtx != nil {
+		p.cancelCtx()
+	}
+
+	return p.Save()
+}
+
+// autoSave is a goroutine that periodically saves the data to the file.
+func (p *JSONPersistence) autoSave() {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			log.Debug().Str("Name", p.Filename).Msg("JSONPersistence auto save.")
+			if err := p.Save(); err != nil {
+				log.Error().Str("Name", p.Filename).Err(err).Msg("JSONPersistence save error.")
+			}
+		case <-p.context.Done():
+			return
+		}
+	}
+}
+
+// GetBotData returns a pointer to the BotData.
+//
+// Returns:
+//   - *SyncMap[string, any]: A pointer to the bot-related data.
+func (p *JSONPersistence) GetBotData() *SyncMap[string, any] {
+	return &p.BotData
+}
+
+// GetChatData returns a pointer to the ChatData for the given key.
+// If the ChatData does not exist, it creates a new one and returns it.
+//
+// Args:
+//   - key: The key to retrieve the ChatData for.
+//
+// Returns:
+//   - *SyncMap[string, any]: A pointer to the chat-related data for the given key.
+func (p *JSONPersistence) GetChatData(key string) *SyncMap[string, any] {
+	chatData, ok := p.ChatData.Get(key)
+	if !ok {
+		chatData = &SyncMap[string, any]{M: map[string]any{}}
+		p.ChatData.Set(key, chatData)
+	}
+
+	return chatData
+}
+
+// DelChatData deletes the ChatData for the given key.
+//
+// Args:
+//   - key: The key to delete the ChatData for.
+func (p *JSONPersistence) DelChatData(key string) {
+	p.ChatData.Del(key)
+}
+
+// encodeJSONMap encodes each value of the map, wrapping the values of the registered types in an object
+// keyed by [JSON_TYPE_KEY] and [JSON_VALUE_KEY].
+func encodeJSONMap(data *SyncMap[string, any]) (map[string]json.RawMessage, error) {
+	data.RLock()
+	defer data.RUnlock()
+
+	encoded := make(map[string]json.RawMessage, len(data.M))
+	for key, value := range data.M {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if value != nil {
+			name := reflect.TypeOf(value).String()
+			if _, ok := jsonTypes.Get(name); ok {
+				typeName, _ := json.Marshal(name)
+				if raw, err = json.Marshal(map[string]json.RawMessage{JSON_TYPE_KEY: typeName, JSON_VALUE_KEY: raw}); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		encoded[key] = raw
+	}
+
+	return encoded, nil
+}
+
+// decodeJSONMap decodes each value of the map, restoring the values of the registered types.
+func decodeJSONMap(encoded map[string]json.RawMessage) map[string]any {
+	data := make(map[string]any, len(encoded))
+	for key, raw := range encoded {
+		data[key] = decodeJSONValue(raw)
+	}
+
+	return data
+}
+
+// decodeJSONValue decodes a value, falling back to the generic JSON types if the type is not registered.
+//
+// Only an object with exactly the [JSON_TYPE_KEY] and [JSON_VALUE_KEY] keys is taken as a typed value,
+// so the user objects are never mistaken for one.
+func decodeJSONValue(raw json.RawMessage) (value any) {
+	var envelope map[string]json.RawMessage
+	if json.Unmarshal(raw, &envelope) == nil && len(envelope) == 2 {
+		var name string
+		typeName, hasType := envelope[JSON_TYPE_KEY]
+		typedValue, hasValue := envelope[JSON_VALUE_KEY]
+		if hasType && hasValue && json.Unmarshal(typeName, &name) == nil {
+			if t, ok := jsonTypes.Get(name); ok {
+				ptr := reflect.New(t)
+				if json.Unmarshal(typedValue, ptr.Interface()) == nil {
+					return ptr.Elem().Interface()
+				}
+			}
+		}
+	}
+
+	json.Unmarshal(raw, &value)
+
+	return
+}
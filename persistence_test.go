@@ -0,0 +1,167 @@
+package chadango
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryDriver is a minimal [driver.Driver] understanding the queries of [SQLitePersistence].
+type memoryDriver struct {
+	sync.Mutex
+	tables map[string]map[string][]byte
+}
+
+func (d *memoryDriver) Open(string) (driver.Conn, error) { return &memoryConn{d}, nil }
+
+type memoryConn struct{ d *memoryDriver }
+
+func (c *memoryConn) Prepare(query string) (driver.Stmt, error) { return &memoryStmt{c.d, query}, nil }
+func (c *memoryConn) Close() error                              { return nil }
+func (c *memoryConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *memoryConn) Commit() error                             { return nil }
+func (c *memoryConn) Rollback() error                           { return nil }
+
+type memoryStmt struct {
+	d     *memoryDriver
+	query string
+}
+
+func (s *memoryStmt) Close() error  { return nil }
+func (s *memoryStmt) NumInput() int { return -1 }
+
+func (s *memoryStmt) table() map[string][]byte {
+	name := "chadango_chat_data"
+	if strings.Contains(s.query, "chadango_bot_data") {
+		name = "chadango_bot_data"
+	}
+	if s.d.tables[name] == nil {
+		s.d.tables[name] = map[string][]byte{}
+	}
+	return s.d.tables[name]
+}
+
+func (s *memoryStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.Lock()
+	defer s.d.Unlock()
+
+	switch {
+	case strings.Contains(s.query, "INSERT"):
+		s.table()[args[0].(string)] = append([]byte(nil), args[1].([]byte)...)
+	case strings.Contains(s.query, "DELETE"):
+		delete(s.table(), args[0].(string))
+	default:
+		s.table()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *memoryStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.Lock()
+	defer s.d.Unlock()
+
+	blob, ok := s.table()[args[0].(string)]
+	return &memoryRows{blob: blob, done: !ok}, nil
+}
+
+type memoryRows struct {
+	blob []byte
+	done bool
+}
+
+func (r *memoryRows) Columns() []string { return []string{"data"} }
+func (r *memoryRows) Close() error      { return nil }
+
+func (r *memoryRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.blob
+	return nil
+}
+
+var memoryDB = &memoryDriver{tables: map[string]map[string][]byte{}}
+
+func init() {
+	sql.Register("chadango-memory", memoryDB)
+}
+
+func TestSQLitePersistence(t *testing.T) {
+	open := func() *SQLitePersistence {
+		db, err := sql.Open("chadango-memory", "")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		p := &SQLitePersistence{DB: db}
+		assert.NoError(t, p.Initialize())
+		return p
+	}
+
+	p := open()
+	p.GetBotData().Set("greeting", "hello")
+	p.GetChatData("chat1").Set("count", 3)
+	p.GetChatData("chat2").Set("count", 5)
+	assert.Same(t, p.GetChatData("chat1"), p.GetChatData("chat1"), "The loaded chat data should be cached")
+	p.DelChatData("chat2")
+	assert.NoError(t, p.Close())
+
+	p = open()
+	greeting, _ := p.GetBotData().Get("greeting")
+	assert.Equal(t, "hello", greeting)
+	assert.Zero(t, p.ChatData.Len(), "The chat data should be loaded lazily")
+	count, _ := p.GetChatData("chat1").Get("count")
+	assert.Equal(t, 3, count)
+	_, ok := p.GetChatData("chat2").Get("count")
+	assert.False(t, ok, "The deleted chat data should not be restored")
+	assert.NoError(t, p.Close())
+
+	assert.ErrorIs(t, (&SQLitePersistence{}).Initialize(), ErrNoDatabase)
+}
+
+type jsonTestProfile struct {
+	Name  string
+	Score int
+}
+
+func TestJSONPersistence(t *testing.T) {
+	RegisterJSONType(jsonTestProfile{})
+
+	filename := filepath.Join(t.TempDir(), "data.json")
+
+	p := &JSONPersistence{Filename: filename}
+	assert.NoError(t, p.Initialize(), "A missing file should not fail")
+	p.GetBotData().Set("greeting", "hello")
+	p.GetBotData().Set("profile", jsonTestProfile{"nekonyan", 42})
+	p.GetBotData().Set("settings", map[string]any{"prefix": "!"})
+	p.GetBotData().Set("lookalike", map[string]any{"type": "chadango.jsonTestProfile", "value": map[string]any{"Name": "mimic"}})
+	p.GetChatData("chat1").Set("count", 3)
+	assert.NoError(t, p.Close())
+
+	p = &JSONPersistence{Filename: filename}
+	assert.NoError(t, p.Initialize())
+
+	greeting, _ := p.GetBotData().Get("greeting")
+	assert.Equal(t, "hello", greeting)
+	profile, _ := p.GetBotData().Get("profile")
+	assert.Equal(t, jsonTestProfile{"nekonyan", 42}, profile, "The registered type should be restored")
+	settings, _ := p.GetBotData().Get("settings")
+	assert.Equal(t, map[string]any{"prefix": "!"}, settings)
+	lookalike, _ := p.GetBotData().Get("lookalike")
+	assert.Equal(t, map[string]any{"type": "chadango.jsonTestProfile", "value": map[string]any{"Name": "mimic"}}, lookalike, "A user object should not be taken for a typed value")
+	count, _ := p.GetChatData("chat1").Get("count")
+	assert.Equal(t, float64(3), count, "The unregistered types should fall back to the generic JSON types")
+
+	p = &JSONPersistence{}
+	assert.NoError(t, p.Initialize())
+	p.Runner(context.Background())
+	assert.Nil(t, p.cancelCtx, "The auto save should be disabled without a filename")
+	assert.NoError(t, p.Close())
+}
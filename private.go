@@ -3,7 +3,10 @@ package chadango
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/n0h4rt/chadango/models"
@@ -23,14 +26,23 @@ type Private struct {
 	TextFont  string       // The font style for displaying text in the message.
 	TextSize  int          // The font size for displaying text in the message.
 
-	WsUrl     string               // The WebSocket URL for connecting to the PM server.
-	ws        *WebSocket           // The WebSocket connection to the PM server.
-	Connected bool                 // Indicates if the PM server is currently connected.
-	events    chan string          // Channel for propagating events back to the listener.
-	takeOver  chan context.Context // Channel for taking over the WebSocket connection.
-	backoff   *Backoff             // Cancelable backoff for reconnection.
-	context   context.Context      // Context for running the private chat operations.
-	cancelCtx context.CancelFunc   // Function for stopping private chat operations.
+	style atomic.Pointer[models.MessageStyle] // The style set by [Private.SetStyle], overriding the style copied from the [Config].
+
+	WsUrl        string               // The WebSocket URL for connecting to the PM server.
+	ws           *WebSocket           // The WebSocket connection to the PM server.
+	Connected    bool                 // Indicates if the PM server is currently connected.
+	events       chan string          // Channel for propagating events back to the listener.
+	takeOver     chan context.Context // Channel for taking over the WebSocket connection.
+	noRetry      bool                 // Indicates if the auto-reconnect is suspended, guarded by [Private.stateMu].
+	reconnecting context.CancelFunc   // Cancels the ongoing [Private.Reconnect], guarded by [Private.stateMu].
+	stateMu      sync.Mutex           // Guards the connected state transitions.
+	context      context.Context      // Context for running the private chat operations.
+	cancelCtx    context.CancelFunc   // Function for stopping private chat operations.
+
+	BackoffBase   time.Duration // The initial reconnect backoff, defaults to [BASE_BACKOFF_DUR] if not positive.
+	BackoffMax    time.Duration // The maximum reconnect backoff, defaults to [MAX_BACKOFF_DUR] if not positive.
+	BackoffJitter float64       // The reconnect backoff jitter, see [Backoff.Jitter].
+	MaxRetries    int           // The maximum reconnect attempts, defaults to the application's [Config.MaxRetry] if not positive.
 
 	token     string        // The auth token used to connect to the PM server.
 	LoginName string        // The login name of the user.
@@ -38,8 +50,12 @@ type Private struct {
 	LoginTime time.Time     // The time when the user logged in.
 	TimeDiff  time.Duration // The time difference between the server and client (serverTime - clientTime).
 
-	idleTimer *time.Timer // The timer for the idle command.
-	IsIdle    bool        // Indicates whether there has been no activity within 1 minute (e.g., sending a message).
+	idleMu      sync.Mutex    // Guards the [Private.idleTimer] and [Private.idleTimeout].
+	idleTimer   *time.Timer   // The timer for the idle command.
+	idleTimeout time.Duration // The inactivity duration before going idle, see [Private.SetIdleTimeout].
+	isIdle      atomic.Bool   // Indicates whether there has been no activity within the idle timeout, see [Private.IsIdle].
+	typing      typingMarks   // The recently sent typing states, see [Private.SendTyping].
+	contacts    contactMarks  // The users who have sent a private message, see [OnPrivateFriendRequest].
 }
 
 // Connect establishes a connection to the server.
@@ -67,13 +83,15 @@ func (p *Private) Connect(ctx context.Context) (err error) {
 		}
 	}()
 
-	err = p.connect()
+	err = p.connect(p.context)
 	if err != nil {
 		p.cancelCtx()
 		return
 	}
 
+	p.stateMu.Lock()
 	p.Connected = true
+	p.stateMu.Unlock()
 
 	log.Debug().Str("Name", p.Name).Msg("Connected")
 
@@ -87,23 +105,27 @@ func (p *Private) Connect(ctx context.Context) (err error) {
 // The function attempts to send a login request and waits for an "OK" or "DENIED"
 // response within 5 attempts. If the login is successful, it sustains the connection
 // and starts listening for incoming events.
+// The established connection lives within the private context, regardless of [ctx].
+//
+// Args:
+//   - ctx: The context bounding the handshake, e.g. canceled by [Private.CancelReconnect].
 //
 // Returns:
 //   - error: An error if the connection cannot be established.
-func (p *Private) connect() (err error) {
-	if err = privateAPI.Login(); err != nil {
+func (p *Private) connect(ctx context.Context) (err error) {
+	if err = p.App.privateAPI.Login(); err != nil {
 		return
 	}
 
 	var ok bool
-	if p.token, ok = privateAPI.GetCookie("auth.chatango.com"); !ok {
+	if p.token, ok = p.App.privateAPI.GetCookie("auth.chatango.com"); !ok {
 		return ErrLoginFailed
 	}
 
 	p.ws = &WebSocket{
 		OnError: p.wsOnError,
 	}
-	if err = p.ws.Connect(p.WsUrl); err != nil {
+	if err = p.ws.ConnectContext(ctx, p.WsUrl); err != nil {
 		return
 	}
 
@@ -138,6 +160,12 @@ func (p *Private) connect() (err error) {
 	return ErrBadLogin
 
 OK:
+	// Do not start a connection that is no longer wanted.
+	if err = ctx.Err(); err != nil {
+		p.ws.Close()
+		return
+	}
+
 	p.ws.Sustain(p.context)
 	go p.listen()
 
@@ -187,46 +215,102 @@ func (p *Private) listen() {
 
 // Disconnect gracefully closes the connection to the PM server.
 func (p *Private) Disconnect() {
-	if p.backoff != nil {
-		p.backoff.Cancel()
-	}
+	p.CancelReconnect()
 
+	p.stateMu.Lock()
 	if !p.Connected {
+		p.stateMu.Unlock()
 		return
 	}
-
 	p.Connected = false
+	p.stateMu.Unlock()
+
 	p.cancelCtx()
 	p.ws.Close()
 }
 
+// SuspendReconnect suspends the auto-reconnect.
+//
+// While suspended, a dropped connection is not retried and the [OnPrivateDisconnected] event is dispatched immediately.
+// This is useful for a controlled shutdown or a planned maintenance.
+func (p *Private) SuspendReconnect() {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+
+	p.noRetry = true
+}
+
+// ResumeReconnect resumes the auto-reconnect suspended by [Private.SuspendReconnect].
+func (p *Private) ResumeReconnect() {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+
+	p.noRetry = false
+}
+
 // Reconnect attempts to reconnect to the PM server.
 //
+// The attempts run under a context derived from the private context,
+// so [Private.CancelReconnect] or [Private.Disconnect] ends them promptly with [ErrRetryEnds].
+// Each attempt logs in again with the existing API client of the application, which is shared with the other users of it.
+// If all the attempts fail, the handler set by [Application.SetReconnectGiveUpHandler] is called.
+//
 // Returns:
 //   - error: An error if the reconnection fails.
 func (p *Private) Reconnect() (err error) {
 	p.ws.Close()
 
-	// Reinitialize the API.
-	initAPI(p.App.Config.Username, p.App.Config.Password, p.App.context)
-
-	p.backoff = &Backoff{
-		Duration:    BASE_BACKOFF_DUR,
-		MaxDuration: MAX_BACKOFF_DUR,
-	}
+	ctx, cancel := context.WithCancel(p.context)
+	p.stateMu.Lock()
+	p.reconnecting = cancel
+	p.stateMu.Unlock()
 	defer func() {
-		p.backoff = nil
+		p.stateMu.Lock()
+		p.reconnecting = nil
+		p.stateMu.Unlock()
+		cancel()
 	}()
-	for retries := 0; retries < MAX_RETRIES && !p.backoff.Sleep(p.context); retries++ {
-		if err = p.connect(); err == nil {
+
+	backoff := newBackoff(p.BackoffBase, p.BackoffMax, p.BackoffJitter)
+	maxRetries := p.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = p.App.maxRetries()
+	}
+	retries := 0
+	for ; retries < maxRetries && !backoff.Sleep(ctx); retries++ {
+		err = p.connect(ctx)
+		if err == nil && ctx.Err() == nil {
 			return
 		}
+		if ctx.Err() != nil {
+			// Canceled while connecting, do not count it as a give up.
+			return ErrRetryEnds
+		}
 	}
 
 	// Either canceled or reached the maximum retries.
+	if retries == maxRetries {
+		p.App.reconnectGaveUp(p.Name, true, retries)
+	}
+
 	return ErrRetryEnds
 }
 
+// CancelReconnect cancels the ongoing reconnection to the PM server, if any.
+//
+// The pending backoff and the connection attempt in progress are interrupted,
+// and [Private.Reconnect] returns [ErrRetryEnds] without calling the give-up handler.
+// An automatic reconnection then ends like a failed one, i.e. [OnPrivateDisconnected] is dispatched.
+func (p *Private) CancelReconnect() {
+	p.stateMu.Lock()
+	cancel := p.reconnecting
+	p.stateMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
 // Send will join the [args] with a ":" separator and then send it to the server asynchronously.
 //
 // Note:
@@ -239,7 +323,7 @@ func (p *Private) Reconnect() (err error) {
 // Returns:
 //   - error: An error if the sending fails.
 func (p *Private) Send(args ...string) error {
-	if !p.ws.Connected {
+	if !p.ws.IsConnected() {
 		return ErrNotConnected
 	}
 
@@ -270,10 +354,19 @@ func (p *Private) SyncSendWithTimeout(callback func(string) bool, timeout time.D
 	ctx, cancel := context.WithTimeout(p.context, timeout)
 	defer cancel()
 
+	return p.syncSend(ctx, callback, args...)
+}
+
+// syncSend sends the specified arguments and waits for a response until the context or the private context is done.
+//
+// See [Private.SyncSendWithTimeout].
+func (p *Private) syncSend(ctx context.Context, callback func(string) bool, args ...string) (err error) {
 	// Make a takeover request to allow the listener to relinquish the connection.
 	select {
 	case <-ctx.Done():
 		return ErrTimeout
+	case <-p.context.Done():
+		return ErrTimeout
 	case p.takeOver <- ctx:
 	}
 
@@ -287,6 +380,8 @@ func (p *Private) SyncSendWithTimeout(callback func(string) bool, timeout time.D
 		select {
 		case <-ctx.Done():
 			return ErrTimeout
+		case <-p.context.Done():
+			return ErrTimeout
 		case frame, ok = <-p.ws.Events:
 			if !ok {
 				close(p.events)
@@ -311,6 +406,89 @@ func (p *Private) SyncSend(cb func(string) bool, args ...string) error {
 	return p.SyncSendWithTimeout(cb, SYNC_SEND_TIMEOUT, args...)
 }
 
+// Ping sends a keepalive to the PM server and measures the round-trip until the pong is received.
+//
+// It is useful for health checks on the PM connection, independent of the group connections.
+// The wait is bounded by [ctx], and by [SYNC_SEND_TIMEOUT] at most.
+//
+// Args:
+//   - ctx: The context bounding the wait.
+//
+// Returns:
+//   - time.Duration: The round-trip time.
+//   - error: [ErrTimeout] if no pong is received in time or [ctx] is done, or an error if the sending fails.
+func (p *Private) Ping(ctx context.Context) (rtt time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(ctx, SYNC_SEND_TIMEOUT)
+	defer cancel()
+
+	start := time.Now()
+	cb := func(frame string) bool {
+		if frame == "" {
+			// pong
+			rtt = time.Since(start)
+			return false
+		}
+		p.events <- frame
+		return true
+	}
+
+	err = p.syncSend(ctx, cb, "\r\n")
+
+	return
+}
+
+// SetStyle sets the style of the private messages, independently of the group messages.
+//
+// Args:
+//   - style: The style to apply, only the colors, font, size, and text decorations are used.
+func (p *Private) SetStyle(style models.MessageStyle) {
+	p.style.Store(&style)
+}
+
+// Style returns the style of the private messages.
+//
+// If no style is set by [Private.SetStyle], it is derived from the style copied from the [Config].
+//
+// Returns:
+//   - models.MessageStyle: The style of the private messages.
+func (p *Private) Style() models.MessageStyle {
+	if style := p.style.Load(); style != nil {
+		return *style
+	}
+
+	return models.MessageStyle{
+		NameColor:  p.NameColor,
+		TextColor:  p.TextColor,
+		FontFamily: p.TextFont,
+		FontSize:   strconv.Itoa(p.TextSize),
+		StylesOn:   true,
+	}
+}
+
+// styleText applies the [Private.Style] to the message text, and replaces the newlines with the `<br/>` tags.
+func (p *Private) styleText(text string) string {
+	style := p.Style()
+
+	if style.Bold {
+		text = "<b>" + text + "</b>"
+	}
+	if style.Italics {
+		text = "<i>" + text + "</i>"
+	}
+	if style.Underline {
+		text = "<u>" + text + "</u>"
+	}
+
+	size, _ := strconv.Atoi(style.FontSize)
+	text = fmt.Sprintf(`<n%s/><m v="1"><g x%02ds%s="%s">%s</g></m>`, style.NameColor, size, style.TextColor, style.FontFamily, text)
+
+	// Replacing newlines with the `<br/>` tag.
+	text = strings.ReplaceAll(text, "\r\n", "<br/>")
+	text = strings.ReplaceAll(text, "\n", "<br/>")
+
+	return text
+}
+
 // SendMessage sends a private message to the specified username with the given text and optional arguments.
 //
 // It returns an error if any occurs during the message sending process.
@@ -348,12 +526,7 @@ func (p *Private) SendMessage(username, text string, a ...any) (err error) {
 
 	username = strings.ToLower(username)
 
-	text = fmt.Sprintf(text, a...)
-	text = fmt.Sprintf(`<n%s/><m v="1"><g x%02ds%s="%s">%s</g></m>`, p.NameColor, p.TextSize, p.TextColor, p.TextFont, text)
-
-	// Replacing newlines with the `<br/>` tag.
-	text = strings.ReplaceAll(text, "\r\n", "<br/>")
-	text = strings.ReplaceAll(text, "\n", "<br/>")
+	text = p.styleText(fmt.Sprintf(text, a...))
 
 	// The "msg" command does not produce a response, so we wait for 0.5 seconds to handle any potential errors that may occur.
 	// The potential errors include "show_fw", "toofast", and "show_offline_limit".
@@ -369,8 +542,22 @@ func (p *Private) SendMessage(username, text string, a ...any) (err error) {
 	return
 }
 
+// SendImage sends the uploaded image to the specified username, see [PrivateAPI.UploadImage].
+//
+// Args:
+//   - username: The username to send the image to.
+//   - img: The uploaded image.
+//
+// Returns:
+//   - error: An error if any occurs during the message sending process.
+func (p *Private) SendImage(username string, img models.UploadedImage) error {
+	return p.SendMessage(username, "%s", img.MessageEmbed())
+}
+
 // Track retrieves the online status of the username.
 //
+// Subsequent status changes of the tracked user are dispatched as [OnPrivateUserStatus] events.
+//
 // Args:
 //   - username: The username to track.
 //
@@ -382,16 +569,7 @@ func (p *Private) Track(username string) (status models.UserStatus, err error) {
 		head, data, _ := strings.Cut(frame, ":")
 		switch head {
 		case "track":
-			fields := strings.SplitN(data, ":", 3)
-			status.User = &models.User{Name: fields[0]}
-			status.Info = fields[2]
-			switch fields[2] {
-			case "offline":
-				status.Time, _ = utils.ParseTime(fields[1])
-			case "online", "app":
-				status.Idle, _ = time.ParseDuration(fields[1] + "m")
-			case "invalid":
-			}
+			status = parseUserStatus(data)
 			return false
 		default:
 			p.events <- frame
@@ -493,6 +671,7 @@ func (p *Private) GetFriendList() (friendlist []models.UserStatus, err error) {
 					status.Idle, _ = time.ParseDuration(fields[i+3] + "m")
 				}
 				friendlist = append(friendlist, status)
+				p.contacts.befriend(status.User.Name, true)
 			}
 			return false
 		default:
@@ -521,6 +700,7 @@ func (p *Private) AddFriend(username string) (status models.UserStatus, err erro
 		case "wladd":
 			fields := strings.SplitN(data, ":", 3)
 			status.User = &models.User{Name: fields[0]}
+			p.contacts.befriend(fields[0], true)
 			switch fields[1] {
 			case "off":
 				status.Info = "offline"
@@ -544,6 +724,53 @@ func (p *Private) AddFriend(username string) (status models.UserStatus, err erro
 	return
 }
 
+// ImportFriends adds many usernames to the friend list.
+//
+// The usernames are added one by one with an interval of [FRIEND_ADD_INTERVAL] to avoid being rate limited.
+// A failure on one username does not stop the import.
+//
+// Args:
+//   - usernames: The usernames to add.
+//
+// Returns:
+//   - map[string]error: The result for each username, nil if the username was added successfully.
+//   - error: An error if the import is interrupted.
+func (p *Private) ImportFriends(usernames []string) (results map[string]error, err error) {
+	results = make(map[string]error, len(usernames))
+
+	for i, username := range usernames {
+		if i > 0 {
+			select {
+			case <-p.context.Done():
+				return results, ErrConnectionClosed
+			case <-time.After(FRIEND_ADD_INTERVAL):
+			}
+		}
+
+		_, results[username] = p.AddFriend(username)
+	}
+
+	return
+}
+
+// ExportFriends returns the usernames in the friend list.
+//
+// Returns:
+//   - []string: The usernames in the friend list.
+//   - error: An error if the friend list cannot be retrieved.
+func (p *Private) ExportFriends() (usernames []string, err error) {
+	var friendlist []models.UserStatus
+	if friendlist, err = p.GetFriendList(); err != nil {
+		return
+	}
+
+	for _, status := range friendlist {
+		usernames = append(usernames, status.User.Name)
+	}
+
+	return
+}
+
 // RemoveFriend removes the username from the friend list.
 //
 // Args:
@@ -563,7 +790,9 @@ func (p *Private) RemoveFriend(username string) (err error) {
 		return true
 	}
 
-	err = p.SyncSend(cb, "wldelete", username, "\r\n")
+	if err = p.SyncSend(cb, "wldelete", username, "\r\n"); err == nil {
+		p.contacts.befriend(username, false)
+	}
 
 	return
 }
@@ -675,6 +904,133 @@ func (p *Private) ConnectUser(username string) (status models.UserStatus, err er
 	return
 }
 
+// SendTyping notifies the username that the current user starts or stops typing.
+//
+// Repeating the same state to the same username within [TYPING_DEBOUNCE] is skipped,
+// so it is safe to call on every keystroke.
+//
+// Args:
+//   - username: The username to notify.
+//   - typing: True if typing, false if stopped typing.
+//
+// Returns:
+//   - error: An error if the operation fails.
+func (p *Private) SendTyping(username string, typing bool) error {
+	username = strings.ToLower(username)
+	if !p.typing.shouldSend(username, typing, time.Now()) {
+		return nil
+	}
+
+	return p.Send("typing", username, utils.BoolZeroOrOne(typing), "\r\n")
+}
+
+// typingMarks holds the recently sent typing state of each username.
+type typingMarks struct {
+	sync.Mutex
+	marks map[string]typingMark
+}
+
+// typingMark is a sent typing state.
+type typingMark struct {
+	typing bool
+	time   time.Time
+}
+
+// shouldSend reports whether the typing state should be sent, and records it if so.
+func (m *typingMarks) shouldSend(username string, typing bool, now time.Time) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if mark, ok := m.marks[username]; ok && mark.typing == typing && now.Sub(mark.time) < TYPING_DEBOUNCE {
+		return false
+	}
+
+	if m.marks == nil {
+		m.marks = map[string]typingMark{}
+	}
+	m.marks[username] = typingMark{typing, now}
+
+	return true
+}
+
+// contactMarks records the users who have sent a private message, and the known friends.
+type contactMarks struct {
+	sync.Mutex
+	recent  idWindow            // The recent contacts, bounded to [CONTACT_MARKS_SIZE].
+	friends map[string]struct{} // The friends seen in the friend list frames, they are never a first contact.
+}
+
+// first reports whether it is the first contact of the username, and records it if so.
+func (m *contactMarks) first(username string) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	username = strings.ToLower(username)
+	if _, ok := m.friends[username]; ok {
+		return false
+	}
+
+	if m.recent.order == nil {
+		m.recent.reset(true, CONTACT_MARKS_SIZE)
+	}
+
+	return !m.recent.seen(username)
+}
+
+// befriend records the username as a friend, or forgets it if [friend] is false.
+func (m *contactMarks) befriend(username string, friend bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	username = strings.ToLower(username)
+	if !friend {
+		delete(m.friends, username)
+		return
+	}
+
+	if m.friends == nil {
+		m.friends = map[string]struct{}{}
+	}
+	m.friends[username] = struct{}{}
+}
+
+// GetHistory retrieves the recent conversation history with the username.
+//
+// The server sends the history as "msg" frames, terminated by either "gotmore" or "nomore".
+// The history is not dispatched as [OnPrivateMessage] events.
+//
+// Args:
+//   - username: The username of the conversation.
+//   - amount: The number of history messages to fetch.
+//
+// Returns:
+//   - []*Message: The history messages, empty if there is none.
+//   - error: An error if the operation fails.
+func (p *Private) GetHistory(username string, amount int) (messages []*Message, err error) {
+	messages = []*Message{}
+	cb := func(frame string) bool {
+		head, data, _ := strings.Cut(frame, ":")
+		switch head {
+		case "msg", "msgoff":
+			// A live message from another user may arrive meanwhile.
+			if sender, _, _ := strings.Cut(data, ":"); !strings.EqualFold(sender, username) && !strings.EqualFold(sender, p.LoginName) {
+				p.events <- frame
+				break
+			}
+			messages = append(messages, ParsePrivateMessage(data, p))
+		case "gotmore", "nomore":
+			return false
+		default:
+			p.events <- frame
+		}
+		return true
+	}
+
+	err = p.SyncSend(cb, "get_more", strings.ToLower(username), strconv.Itoa(amount), "\r\n")
+
+	return
+}
+
 // DisconnectUser closes the chat session with the username.
 //
 // Args:
@@ -746,34 +1102,106 @@ func (p *Private) ProfileRefresh() error {
 	return p.SyncSend(cb, "miu", "\r\n")
 }
 
+// SetIdleTimeout sets the inactivity duration before the user goes idle.
+//
+// It takes effect on the next activity, see [Private.WentActive].
+//
+// Args:
+//   - d: The idle timeout, raised to [MIN_IDLE_TIMEOUT] if lower.
+func (p *Private) SetIdleTimeout(d time.Duration) {
+	if d < MIN_IDLE_TIMEOUT {
+		d = MIN_IDLE_TIMEOUT
+	}
+	p.idleMu.Lock()
+	p.idleTimeout = d
+	p.idleMu.Unlock()
+}
+
+// getIdleTimeout returns the idle timeout, defaults to [IDLE_TIMEOUT] if not set.
+func (p *Private) getIdleTimeout() time.Duration {
+	p.idleMu.Lock()
+	defer p.idleMu.Unlock()
+
+	return p.idleTimeoutLocked()
+}
+
+// idleTimeoutLocked is [Private.getIdleTimeout] for the callers holding [Private.idleMu].
+func (p *Private) idleTimeoutLocked() time.Duration {
+	if p.idleTimeout > 0 {
+		return p.idleTimeout
+	}
+
+	return IDLE_TIMEOUT
+}
+
+// startIdleTimer (re)starts the idle timer with the configured idle timeout.
+func (p *Private) startIdleTimer() {
+	p.idleMu.Lock()
+	defer p.idleMu.Unlock()
+
+	if p.idleTimer != nil {
+		p.idleTimer.Stop()
+	}
+	p.idleTimer = time.AfterFunc(p.idleTimeoutLocked(), func() { p.WentIdle() })
+}
+
+// IsIdle checks whether there has been no activity within the idle timeout (e.g., sending a message).
+//
+// Returns:
+//   - bool: True if the user is idle, otherwise false.
+func (p *Private) IsIdle() bool {
+	return p.isIdle.Load()
+}
+
 // WentIdle notifies the server that the user went idle.
 //
+// The [OnPrivateSelfIdle] event is dispatched if the user was active.
+//
 // Returns:
 //   - error: An error if the operation fails.
 func (p *Private) WentIdle() error {
-	p.IsIdle = true
-	return p.Send("idle", "0", "\r\n")
+	// The timer goroutine may race with [Private.WentActive], so the state is swapped atomically.
+	wasIdle := p.isIdle.Swap(true)
+	if err := p.Send("idle", "0", "\r\n"); err != nil {
+		return err
+	}
+
+	if !wasIdle {
+		event := &Event{
+			Type:      OnPrivateSelfIdle,
+			Private:   p,
+			IsPrivate: true,
+		}
+		p.App.dispatchEvent(event)
+	}
+
+	return nil
 }
 
-// WentActive notifies the server that the user went active.
+// WentActive notifies the server that the user went active, and restarts the idle timer.
+//
+// The [OnPrivateSelfActive] event is dispatched if the user was idle.
 //
 // Returns:
 //   - error: An error if the operation fails.
 func (p *Private) WentActive() (err error) {
-	// Stops the previous timer.
-	if !p.idleTimer.Stop() {
-		// It appears that the callback has already been fired. Draining the channel.
-		<-p.idleTimer.C
-	}
-	if p.IsIdle {
+	if p.isIdle.Load() {
 		err = p.Send("idle", "1", "\r\n")
 		if err != nil {
 			return
 		}
-		p.IsIdle = false
+
+		if p.isIdle.CompareAndSwap(true, false) {
+			event := &Event{
+				Type:      OnPrivateSelfActive,
+				Private:   p,
+				IsPrivate: true,
+			}
+			p.App.dispatchEvent(event)
+		}
 	}
-	// Setup a new idle timer.
-	p.idleTimer = time.AfterFunc(60*time.Second, func() { p.WentIdle() })
+
+	p.startIdleTimer()
 
 	return
 }
@@ -806,7 +1234,12 @@ func (p *Private) GetContext() context.Context {
 func (p *Private) wsOnError(err error) {
 	close(p.events)
 	close(p.takeOver)
-	if p.Connected {
+
+	p.stateMu.Lock()
+	retry := p.Connected && !p.noRetry
+	p.stateMu.Unlock()
+
+	if retry {
 		if p.Reconnect() == nil {
 			log.Debug().Str("Name", p.Name).Msg("Reconnected")
 
@@ -877,13 +1310,17 @@ func (p *Private) wsOnFrame(frame string) {
 		p.eventIdleUpdate(data)
 	case "miu":
 		p.eventUpdateUserProfile(data)
+	case "status":
+		p.eventUserStatus(data)
+	case "typing":
+		p.eventFriendTyping(data)
 	case "show_fw", "toofast", "show_offline_limit":
 		fallthrough
 	case "track", "settings", "wl", "wladd", "wldelete":
 		fallthrough
 	case "block_list", "blocked", "unblocked":
 		fallthrough
-	case "connect", "presence":
+	case "connect", "presence", "gotmore", "nomore":
 		// This occurs when the [Private.SyncSendWithTimeout] fails to capture these events.
 		// I'm leaving this here for debugging purposes.
 		log.Debug().Str("Name", p.Name).Str("Frame", frame).Msg("Uncaptured")
@@ -892,7 +1329,6 @@ func (p *Private) wsOnFrame(frame string) {
 		// "reload_profile", Similar to "miu"?
 		// "firstlogin", It is possible that the event is triggered after logging into a newly created or purchased account.
 		// "lowversion", This event might be utilized by the message catcher that sends the "version" command to the server.
-		// "status", Similar to "track"?
 		log.Debug().Str("Name", p.Name).Str("Frame", frame).Msg("Unknown")
 	}
 }
@@ -909,8 +1345,8 @@ func (p *Private) eventServerTime(data string) {
 //
 // It dispatches the [OnPrivateConnected] event and sets up an idle timer.
 func (p *Private) eventOK() {
-	// Send the idle command 1 minute after the connection is established.
-	p.idleTimer = time.AfterFunc(60*time.Second, func() { p.WentIdle() })
+	// Send the idle command once the idle timeout has passed after the connection is established.
+	p.startIdleTimer()
 
 	// if p.App.Config.EnableBG {
 	// 	go p.SetBackground(true)
@@ -946,6 +1382,7 @@ func (p *Private) eventKickedOff() {
 // eventMessage handles the message event.
 func (p *Private) eventMessage(data string) {
 	message := ParsePrivateMessage(data, p)
+	p.firstContact(message.User)
 
 	event := &Event{
 		Type:      OnPrivateMessage,
@@ -960,6 +1397,7 @@ func (p *Private) eventMessage(data string) {
 // eventOfflineMessage handles the offline message event.
 func (p *Private) eventOfflineMessage(data string) {
 	message := ParsePrivateMessage(data, p)
+	p.firstContact(message.User)
 
 	event := &Event{
 		Type:      OnPrivateOfflineMessage,
@@ -971,9 +1409,43 @@ func (p *Private) eventOfflineMessage(data string) {
 	p.App.dispatchEvent(event)
 }
 
+// firstContact dispatches the [OnPrivateFriendRequest] event upon the first private message of the user,
+// and adds the user to the friend list if [Config.AutoAddFriends] is set.
+// The known friends are skipped, see [contactMarks].
+func (p *Private) firstContact(user *models.User) {
+	if user == nil || strings.EqualFold(user.Name, p.LoginName) || !p.contacts.first(user.Name) {
+		return
+	}
+
+	event := &Event{
+		Type:      OnPrivateFriendRequest,
+		Private:   p,
+		IsPrivate: true,
+		User:      user,
+	}
+	p.App.dispatchEvent(event)
+
+	if !p.App.Config.AutoAddFriends {
+		return
+	}
+
+	if user.IsAnon {
+		log.Debug().Str("Name", p.Name).Str("User", user.Name).Msg("Anonymous users cannot be friends")
+		return
+	}
+
+	// Adding a friend waits for the response, which must not block the listener.
+	go func() {
+		if _, err := p.AddFriend(user.Name); err != nil {
+			log.Debug().Str("Name", p.Name).Str("User", user.Name).Err(err).Msg("Auto add friend failed")
+		}
+	}()
+}
+
 // eventFriendOnline handles the friend online event.
 func (p *Private) eventFriendOnline(data string) {
 	username, _, _ := strings.Cut(data, ":")
+	p.contacts.befriend(username, true)
 
 	event := &Event{
 		Type:      OnPrivateFriendOnline,
@@ -987,6 +1459,7 @@ func (p *Private) eventFriendOnline(data string) {
 // eventFriendOnlineApp handles the friend online (app) event.
 func (p *Private) eventFriendOnlineApp(data string) {
 	username, _, _ := strings.Cut(data, ":")
+	p.contacts.befriend(username, true)
 
 	event := &Event{
 		Type:      OnPrivateFriendOnlineApp,
@@ -1000,6 +1473,7 @@ func (p *Private) eventFriendOnlineApp(data string) {
 // eventFriendOffline handles the friend offline event.
 func (p *Private) eventFriendOffline(data string) {
 	username, _, _ := strings.Cut(data, ":")
+	p.contacts.befriend(username, true)
 
 	event := &Event{
 		Type:      OnPrivateFriendOffline,
@@ -1043,3 +1517,63 @@ func (p *Private) eventUpdateUserProfile(data string) {
 	}
 	p.App.dispatchEvent(event)
 }
+
+// eventUserStatus handles the user status event.
+//
+// This event is triggered when the status of a tracked user changes.
+func (p *Private) eventUserStatus(data string) {
+	status := parseUserStatus(data)
+
+	event := &Event{
+		Type:       OnPrivateUserStatus,
+		Private:    p,
+		IsPrivate:  true,
+		User:       status.User,
+		UserStatus: &status,
+	}
+	p.App.dispatchEvent(event)
+}
+
+// eventFriendTyping handles the friend typing event.
+func (p *Private) eventFriendTyping(data string) {
+	// typing:username:1
+	username, state, _ := strings.Cut(data, ":")
+
+	event := &Event{
+		Type:      OnPrivateFriendTyping,
+		Private:   p,
+		IsPrivate: true,
+		User:      &models.User{Name: username},
+		Typing:    state == "1",
+	}
+	p.App.dispatchEvent(event)
+}
+
+// parseUserStatus parses the data of the "track" and "status" frames.
+//
+// The data is in the form of "username:value:info", where the value is
+// the last seen time for offline users and the idle minutes for online users.
+//
+// Args:
+//   - data: The frame data without the head.
+//
+// Returns:
+//   - models.UserStatus: The parsed user status.
+func parseUserStatus(data string) (status models.UserStatus) {
+	fields := strings.SplitN(data, ":", 3)
+	for len(fields) < 3 {
+		fields = append(fields, "")
+	}
+
+	status.User = &models.User{Name: fields[0]}
+	status.Info = fields[2]
+	switch fields[2] {
+	case "offline":
+		status.Time, _ = utils.ParseTime(fields[1])
+	case "online", "app":
+		status.Idle, _ = time.ParseDuration(fields[1] + "m")
+	case "invalid":
+	}
+
+	return
+}
@@ -0,0 +1,391 @@
+package chadango
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n0h4rt/chadango/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want models.UserStatus
+	}{
+		{
+			name: "Offline",
+			data: "clonerxyz:1723029464.85:offline",
+			want: models.UserStatus{User: &models.User{Name: "clonerxyz"}, Info: "offline", Time: time.Unix(1723029464, 850000000)},
+		},
+		{
+			name: "Online",
+			data: "clonerxyz:5:online",
+			want: models.UserStatus{User: &models.User{Name: "clonerxyz"}, Info: "online", Idle: 5 * time.Minute},
+		},
+		{
+			name: "App",
+			data: "clonerxyz:0:app",
+			want: models.UserStatus{User: &models.User{Name: "clonerxyz"}, Info: "app"},
+		},
+		{
+			name: "Invalid",
+			data: "nosuchuser:0:invalid",
+			want: models.UserStatus{User: &models.User{Name: "nosuchuser"}, Info: "invalid"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseUserStatus(tt.data)
+			assert.Equal(t, tt.want.User.Name, got.User.Name)
+			assert.Equal(t, tt.want.Info, got.Info)
+			assert.Equal(t, tt.want.Time, got.Time)
+			assert.Equal(t, tt.want.Idle, got.Idle)
+		})
+	}
+}
+
+func TestPrivate_StatusFrame(t *testing.T) {
+	var got *Event
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { got = event }, nil, OnPrivateUserStatus))
+
+	private := &Private{App: app, Name: "PM"}
+	private.wsOnFrame("status:clonerxyz:3:online")
+
+	if assert.NotNil(t, got, "The status frame should dispatch an event") {
+		assert.Equal(t, OnPrivateUserStatus, got.Type)
+		assert.True(t, got.IsPrivate)
+		assert.Equal(t, "clonerxyz", got.User.Name)
+		assert.Equal(t, "online", got.UserStatus.Info)
+		assert.Equal(t, 3*time.Minute, got.UserStatus.Idle)
+	}
+}
+
+func TestPrivate_PingContext(t *testing.T) {
+	// The server never answers, so the ping waits until the context is done.
+	private := newServedPrivate(t, newTestApp(&Config{}), func(head, data string) []string { return nil })
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	rtt, err := private.Ping(ctx)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Zero(t, rtt)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = private.Ping(ctx)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Less(t, time.Since(start), SYNC_SEND_TIMEOUT, "The ping should abort once the context is canceled")
+}
+
+// newServedPrivate returns a private chat connected to a test server.
+//
+// For each received command, the server sends back the frames returned by the reply function.
+func newServedPrivate(t *testing.T, app *Application, reply func(head, data string) []string) *Private {
+	wsURL := newReplyServer(t, reply)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	private := &Private{
+		App:       app,
+		Name:      "Private",
+		LoginName: "nekonyan",
+		ws:        &WebSocket{},
+		events:    make(chan string, EVENT_BUFFER_SIZE),
+		takeOver:  make(chan context.Context),
+		context:   ctx,
+	}
+
+	if !assert.NoError(t, private.ws.Connect(wsURL)) {
+		t.FailNow()
+	}
+	private.ws.Sustain(ctx)
+	go private.listen()
+
+	return private
+}
+
+func TestPrivate_GetHistory(t *testing.T) {
+	live := make(chan string, 1)
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		live <- event.Message.Text
+	}, nil, OnPrivateMessage))
+
+	private := newServedPrivate(t, app, func(head, data string) []string {
+		if head != "get_more" {
+			return nil
+		}
+		// get_more:username:amount
+		if username, _, _ := strings.Cut(data, ":"); username != "clonerxyz" {
+			return []string{"nomore"}
+		}
+		return []string{
+			"msg:clonerxyz:nekonyan:*:1723029464.85:0:<n000/><m v=\"1\">hi</m>",
+			"msg:someone:nekonyan:*:1723029465.85:0:<n000/><m v=\"1\">live</m>",
+			"msg:nekonyan:clonerxyz:*:1723029466.85:0:<n000/><m v=\"1\">hello</m>",
+			"gotmore",
+		}
+	})
+
+	messages, err := private.GetHistory("ClonerXYZ", 20)
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, "hi", messages[0].Text)
+		assert.Equal(t, "hello", messages[1].Text)
+	}
+
+	messages, err = private.GetHistory("nosuchuser", 20)
+	assert.NoError(t, err)
+	assert.NotNil(t, messages)
+	assert.Empty(t, messages)
+
+	select {
+	case text := <-live:
+		assert.Equal(t, "live", text, "A live message should be dispatched")
+	case <-time.After(time.Second):
+		t.Fatal("A live message should be dispatched")
+	}
+}
+
+func TestPrivate_SendTyping(t *testing.T) {
+	sent := make(chan string, 10)
+
+	app := newTestApp(&Config{})
+	private := newServedPrivate(t, app, func(head, data string) []string {
+		sent <- head + ":" + data
+		return nil
+	})
+
+	assert.NoError(t, private.SendTyping("ClonerXYZ", true))
+	assert.NoError(t, private.SendTyping("clonerxyz", true))
+	assert.NoError(t, private.SendTyping("clonerxyz", false))
+
+	assert.Equal(t, "typing:clonerxyz:1", <-sent)
+	assert.Equal(t, "typing:clonerxyz:0", <-sent, "The repeated state should be debounced")
+
+	var marks typingMarks
+	now := time.Now()
+	assert.True(t, marks.shouldSend("clonerxyz", true, now))
+	assert.False(t, marks.shouldSend("clonerxyz", true, now.Add(TYPING_DEBOUNCE/2)))
+	assert.True(t, marks.shouldSend("someone", true, now), "The debounce should be per username")
+	assert.True(t, marks.shouldSend("clonerxyz", true, now.Add(TYPING_DEBOUNCE)))
+}
+
+func TestPrivate_TypingFrame(t *testing.T) {
+	var got *Event
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) { got = event }, nil, OnPrivateFriendTyping))
+
+	private := &Private{App: app, Name: "PM"}
+	private.wsOnFrame("typing:clonerxyz:1")
+
+	if assert.NotNil(t, got, "The typing frame should dispatch an event") {
+		assert.True(t, got.IsPrivate)
+		assert.Equal(t, "clonerxyz", got.User.Name)
+		assert.True(t, got.Typing)
+	}
+}
+
+func TestPrivate_IdleState(t *testing.T) {
+	sent := make(chan string, 10)
+	events := make(chan EventType, 10)
+
+	app := newTestApp(&Config{})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		events <- event.Type
+	}, nil, OnPrivateSelfIdle|OnPrivateSelfActive))
+
+	private := newServedPrivate(t, app, func(head, data string) []string {
+		sent <- head + ":" + data
+		return nil
+	})
+
+	private.SetIdleTimeout(time.Millisecond)
+	assert.Equal(t, MIN_IDLE_TIMEOUT, private.getIdleTimeout(), "The idle timeout should be raised to the minimum")
+
+	// The idle timer is not started before connecting.
+	assert.NoError(t, private.WentActive(), "WentActive should not fail without an idle timer")
+	private.idleMu.Lock()
+	assert.NotNil(t, private.idleTimer)
+	private.idleMu.Unlock()
+
+	assert.NoError(t, private.WentIdle())
+	assert.Equal(t, "idle:0", <-sent)
+	assert.Equal(t, OnPrivateSelfIdle, <-events)
+
+	assert.NoError(t, private.WentActive())
+	assert.Equal(t, "idle:1", <-sent)
+	assert.Equal(t, OnPrivateSelfActive, <-events)
+
+	private.idleMu.Lock()
+	private.idleTimeout = 20 * time.Millisecond
+	private.idleMu.Unlock()
+	assert.NoError(t, private.WentActive())
+	select {
+	case eventType := <-events:
+		assert.Equal(t, OnPrivateSelfIdle, eventType, "The idle timer should use the configured timeout")
+	case <-time.After(time.Second):
+		t.Fatal("The idle timer did not fire")
+	}
+	assert.True(t, private.IsIdle())
+	private.idleMu.Lock()
+	private.idleTimer.Stop()
+	private.idleMu.Unlock()
+}
+
+func TestPrivate_SetStyle(t *testing.T) {
+	private := &Private{NameColor: "F00", TextColor: "0F0", TextFont: "1", TextSize: 11}
+
+	assert.Equal(t, models.MessageStyle{NameColor: "F00", TextColor: "0F0", FontFamily: "1", FontSize: "11", StylesOn: true}, private.Style(), "The style should default to the copied fields")
+	assert.Equal(t, `<nF00/><m v="1"><g x11s0F0="1">hello<br/>world</g></m>`, private.styleText("hello\nworld"))
+
+	private.SetStyle(models.MessageStyle{NameColor: "00F", TextColor: "FFF", FontFamily: "2", FontSize: "9", Bold: true, Underline: true})
+	assert.Equal(t, "00F", private.Style().NameColor)
+	assert.Equal(t, `<n00F/><m v="1"><g x09sFFF="2"><u><b>hello</b></u></g></m>`, private.styleText("hello"), "The set style should be used instead")
+	assert.Equal(t, "F00", private.NameColor, "The copied fields should be left untouched")
+
+	// The style may be set while the messages are being sent.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			private.SetStyle(models.MessageStyle{NameColor: "0F0", FontSize: "11"})
+		}()
+		go func() {
+			defer wg.Done()
+			private.styleText("hello")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, "0F0", private.Style().NameColor)
+}
+
+func TestPrivate_FriendRequest(t *testing.T) {
+	requests := make(chan string, 10)
+	added := make(chan string, 10)
+
+	app := newTestApp(&Config{AutoAddFriends: true})
+	app.AddHandler(NewTypeHandler(func(event *Event, context *Context) {
+		requests <- event.User.Name
+	}, nil, OnPrivateFriendRequest))
+
+	private := newServedPrivate(t, app, func(head, data string) []string {
+		if head != "wladd" {
+			return nil
+		}
+		added <- data
+		return []string{"wladd:" + data + ":on:0"}
+	})
+
+	private.wsOnFrame(`msg:clonerxyz:nekonyan:*:1723029464.85:0:<n000/><m v="1">hi</m>`)
+	private.wsOnFrame(`msg:ClonerXYZ:nekonyan:*:1723029465.85:0:<n000/><m v="1">again</m>`)
+	private.wsOnFrame(`msgoff:someone:nekonyan:*:1723029466.85:0:<n000/><m v="1">offline</m>`)
+	private.firstContact(&models.User{Name: "anon1234", IsAnon: true})
+
+	assert.Equal(t, "clonerxyz", <-requests)
+	assert.Equal(t, "someone", <-requests, "An offline message should be a first contact too")
+	assert.Equal(t, "anon1234", <-requests)
+	assert.Empty(t, requests, "A repeated contact should not be a request")
+
+	var names []string
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-added:
+			names = append(names, name)
+		case <-time.After(time.Second):
+			t.Fatal("The users should be added as friends")
+		}
+	}
+	assert.ElementsMatch(t, []string{"clonerxyz", "someone"}, names)
+
+	select {
+	case name := <-added:
+		t.Fatalf("The anonymous user should not be added, got %q", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A known friend is not a request.
+	private.wsOnFrame("wlonline:buddy:1723029464.85")
+	private.wsOnFrame(`msg:buddy:nekonyan:*:1723029467.85:0:<n000/><m v="1">hey</m>`)
+	assert.Empty(t, requests, "A friend should not be a request")
+}
+
+func TestContactMarks_Bounded(t *testing.T) {
+	var marks contactMarks
+
+	assert.True(t, marks.first("user0"))
+	for i := 1; i <= CONTACT_MARKS_SIZE; i++ {
+		marks.first(fmt.Sprintf("user%d", i))
+	}
+	assert.Equal(t, CONTACT_MARKS_SIZE, marks.recent.order.Len(), "The marks should be bounded")
+	assert.True(t, marks.first("user0"), "The least recent contact should be forgotten")
+
+	marks.befriend("Buddy", true)
+	assert.False(t, marks.first("buddy"))
+	marks.befriend("buddy", false)
+	assert.True(t, marks.first("buddy"), "A removed friend should be a contact again")
+}
+
+func TestPrivate_CancelReconnect(t *testing.T) {
+	gaveUp := false
+	app := newTestApp(&Config{})
+	app.SetReconnectGiveUpHandler(func(name string, isPrivate bool, retries int) {
+		gaveUp = true
+	})
+	app.initAPI(context.Background())
+	api := app.PrivateAPI()
+
+	private := &Private{
+		App:         app,
+		Name:        "Private",
+		ws:          &WebSocket{},
+		context:     context.Background(),
+		BackoffBase: time.Hour,
+		MaxRetries:  3,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- private.Reconnect() }()
+
+	time.Sleep(50 * time.Millisecond)
+	private.CancelReconnect()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrRetryEnds)
+		assert.False(t, gaveUp, "A canceled reconnect should not call the give-up handler")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reconnect did not return after CancelReconnect")
+	}
+	assert.Same(t, api, app.PrivateAPI(), "The shared API client should not be replaced")
+
+	private.CancelReconnect() // No-op once the reconnect has ended.
+}
+
+func TestPrivate_ExportFriends(t *testing.T) {
+	private := newServedPrivate(t, newTestApp(&Config{}), func(head, data string) []string {
+		if head != "wl" {
+			return nil
+		}
+		// wl:username:time:state:idle[:...]
+		return []string{"wl:clonerxyz:1723029464.85:on:0:someone:1723029464.85:off:0"}
+	})
+
+	usernames, err := private.ExportFriends()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"clonerxyz", "someone"}, usernames)
+}
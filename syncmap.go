@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/gob"
 	"sync"
+
+	"github.com/n0h4rt/chadango/utils"
 )
 
 // SyncMap is a synchronized map that can be accessed concurrently.
@@ -54,6 +56,31 @@ func (sm *SyncMap[K, V]) Del(key K) {
 	delete(sm.M, key)
 }
 
+// CompareAndDelete removes the key-value pair with the specified key only if its current value equals the old value.
+//
+// It is a function rather than a method, since the values must be comparable, unlike the values of [SyncMap].
+//
+// Args:
+//   - sm: The map to remove from.
+//   - key: The key to remove.
+//   - old: The value expected to be associated with the key.
+//
+// Returns:
+//   - bool: True if the key-value pair was removed, false otherwise.
+func CompareAndDelete[K, V comparable](sm *SyncMap[K, V], key K, old V) (deleted bool) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	val, ok := sm.M[key]
+	if !ok || val != old {
+		return false
+	}
+
+	delete(sm.M, key)
+
+	return true
+}
+
 // Len returns the number of key-value pairs in the SyncMap.
 //
 // Returns:
@@ -282,6 +309,64 @@ func (sm *OrderedSyncMap[K, V]) RangeReversed(fun func(K, V) bool) {
 	}
 }
 
+// RangeReversedLimit iterates over at most n key-value pairs in the OrderedSyncMap in reverse order and calls the specified function.
+//
+// Args:
+//   - n: The maximum number of key-value pairs to visit.
+//   - fun: The function to call for each key-value pair.
+//
+// If the function returns false, the iteration stops.
+func (sm *OrderedSyncMap[K, V]) RangeReversedLimit(n int, fun func(K, V) bool) {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	for i := len(sm.K) - 1; i >= 0 && n > 0; i, n = i-1, n-1 {
+		if !fun(sm.K[i], sm.M[sm.K[i]]) {
+			return
+		}
+	}
+}
+
+// Newest returns the values of the n most recently inserted keys, in insertion order.
+//
+// Args:
+//   - n: The number of values to return, all of them if it exceeds the length.
+//
+// Returns:
+//   - []V: The newest values.
+func (sm *OrderedSyncMap[K, V]) Newest(n int) []V {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	n = utils.Max(utils.Min(n, len(sm.K)), 0)
+	vals := make([]V, 0, n)
+	for _, key := range sm.K[len(sm.K)-n:] {
+		vals = append(vals, sm.M[key])
+	}
+
+	return vals
+}
+
+// Oldest returns the values of the n least recently inserted keys, in insertion order.
+//
+// Args:
+//   - n: The number of values to return, all of them if it exceeds the length.
+//
+// Returns:
+//   - []V: The oldest values.
+func (sm *OrderedSyncMap[K, V]) Oldest(n int) []V {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	n = utils.Max(utils.Min(n, len(sm.K)), 0)
+	vals := make([]V, 0, n)
+	for _, key := range sm.K[:n] {
+		vals = append(vals, sm.M[key])
+	}
+
+	return vals
+}
+
 // Clear removes all key-value pairs from the OrderedSyncMap.
 func (sm *OrderedSyncMap[K, V]) Clear() {
 	sm.Lock()
@@ -302,6 +387,35 @@ func (sm *OrderedSyncMap[K, V]) Keys() []K {
 	return sm.K
 }
 
+// Replace replaces the key-value pair of the old key with the new one, keeping its position.
+//
+// If the old key does not exist, the new pair is added at the end, like [OrderedSyncMap.Set].
+//
+// Args:
+//   - oldKey: The key to replace.
+//   - newKey: The new key.
+//   - val: The value to associate with the new key.
+func (sm *OrderedSyncMap[K, V]) Replace(oldKey, newKey K, val V) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	if oldKey != newKey {
+		sm.del(newKey)
+	}
+
+	for i, k := range sm.K {
+		if k == oldKey {
+			delete(sm.M, oldKey)
+			sm.K[i] = newKey
+			sm.M[newKey] = val
+			return
+		}
+	}
+
+	sm.K = append(sm.K, newKey)
+	sm.M[newKey] = val
+}
+
 // SetFront adds or updates a key-value pair at the front of the OrderedSyncMap.
 //
 // Args:
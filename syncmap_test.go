@@ -62,6 +62,36 @@ func TestSyncMap_Del(t *testing.T) {
 	assert.Equal(t, "value3", val3)
 }
 
+func TestSyncMap_CompareAndDelete(t *testing.T) {
+	// Create a new instance of SyncMap
+	sm := NewSyncMap[string, string]()
+
+	sm.Set("key1", "value1")
+	sm.Set("key2", "value2")
+
+	// Mismatched value should not delete
+	assert.False(t, CompareAndDelete(&sm, "key1", "stale"))
+	val1, ok1 := sm.Get("key1")
+	assert.True(t, ok1)
+	assert.Equal(t, "value1", val1)
+
+	// Matched value should delete
+	assert.True(t, CompareAndDelete(&sm, "key2", "value2"))
+	_, ok2 := sm.Get("key2")
+	assert.False(t, ok2)
+
+	// Non-existing key should not delete
+	assert.False(t, CompareAndDelete(&sm, "key3", ""))
+
+	// Pointers are compared by identity
+	pm := NewSyncMap[string, *Message]()
+	oldMsg, newMsg := &Message{}, &Message{}
+	pm.Set("key", newMsg)
+	assert.False(t, CompareAndDelete(&pm, "key", oldMsg))
+	assert.True(t, CompareAndDelete(&pm, "key", newMsg))
+	assert.Equal(t, 0, pm.Len())
+}
+
 func TestSyncMap_Len(t *testing.T) {
 	// Create a new instance of SyncMap
 	sm := NewSyncMap[string, string]()
@@ -192,6 +222,32 @@ func TestOrderedSyncMap_Del(t *testing.T) {
 	assert.Equal(t, "value3", val3)
 }
 
+func TestOrderedSyncMap_Replace(t *testing.T) {
+	sm := NewOrderedSyncMap[string, string]()
+	sm.Set("key1", "value1")
+	sm.Set("key2", "value2")
+	sm.Set("key3", "value3")
+
+	// Replace a key in the middle
+	sm.Replace("key2", "key4", "value4")
+	assert.Equal(t, []string{"key1", "key4", "key3"}, sm.Keys())
+	_, ok := sm.Get("key2")
+	assert.False(t, ok)
+	val, _ := sm.Get("key4")
+	assert.Equal(t, "value4", val)
+
+	// Replace with an existing key
+	sm.Replace("key1", "key3", "value5")
+	assert.Equal(t, []string{"key3", "key4"}, sm.Keys())
+	val, _ = sm.Get("key3")
+	assert.Equal(t, "value5", val)
+
+	// Replace a missing key
+	sm.Replace("key6", "key7", "value7")
+	assert.Equal(t, []string{"key3", "key4", "key7"}, sm.Keys())
+	assert.Equal(t, 3, sm.Len())
+}
+
 func TestOrderedSyncMap_Len(t *testing.T) {
 	// Create a new instance of OrderedSyncMap
 	sm := NewOrderedSyncMap[string, string]()
@@ -326,3 +382,45 @@ func TestOrderedSyncMap_TrimFront(t *testing.T) {
 	// Assert that the length is correct
 	assert.Equal(t, 2, length)
 }
+
+func TestOrderedSyncMap_NewestOldest(t *testing.T) {
+	// Create a new instance of OrderedSyncMap with 100 entries
+	sm := NewOrderedSyncMap[int, int]()
+	for i := 0; i < 100; i++ {
+		sm.Set(i, i*10)
+	}
+
+	// Assert that the newest and oldest values are in insertion order
+	assert.Equal(t, []int{950, 960, 970, 980, 990}, sm.Newest(5))
+	assert.Equal(t, []int{0, 10, 20}, sm.Oldest(3))
+
+	// Assert that n is clamped to the length
+	assert.Len(t, sm.Newest(200), 100)
+	assert.Len(t, sm.Oldest(200), 100)
+	assert.Empty(t, sm.Newest(0))
+	assert.Empty(t, sm.Oldest(-1))
+}
+
+func TestOrderedSyncMap_RangeReversedLimit(t *testing.T) {
+	// Create a new instance of OrderedSyncMap
+	sm := NewOrderedSyncMap[string, string]()
+	sm.Set("key1", "value1")
+	sm.Set("key2", "value2")
+	sm.Set("key3", "value3")
+
+	// Assert that the iteration stops after n elements
+	var keys []string
+	sm.RangeReversedLimit(2, func(key, _ string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []string{"key3", "key2"}, keys)
+
+	// Assert that n greater than the length visits every element
+	keys = nil
+	sm.RangeReversedLimit(10, func(key, _ string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []string{"key3", "key2", "key1"}, keys)
+}
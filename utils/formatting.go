@@ -9,8 +9,8 @@ import (
 
 // ParseTime parses the provided string representation of time into a [time.Time] value.
 //
-// The function splits the string into seconds and nanoseconds parts,
-// pads the nanoseconds part with zeros to ensure 6 digits,
+// The function splits the string into seconds and fractional seconds parts,
+// pads or truncates the fractional part to 9 digits so that it represents nanoseconds,
 // and then converts the values into int64 before creating a [time.Time] object using [time.Unix].
 //
 // Args:
@@ -21,7 +21,11 @@ import (
 //   - error: An error if the parsing fails.
 func ParseTime(strtime string) (t time.Time, err error) {
 	sec, nsec, _ := strings.Cut(strtime, ".")
-	nsec = nsec + strings.Repeat("0", 6-len(nsec))
+	if len(nsec) > 9 {
+		// Anything beyond nanoseconds is not representable.
+		nsec = nsec[:9]
+	}
+	nsec = nsec + strings.Repeat("0", 9-len(nsec))
 
 	var secInt, nsecInt int64
 	if secInt, err = strconv.ParseInt(sec, 10, 64); err != nil {
@@ -13,7 +13,11 @@ func TestParseTime(t *testing.T) {
 		expected    time.Time
 		expectError bool
 	}{
-		{"1632992395.123456", time.Unix(1632992395, 123456), false},
+		{"1632992395.123456", time.Unix(1632992395, 123456000), false},
+		{"1688488704", time.Unix(1688488704, 0), false},
+		{"1688488704.5", time.Unix(1688488704, 500000000), false},
+		{"1688488704.123456", time.Unix(1688488704, 123456000), false},
+		{"1688488704.1234567891234", time.Unix(1688488704, 123456789), false},
 		{"0.0", time.Unix(0, 0), false},
 		{"invalid", time.Time{}, true},
 	}
@@ -30,6 +30,19 @@ var (
 
 // GetServer returns the server URL for a given name.
 //
+// The server is selected by [ServerNumber].
+//
+// Args:
+//   - name: The name of the chat room.
+//
+// Returns:
+//   - string: The server URL for the given name.
+func GetServer(name string) string {
+	return fmt.Sprintf("ws://s%s.chatango.com:8080/", ServerNumber(name))
+}
+
+// ServerNumber returns the server number for a given name, e.g. "39" for "ws://s39.chatango.com:8080/".
+//
 // The function uses a weighted round-robin algorithm to select a server based on a calculated modulus ratio.
 // It takes the name as input and calculates a ratio based on the first and second halves of the name.
 // This ratio is then used to determine the server with the highest weight.
@@ -38,8 +51,8 @@ var (
 //   - name: The name of the chat room.
 //
 // Returns:
-//   - string: The server URL for the given name.
-func GetServer(name string) string {
+//   - string: The server number for the given name.
+func ServerNumber(name string) string {
 	var (
 		firstHalf   int64
 		secondHalf  int64 = 1000
@@ -67,9 +80,9 @@ func GetServer(name string) string {
 	for _, serverEntry = range ctssm {
 		weightRatio += ctssw[serverEntry[1]] / totalWeight
 		if modRatio <= weightRatio {
-			return fmt.Sprintf("ws://s%s.chatango.com:8080/", serverEntry[0])
+			return serverEntry[0]
 		}
 	}
 
-	return "ws://s5.chatango.com:8080/" // Default
+	return "5" // Default
 }
@@ -21,3 +21,19 @@ func TestGetServer(t *testing.T) {
 		assert.Equal(t, test.expected, result, "GetServer result should match the expected result")
 	}
 }
+
+func TestServerNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"khususme", "39"},
+		{"animeindofun", "50"},
+		{"komikcastsite", "16"},
+	}
+
+	for _, test := range tests {
+		result := ServerNumber(test.name)
+		assert.Equal(t, test.expected, result, "ServerNumber result should match the expected result")
+	}
+}
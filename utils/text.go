@@ -3,6 +3,7 @@ package utils
 import (
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // IsDigit checks whether the provided string represents a digit.
@@ -54,3 +55,130 @@ func SplitTextIntoChunks(text string, chunkSize int) (chunks []string) {
 
 	return
 }
+
+// TokenizeArgs splits the provided text into arguments, in the manner of a shell.
+//
+// The arguments are separated by whitespace, unless it is quoted with double or single quotes.
+// A backslash escapes the next character, except within single quotes,
+// and only a double quote or a backslash within double quotes.
+// A quote only opens at the start of an argument, so that apostrophes in words such as "don't" are kept.
+// If a quote is left unclosed, the text is split by whitespace instead.
+//
+// Args:
+//   - text: The text to split into arguments.
+//
+// Returns:
+//   - []string: A slice of strings representing the arguments.
+func TokenizeArgs(text string) []string {
+	args := []string{}
+	var current strings.Builder
+	var quote rune
+	var inArg, escaped bool
+
+	for _, r := range text {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			inArg = true
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case (r == '"' || r == '\'') && !inArg:
+			inArg = true
+			quote = r
+		default:
+			inArg = true
+			current.WriteRune(r)
+		}
+	}
+
+	if quote != 0 {
+		return strings.Fields(text)
+	}
+
+	if escaped {
+		// A trailing backslash is kept as is.
+		current.WriteRune('\\')
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+// Levenshtein computes the edit distance between two strings.
+//
+// The distance is the minimum number of single-character insertions, deletions, or substitutions
+// required to change one string into the other. The comparison is rune-based.
+//
+// Args:
+//   - a: The first string.
+//   - b: The second string.
+//
+// Returns:
+//   - int: The edit distance between the two strings.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	var cost int
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost = 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = Min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// Similarity returns the normalized similarity of two strings based on the [Levenshtein] distance.
+//
+// The result ranges from 0 (completely different) to 1 (identical).
+//
+// Args:
+//   - a: The first string.
+//   - b: The second string.
+//
+// Returns:
+//   - float64: The similarity ratio between the two strings.
+func Similarity(a, b string) float64 {
+	longest := Max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(Levenshtein(a, b))/float64(longest)
+}
@@ -21,3 +21,54 @@ func TestSplitTextIntoChunks(t *testing.T) {
 	// Check if the result matches the expected result
 	assert.Equal(t, expectedResult, result, "SplitTextIntoChunks result should match the expected result")
 }
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"echo", "ehco", 2},
+		{"help", "help", 0},
+	}
+
+	for _, test := range tests {
+		result := Levenshtein(test.a, test.b)
+		assert.Equal(t, test.expected, result, "Levenshtein(%q, %q) should match the expected result", test.a, test.b)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("", ""), "Similarity of empty strings should be 1")
+	assert.Equal(t, 1.0, Similarity("echo", "echo"), "Similarity of identical strings should be 1")
+	assert.Equal(t, 0.75, Similarity("echo", "ecko"), "Similarity should be normalized by the longest string")
+	assert.Equal(t, 0.0, Similarity("abc", "xyz"), "Similarity of completely different strings should be 0")
+}
+
+func TestTokenizeArgs(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"", []string{}},
+		{"  hello   world ", []string{"hello", "world"}},
+		{`"hello world" foo`, []string{"hello world", "foo"}},
+		{`'hello "world"' foo`, []string{`hello "world"`, "foo"}},
+		{`"say \"hi\"" there`, []string{`say "hi"`, "there"}},
+		{`say\"hi \\ back\ slash`, []string{`say"hi`, `\`, "back slash"}},
+		{`"C:\path" '\n'`, []string{`C:\path`, `\n`}},
+		{`don't stop`, []string{"don't", "stop"}},
+		{`"" empty`, []string{"", "empty"}},
+		{`trailing\`, []string{`trailing\`}},
+		{`"unmatched quote here`, []string{`"unmatched`, "quote", "here"}},
+		{`it's 'unmatched`, []string{"it's", "'unmatched"}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, TokenizeArgs(test.text), "TokenizeArgs(%q)", test.text)
+	}
+}
@@ -2,6 +2,8 @@ package chadango
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/websocket"
@@ -11,14 +13,23 @@ import (
 // It implements `golang.org/x/net/websocket` under the hood and wraps it into a channel,
 // allowing it to be select-able along with other channels.
 type WebSocket struct {
-	Connected bool        // Connected indicates whether the WebSocket connection is currently active.
-	Events    chan string // Events is a channel for receiving WebSocket events and messages.
-	OnError   func(error) // OnError is a callback function that will be called in case of an error during WebSocket operation.
+	Events  chan string // Events is a channel for receiving WebSocket events and messages.
+	OnError func(error) // OnError is a callback function that will be called in case of an error during WebSocket operation.
 
+	connected atomic.Bool        // connected indicates whether the WebSocket connection is currently active, see [WebSocket.IsConnected].
 	url       string             // url is the WebSocket server URL.
 	client    *websocket.Conn    // client is the underlying WebSocket connection.
 	context   context.Context    // context is the context used for managing the WebSocket connection's lifecycle.
 	cancelCtx context.CancelFunc // cancelFunc is the function to cancel the WebSocket connection's lifecycle context.
+	mu        sync.Mutex         // mu guards the closing of the connection.
+}
+
+// IsConnected checks whether the WebSocket connection is currently active.
+//
+// Returns:
+//   - bool: True if the connection is active, otherwise false.
+func (w *WebSocket) IsConnected() bool {
+	return w.connected.Load()
 }
 
 // Connect establishes a WebSocket connection to the specified URL.
@@ -29,25 +40,63 @@ type WebSocket struct {
 // Returns:
 //   - error: An error if the connection fails.
 func (w *WebSocket) Connect(url string) (err error) {
-	if w.Connected {
+	return w.ConnectContext(context.Background(), url)
+}
+
+// ConnectContext establishes a WebSocket connection to the specified URL, bounded by the context.
+//
+// Args:
+//   - ctx: The context for the dial and the opening handshake.
+//   - url: The WebSocket server URL.
+//
+// Returns:
+//   - error: An error if the connection fails.
+func (w *WebSocket) ConnectContext(ctx context.Context, url string) (err error) {
+	if w.connected.Load() {
+		return
+	}
+
+	var config *websocket.Config
+	if config, err = websocket.NewConfig(url, WEBSOCKET_ORIGIN); err != nil {
 		return
 	}
 
 	w.url = url
-	w.client, err = websocket.Dial(url, "", WEBSOCKET_ORIGIN)
+	w.client, err = config.DialContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	w.Connected = true
 	w.Events = make(chan string, EVENT_BUFFER_SIZE)
+	w.connected.Store(true)
 	return
 }
 
+// SetDeadline sets the read and write deadlines of the WebSocket connection.
+//
+// A zero value for t means the operations will not time out.
+//
+// Args:
+//   - t: The deadline.
+//
+// Returns:
+//   - error: An error if the deadline cannot be set.
+func (w *WebSocket) SetDeadline(t time.Time) error {
+	if !w.connected.Load() {
+		return ErrNotConnected
+	}
+
+	return w.client.SetDeadline(t)
+}
+
 // Close closes the WebSocket connection.
+//
+// It is safe to call it multiple times.
 func (w *WebSocket) Close() {
-	if w.Connected {
-		w.Connected = false
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.connected.CompareAndSwap(true, false) {
 		if w.cancelCtx != nil {
 			w.cancelCtx()
 		}
@@ -113,7 +162,7 @@ func (w *WebSocket) keepAlive() {
 // Returns:
 //   - error: An error if the sending fails.
 func (w *WebSocket) Send(msg string) (err error) {
-	if w.Connected {
+	if w.connected.Load() {
 		err = websocket.Message.Send(w.client, msg)
 	} else {
 		err = ErrNotConnected
@@ -127,7 +176,7 @@ func (w *WebSocket) Send(msg string) (err error) {
 //   - string: The received message.
 //   - error: An error if the receiving fails.
 func (w *WebSocket) Recv() (msg string, err error) {
-	if w.Connected {
+	if w.connected.Load() {
 		err = websocket.Message.Receive(w.client, &msg)
 	} else {
 		err = ErrNotConnected